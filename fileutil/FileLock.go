@@ -0,0 +1,136 @@
+package fileutil
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racklog"
+)
+
+// lockRetryInterval is the time to wait between two attempts to acquire a lock
+const lockRetryInterval = 100 * time.Millisecond
+
+// FileLock is an exclusive lock on a file, held by the existence of a lock file next to it.
+// As the lock file is created with O_EXCL, only one process at a time is able to hold the lock.
+type FileLock struct {
+	path string
+}
+
+// LockFile acquires the lock for the file at the given path.
+// It waits for at most timeout for other processes to release the lock.
+// Lock files of processes, that are not running anymore, and lock files older than staleAfter
+// are considered abandoned and are removed.
+func LockFile(path string, timeout time.Duration, staleAfter time.Duration) (*FileLock, error) {
+	lockPath := path + ".lock"
+	deadline := time.Now().Add(timeout)
+
+	for {
+		file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			_, err = fmt.Fprintf(file, "%d\n", os.Getpid())
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+
+			if err != nil {
+				_ = os.Remove(lockPath)
+				return nil, err
+			}
+
+			return &FileLock{lockPath}, nil
+		}
+
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		if isStaleLock(lockPath, staleAfter) {
+			if err := removeStaleLock(lockPath, staleAfter); err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, errors.New("timeout while waiting for lock " + lockPath)
+		}
+
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+// isStaleLock returns true, if the process, that holds the lock, is not running anymore or the lock is too old.
+// A lock file without a PID is only stale, when it is too old, as its process may still be writing the PID.
+func isStaleLock(lockPath string, staleAfter time.Duration) bool {
+	info, err := os.Stat(lockPath)
+	if err != nil {
+		return false
+	}
+
+	if time.Since(info.ModTime()) > staleAfter {
+		return true
+	}
+
+	content, err := ioutil.ReadFile(lockPath) //nolint, the path of the lock file is built by rackjobber
+	if err != nil {
+		return false
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil {
+		return false
+	}
+
+	return !processIsRunning(pid)
+}
+
+// removeStaleLock moves the stale lock file away, before it is removed.
+// If the moved file is not stale, another process acquired the lock after it was found to be stale,
+// so the lock file is moved back instead.
+func removeStaleLock(lockPath string, staleAfter time.Duration) error {
+	stalePath := lockPath + ".stale." + strconv.Itoa(os.Getpid())
+
+	if err := os.Rename(lockPath, stalePath); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	if isStaleLock(stalePath, staleAfter) {
+		racklog.Infof("Removing stale lock file %v\n", lockPath)
+		return os.Remove(stalePath)
+	}
+
+	// os.Link fails instead of overriding a lock file, that was created in the meantime
+	if err := os.Link(stalePath, lockPath); err != nil && !os.IsExist(err) {
+		return err
+	}
+
+	return os.Remove(stalePath)
+}
+
+// processIsRunning returns true, if a process with the PID is running on this machine
+func processIsRunning(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+
+	err = process.Signal(syscall.Signal(0))
+
+	return err == nil || err == syscall.EPERM
+}
+
+// Unlock releases the lock, so other processes are able to acquire it
+func (l *FileLock) Unlock() error {
+	return os.Remove(l.path)
+}
@@ -0,0 +1,153 @@
+package fileutil
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestLockFile(t *testing.T) {
+	root, err := ioutil.TempDir("", "rackjobber")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(root)
+
+	path := filepath.Join(root, "shopstore.yaml")
+
+	lock, err := LockFile(path, 0, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LockFile(path, 200*time.Millisecond, time.Hour); err == nil {
+		t.Error("expected the held lock to time out")
+	}
+
+	if err := lock.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	lock, err = LockFile(path, 0, time.Hour)
+	if err != nil {
+		t.Fatalf("the released lock can not be acquired: %v", err)
+	}
+
+	if err := lock.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLockFileRemovesStaleLocks(t *testing.T) {
+	root, err := ioutil.TempDir("", "rackjobber")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(root)
+
+	finished := exec.Command("true")
+	if err := finished.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	old := time.Now().Add(-2 * time.Hour)
+
+	tests := []struct {
+		name      string
+		content   string
+		modified  time.Time
+		wantStale bool
+	}{
+		{name: "running process", content: strconv.Itoa(os.Getpid()), modified: time.Now()},
+		{name: "lock without PID yet", content: "", modified: time.Now()},
+		{name: "finished process", content: strconv.Itoa(finished.Process.Pid), modified: time.Now(), wantStale: true},
+		{name: "old lock", content: strconv.Itoa(os.Getpid()), modified: old, wantStale: true},
+	}
+
+	for i, test := range tests {
+		path := filepath.Join(root, strconv.Itoa(i))
+		lockPath := path + ".lock"
+
+		if err := ioutil.WriteFile(lockPath, []byte(test.content+"\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.Chtimes(lockPath, test.modified, test.modified); err != nil {
+			t.Fatal(err)
+		}
+
+		lock, err := LockFile(path, 0, time.Hour)
+		if (err == nil) != test.wantStale {
+			t.Errorf("%v: got error %v, want the stale lock to be removed %v", test.name, err, test.wantStale)
+		}
+
+		if lock != nil {
+			if err := lock.Unlock(); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if files, _ := filepath.Glob(lockPath + ".stale.*"); len(files) > 0 {
+			t.Errorf("%v: the removal left %v behind", test.name, files)
+		}
+	}
+}
+
+func TestRemoveStaleLockKeepsFreshLock(t *testing.T) {
+	root, err := ioutil.TempDir("", "rackjobber")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(root)
+
+	lockPath := filepath.Join(root, "shopstore.yaml.lock")
+	content := strconv.Itoa(os.Getpid()) + "\n"
+
+	if err := ioutil.WriteFile(lockPath, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := removeStaleLock(lockPath, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	if read, err := ioutil.ReadFile(lockPath); err != nil || string(read) != content {
+		t.Errorf("the fresh lock contains %q, %v, want %q", read, err, content)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	root, err := ioutil.TempDir("", "rackjobber")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(root)
+
+	path := filepath.Join(root, "shopstore.yaml")
+
+	for _, content := range []string{"shops: []\n", "groups: []\n"} {
+		if err := WriteFileAtomic(path, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+
+		if read, err := ioutil.ReadFile(path); err != nil || string(read) != content {
+			t.Errorf("the file contains %q, %v, want %q", read, err, content)
+		}
+	}
+
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("got %v, %v, want mode 0600", info, err)
+	}
+
+	if files, _ := ioutil.ReadDir(root); len(files) != 1 {
+		t.Errorf("the writes left %v files behind", len(files))
+	}
+}
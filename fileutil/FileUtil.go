@@ -3,13 +3,12 @@ package fileutil
 
 import (
 	"errors"
-	"fmt"
 	"io"
 	"io/ioutil"
-	"log"
 	"os"
 	"path/filepath"
-	"strings"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racklog"
 )
 
 // ObjectExists checks if a directory or file exists at the specified path
@@ -29,7 +28,7 @@ func ObjectExists(path string) (bool, error) {
 // ReadFile will read out the data of a file and return it.
 func ReadFile(path string) (*[]byte, error) {
 	if err := ValidateFilepath(path); err != nil {
-		log.Printf("ValidateFilepath - error: %v\n", err)
+		racklog.Warnf("ValidateFilepath - error: %v\n", err)
 		return nil, err
 	}
 
@@ -40,7 +39,7 @@ func ReadFile(path string) (*[]byte, error) {
 
 	defer func() {
 		if err := file.Close(); err != nil {
-			log.Printf("file.Close - error: %v\n", err)
+			racklog.Warnf("file.Close - error: %v\n", err)
 		}
 	}()
 
@@ -81,28 +80,36 @@ func OverrideFile(path string, data []byte) error {
 	return ioutil.WriteFile(path, data, os.ModePerm)
 }
 
-// GetAppPath returns the path of the current application
-func getAppPath() (*string, error) {
-	ex, err := os.Executable()
+// WriteFileAtomic writes the data to a temporary file next to the given path and renames it afterwards.
+// Readers will either see the old or the new content of the file, but never a partially written one.
+// The written file is only accessible by the current user.
+func WriteFileAtomic(path string, data []byte) error {
+	file, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	exPath := filepath.Dir(ex)
+	tempPath := file.Name()
 
-	return &exPath, nil
-}
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
 
-// GetAppFolderPath will return the folder path of the executable where config data is stored.
-func GetAppFolderPath() (*string, error) {
-	exPath, err := getAppPath()
-	if err != nil {
-		return nil, err
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
 	}
 
-	folderPath := filepath.Join(*exPath, "rackresource")
+	if err == nil {
+		err = os.Chmod(tempPath, 0600)
+	}
 
-	return &folderPath, nil
+	if err != nil {
+		_ = os.Remove(tempPath)
+		return err
+	}
+
+	return os.Rename(tempPath, path)
 }
 
 // CopyFile will copy a file from a specified source to a specified destination
@@ -125,7 +132,7 @@ func CopyFile(src string, dst string) error {
 
 	defer func() {
 		if err := source.Close(); err != nil {
-			log.Printf("source.Close - error: %v\n", err)
+			racklog.Warnf("source.Close - error: %v\n", err)
 		}
 	}()
 
@@ -136,7 +143,7 @@ func CopyFile(src string, dst string) error {
 
 	defer func() {
 		if err := destination.Close(); err != nil {
-			log.Printf("destination.Close - error: %v\n", err)
+			racklog.Warnf("destination.Close - error: %v\n", err)
 		}
 	}()
 
@@ -162,30 +169,15 @@ func CreateDirIfNotExistant(dir string) error {
 
 //DeleteDirectory deletes a given directory, as well as all children
 func DeleteDirectory(dir string) error {
-	fmt.Printf("deleting directory: \n")
+	racklog.Verbosef("deleting directory: %v\n", dir)
 
 	err := os.RemoveAll(dir)
 	if err != nil {
-		log.Printf("Failed to delete directory: %v\n", err)
+		racklog.Warnf("Failed to delete directory: %v\n", err)
 		return err
 	}
 
-	fmt.Printf("successfully deleted directory: \n")
-
-	return nil
-}
-
-// ValidateFilepath ckecks if the given filepath lies within rackjobbers directory structure
-func ValidateFilepath(filePath string) error {
-	ex, err := os.Executable()
-	if err != nil {
-		return err
-	}
-
-	exPath := filepath.Dir(ex)
-	if !strings.Contains(filePath, exPath) {
-		return errors.New("provided filepath is outside of rackjobbers boundaries")
-	}
+	racklog.Verbosef("successfully deleted directory: %v\n", dir)
 
 	return nil
 }
@@ -0,0 +1,219 @@
+package fileutil
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// HomeEnv is the environment variable, that sets the folder of the config and the data of rackjobber
+const HomeEnv = "RACKJOBBER_HOME"
+
+//appName is the name of the folders of rackjobber in the XDG base directories
+const appName = "rackjobber"
+
+//legacyFolderName is the name of the folder next to the executable, that older versions kept everything in
+const legacyFolderName = "rackresource"
+
+//home is the folder of the config and the data set by the home flag, it replaces the environment and the defaults
+var home string
+
+// SetHome sets the folder, that holds the config and the data of rackjobber, like --home does.
+// An empty path keeps RACKJOBBER_HOME and the defaults.
+func SetHome(path string) error {
+	if len(path) == 0 {
+		home = ""
+		return nil
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	home = absPath
+
+	return nil
+}
+
+// GetConfigFolderPath returns the folder of the config.yaml and the shopstore.yaml.
+// It is the home set by --home or RACKJOBBER_HOME, the rackresource folder next to the executable, if it still exists,
+// or $XDG_CONFIG_HOME/rackjobber, which defaults to ~/.config/rackjobber.
+func GetConfigFolderPath() (*string, error) {
+	configFolder, _, err := folders(true)
+	if err != nil {
+		return nil, err
+	}
+
+	return &configFolder, nil
+}
+
+// GetAppFolderPath returns the folder of the data of rackjobber, like the repositories and the local backups.
+// It is the home set by --home or RACKJOBBER_HOME, the rackresource folder next to the executable, if it still exists,
+// or $XDG_DATA_HOME/rackjobber, which defaults to ~/.local/share/rackjobber.
+func GetAppFolderPath() (*string, error) {
+	_, dataFolder, err := folders(true)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dataFolder, nil
+}
+
+// GetLegacyFolderPath returns the rackresource folder next to the executable, that older versions kept everything in
+func GetLegacyFolderPath() (*string, error) {
+	ex, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+
+	folderPath := filepath.Join(filepath.Dir(ex), legacyFolderName)
+
+	return &folderPath, nil
+}
+
+// UsesLegacyFolder returns if the config and the data are still kept in the rackresource folder next to the executable
+func UsesLegacyFolder() bool {
+	legacyFolder, err := GetLegacyFolderPath()
+	if err != nil {
+		return false
+	}
+
+	_, dataFolder, err := folders(true)
+
+	return err == nil && dataFolder == *legacyFolder
+}
+
+// CreateHomeFolders creates the config folder, the data folder and the folder of its repositories, if they are missing
+func CreateHomeFolders() error {
+	configFolder, dataFolder, err := folders(true)
+	if err != nil {
+		return err
+	}
+
+	for _, folder := range []string{configFolder, dataFolder, filepath.Join(dataFolder, "repos")} {
+		if err := os.MkdirAll(folder, os.ModePerm); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//folders returns the config and the data folder. The rackresource folder next to the executable is only used,
+//if legacy is set and no home is set, so older installations keep working until they are migrated.
+func folders(legacy bool) (string, string, error) {
+	if len(home) > 0 {
+		return home, home, nil
+	}
+
+	if envHome := os.Getenv(HomeEnv); len(envHome) > 0 {
+		absHome, err := filepath.Abs(envHome)
+		return absHome, absHome, err
+	}
+
+	if legacy {
+		if legacyFolder, err := GetLegacyFolderPath(); err == nil {
+			if info, err := os.Stat(*legacyFolder); err == nil && info.IsDir() {
+				return *legacyFolder, *legacyFolder, nil
+			}
+		}
+	}
+
+	configFolder, err := xdgFolder("XDG_CONFIG_HOME", ".config")
+	if err != nil {
+		return "", "", err
+	}
+
+	dataFolder, err := xdgFolder("XDG_DATA_HOME", filepath.Join(".local", "share"))
+	if err != nil {
+		return "", "", err
+	}
+
+	return configFolder, dataFolder, nil
+}
+
+//xdgFolder returns the folder of rackjobber in the XDG base directory of the variable,
+//or in the default below the home of the user, if the variable is not set to an absolute path
+func xdgFolder(variable string, defaultFolder string) (string, error) {
+	if base := os.Getenv(variable); filepath.IsAbs(base) {
+		return filepath.Join(base, appName), nil
+	}
+
+	userHome, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.New("failed to find the home of the user, set --home or " + HomeEnv + ": " + err.Error())
+	}
+
+	return filepath.Join(userHome, defaultFolder, appName), nil
+}
+
+// ValidateFilepath checks if the given filepath lies within the config or the data folder of rackjobber
+func ValidateFilepath(filePath string) error {
+	configFolder, dataFolder, err := folders(true)
+	if err != nil {
+		return err
+	}
+
+	for _, folder := range []string{configFolder, dataFolder} {
+		within, err := isWithin(filePath, folder)
+		if err != nil {
+			return err
+		}
+
+		if within {
+			return nil
+		}
+	}
+
+	return errors.New("provided filepath " + filePath + " is outside of rackjobbers boundaries")
+}
+
+//isWithin returns if the path is the folder or lies below it. Both are made absolute, cleaned and their symbolic
+//links are resolved, so neither a common prefix of their names, .. elements nor links let a path escape the folder.
+func isWithin(path string, folder string) (bool, error) {
+	resolvedPath, err := resolvePath(path)
+	if err != nil {
+		return false, err
+	}
+
+	resolvedFolder, err := resolvePath(folder)
+	if err != nil {
+		return false, err
+	}
+
+	rel, err := filepath.Rel(resolvedFolder, resolvedPath)
+	if err != nil {
+		// the path is on another volume than the folder
+		return false, nil
+	}
+
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)), nil
+}
+
+//resolvePath returns the absolute path with the symbolic links resolved. Only the existing part of the path is
+//resolved, the missing elements are appended as they are.
+func resolvePath(path string) (string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	resolved, err := filepath.EvalSymlinks(absPath)
+	if err == nil {
+		return resolved, nil
+	}
+
+	parent := filepath.Dir(absPath)
+	if !os.IsNotExist(err) || parent == absPath {
+		return "", err
+	}
+
+	resolvedParent, err := resolvePath(parent)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(resolvedParent, filepath.Base(absPath)), nil
+}
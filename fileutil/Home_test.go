@@ -0,0 +1,67 @@
+package fileutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsWithin(t *testing.T) {
+	root, err := ioutil.TempDir("", "rackjobber")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(root)
+
+	config := filepath.Join(root, "config")
+	outside := filepath.Join(root, "outside")
+
+	for _, folder := range []string{config, outside} {
+		if err := os.Mkdir(folder, os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.Symlink(outside, filepath.Join(config, "escape")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Symlink(config, filepath.Join(outside, "linked")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		folder string
+		within bool
+	}{
+		{name: "the folder itself", path: config, folder: config, within: true},
+		{name: "file in the folder", path: filepath.Join(config, "config.yaml"), folder: config, within: true},
+		{name: "missing children", path: filepath.Join(config, "repos", "master", "spec"), folder: config,
+			within: true},
+		{name: "common prefix", path: config + "-old", folder: config, within: false},
+		{name: "dot dot elements", path: filepath.Join(config, "..", "outside", "file"), folder: config,
+			within: false},
+		{name: "link out of the folder", path: filepath.Join(config, "escape", "file"), folder: config,
+			within: false},
+		{name: "link into the folder", path: filepath.Join(outside, "linked", "file"), folder: config,
+			within: true},
+		{name: "linked folder", path: filepath.Join(config, "file"), folder: filepath.Join(outside, "linked"),
+			within: true},
+	}
+
+	for _, test := range tests {
+		within, err := isWithin(test.path, test.folder)
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.name, err)
+			continue
+		}
+
+		if within != test.within {
+			t.Errorf("%v: isWithin(%v, %v) = %v, want %v", test.name, test.path, test.folder, within, test.within)
+		}
+	}
+}
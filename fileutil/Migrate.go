@@ -0,0 +1,226 @@
+package fileutil
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racklog"
+)
+
+//configFiles are the files of a rackresource folder, that are moved to the config folder, everything else is data
+var configFiles = []string{"config.yaml", "shopstore.yaml"}
+
+// Move is an entry of a rackresource folder and the path it is moved to
+type Move struct {
+	From string
+	To   string
+}
+
+// PlanMigration returns the moves of the entries of the rackresource folder to the config and the data folder,
+// that are used once the rackresource folder next to the executable is gone.
+// It fails, if the folder is in use or an entry already exists in its new folder with another content.
+// Entries, that an interrupted migration already copied, are moved again, which only removes them from the source.
+func PlanMigration(source string) ([]Move, error) {
+	source, err := filepath.Abs(source)
+	if err != nil {
+		return nil, err
+	}
+
+	configFolder, dataFolder, err := folders(false)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, folder := range []string{configFolder, dataFolder} {
+		if within, _ := isWithin(folder, source); within {
+			return nil, errors.New(folder + " lies within " + source + ", the folder is already in use")
+		}
+	}
+
+	for _, name := range configFiles {
+		if exists, _ := ObjectExists(filepath.Join(source, name+".lock")); exists {
+			return nil, errors.New(name + " is locked by a running rackjobber, try again when it finished")
+		}
+	}
+
+	entries, err := ioutil.ReadDir(source)
+	if err != nil {
+		return nil, err
+	}
+
+	moves := []Move{}
+
+	for _, entry := range entries {
+		target := filepath.Join(dataFolder, entry.Name())
+		if isConfigFile(entry.Name()) {
+			target = filepath.Join(configFolder, entry.Name())
+		}
+
+		if exists, _ := ObjectExists(target); exists && !sameTree(filepath.Join(source, entry.Name()), target) {
+			return nil, errors.New(target + " already exists, move it away or merge it by hand")
+		}
+
+		moves = append(moves, Move{From: filepath.Join(source, entry.Name()), To: target})
+	}
+
+	return moves, nil
+}
+
+// Migrate executes the moves and removes the emptied source folder.
+// Entries are renamed, or copied if their new folder is on another file system. The copied entries are only removed,
+// when all entries were copied, so an interrupted migration can be resumed.
+func Migrate(source string, moves []Move) error {
+	copied := []Move{}
+
+	for _, move := range moves {
+		if err := os.MkdirAll(filepath.Dir(move.To), os.ModePerm); err != nil {
+			return err
+		}
+
+		renamed, err := moveEntry(move.From, move.To)
+		if err != nil {
+			return errors.New("failed to move " + move.From + " to " + move.To + ": " + err.Error())
+		}
+
+		if renamed {
+			racklog.Infof("Moved %v to %v", move.From, move.To)
+		} else {
+			copied = append(copied, move)
+		}
+	}
+
+	for _, move := range copied {
+		if err := os.RemoveAll(move.From); err != nil {
+			return errors.New("failed to remove " + move.From + " after copying it: " + err.Error())
+		}
+
+		racklog.Infof("Moved %v to %v", move.From, move.To)
+	}
+
+	return os.Remove(source)
+}
+
+//isConfigFile returns if an entry of a rackresource folder belongs into the config folder
+func isConfigFile(name string) bool {
+	for _, configFile := range configFiles {
+		if name == configFile {
+			return true
+		}
+	}
+
+	return false
+}
+
+//moveEntry renames a file or directory, or copies it, if it can not be renamed. It returns if the entry was renamed.
+//A copy is made next to the target and renamed when it is complete, so an existing target was copied before.
+func moveEntry(from string, to string) (bool, error) {
+	if exists, _ := ObjectExists(to); exists {
+		return false, nil
+	}
+
+	partial := to + ".migrating"
+	if err := os.RemoveAll(partial); err != nil {
+		return false, err
+	}
+
+	if err := os.Rename(from, to); err == nil {
+		return true, nil
+	}
+
+	if err := copyTree(from, partial); err != nil {
+		_ = os.RemoveAll(partial)
+		return false, err
+	}
+
+	return false, os.Rename(partial, to)
+}
+
+//copyTree copies a file, a symbolic link or a directory with all its children and keeps their permissions
+func copyTree(from string, to string) error {
+	info, err := os.Lstat(from)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		link, err := os.Readlink(from)
+		if err != nil {
+			return err
+		}
+
+		return os.Symlink(link, to)
+	case info.IsDir():
+		if err := os.Mkdir(to, os.ModePerm); err != nil {
+			return err
+		}
+
+		entries, err := ioutil.ReadDir(from)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			if err := copyTree(filepath.Join(from, entry.Name()), filepath.Join(to, entry.Name())); err != nil {
+				return err
+			}
+		}
+
+		return os.Chmod(to, info.Mode().Perm())
+	default:
+		if err := CopyFile(from, to); err != nil {
+			return err
+		}
+
+		return os.Chmod(to, info.Mode().Perm())
+	}
+}
+
+//sameTree returns if two files, symbolic links or directories have the same content, like after copyTree
+func sameTree(a string, b string) bool {
+	infoA, err := os.Lstat(a)
+	if err != nil {
+		return false
+	}
+
+	infoB, err := os.Lstat(b)
+	if err != nil || infoA.Mode()&os.ModeType != infoB.Mode()&os.ModeType {
+		return false
+	}
+
+	switch {
+	case infoA.Mode()&os.ModeSymlink != 0:
+		linkA, errA := os.Readlink(a)
+		linkB, errB := os.Readlink(b)
+
+		return errA == nil && errB == nil && linkA == linkB
+	case infoA.IsDir():
+		entriesA, errA := ioutil.ReadDir(a)
+		entriesB, errB := ioutil.ReadDir(b)
+
+		if errA != nil || errB != nil || len(entriesA) != len(entriesB) {
+			return false
+		}
+
+		for i, entry := range entriesA {
+			if entry.Name() != entriesB[i].Name() ||
+				!sameTree(filepath.Join(a, entry.Name()), filepath.Join(b, entry.Name())) {
+				return false
+			}
+		}
+
+		return true
+	default:
+		if infoA.Size() != infoB.Size() {
+			return false
+		}
+
+		contentA, errA := ioutil.ReadFile(a) //nolint
+		contentB, errB := ioutil.ReadFile(b) //nolint
+
+		return errA == nil && errB == nil && bytes.Equal(contentA, contentB)
+	}
+}
@@ -0,0 +1,72 @@
+package fileutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrateResumes(t *testing.T) {
+	root, err := ioutil.TempDir("", "rackjobber")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(root)
+
+	source := filepath.Join(root, "rackresource")
+	data := filepath.Join(root, "data")
+
+	files := map[string]string{
+		filepath.Join(source, "config.yaml"):          "accounts: []\n",
+		filepath.Join(source, "repos", "master", "a"): "spec",
+		filepath.Join(data, "repos", "master", "a"):   "spec",
+		filepath.Join(data, "backups.migrating", "x"): "partial",
+		filepath.Join(source, "backups", "x"):         "backup",
+	}
+
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if !sameTree(filepath.Join(source, "repos"), filepath.Join(data, "repos")) {
+		t.Error("expected the copied repos to be the same")
+	}
+
+	if sameTree(filepath.Join(source, "backups"), filepath.Join(data, "backups.migrating")) {
+		t.Error("expected the partial copy to differ")
+	}
+
+	moves := []Move{
+		{From: filepath.Join(source, "config.yaml"), To: filepath.Join(root, "config", "config.yaml")},
+		{From: filepath.Join(source, "repos"), To: filepath.Join(data, "repos")},
+		{From: filepath.Join(source, "backups"), To: filepath.Join(data, "backups")},
+	}
+
+	if err := Migrate(source, moves); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{source, filepath.Join(data, "backups.migrating")} {
+		if exists, _ := ObjectExists(path); exists {
+			t.Errorf("expected %v to be removed", path)
+		}
+	}
+
+	for path, content := range map[string]string{
+		filepath.Join(root, "config", "config.yaml"): "accounts: []\n",
+		filepath.Join(data, "repos", "master", "a"):  "spec",
+		filepath.Join(data, "backups", "x"):          "backup",
+	} {
+		if read, err := ioutil.ReadFile(path); err != nil || string(read) != content {
+			t.Errorf("%v contains %q, %v, want %q", path, read, err, content)
+		}
+	}
+}
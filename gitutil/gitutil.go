@@ -3,18 +3,21 @@ package gitutil
 
 import (
 	"bytes"
+	"context"
 	"errors"
-	"fmt"
-	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"gopkg.in/src-d/go-git.v4"
 
 	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/fileutil"
 	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackconfig"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racklog"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackmetrics"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackretry"
 )
 
 // MasterRepo returns the default RackRepo for RackJobber
@@ -22,9 +25,32 @@ func MasterRepo() string {
 	return "https://github.com/worldiety/Rackspecs.git"
 }
 
-// Fetch will fetch a repository and will ask for authentication if necessary
-func Fetch(repo git.Repository, domain string, opts git.FetchOptions) error {
-	err := repo.Fetch(&opts)
+// Fetch will fetch a repository and will ask for authentication if necessary.
+// The fetch is aborted after the git timeout or when the context is done, and retried with the retry policy.
+func Fetch(ctx context.Context, repo git.Repository, domain string, opts git.FetchOptions) error {
+	defer measure(time.Now())
+
+	return rackretry.Do(ctx, rackconfig.GetRetryPolicy(nil), "git fetch", func() error {
+		err := rackretry.WithTimeout(ctx, rackconfig.GetTimeouts(nil).GetGit(), "git fetch",
+			func(ctx context.Context) error {
+				return fetch(ctx, repo, domain, opts)
+			})
+		if err == git.NoErrAlreadyUpToDate || checkForAuthenticationError(err) {
+			return rackretry.Stop(err)
+		}
+
+		return err
+	})
+}
+
+//measure counts a git operation, that started at start, for the metrics of the run
+func measure(start time.Time) {
+	rackmetrics.AddGit(time.Since(start))
+}
+
+//fetch fetches a repository once and asks for authentication if necessary
+func fetch(ctx context.Context, repo git.Repository, domain string, opts git.FetchOptions) error {
+	err := repo.FetchContext(ctx, &opts)
 
 	if checkForAuthenticationError(err) {
 		auth := rackconfig.GetGITAuth(domain)
@@ -39,9 +65,9 @@ func Fetch(repo git.Repository, domain string, opts git.FetchOptions) error {
 			Force:      opts.Force,
 		}
 
-		err = repo.Fetch(&authOpts)
+		err = repo.FetchContext(ctx, &authOpts)
 		if checkForAuthenticationError(err) {
-			log.Fatalln("Username or Password are wrong - Aborting")
+			racklog.Fatalf("Username or Password are wrong - Aborting")
 
 			err = errors.New("Username or password are wrong")
 		}
@@ -50,14 +76,32 @@ func Fetch(repo git.Repository, domain string, opts git.FetchOptions) error {
 	return err
 }
 
-// Clone will clone a repository to a specified path
-func Clone(path string, opts git.CloneOptions) (*git.Repository, error) {
-	repository, err := git.PlainClone(path, false, &opts)
+// Clone will clone a repository to a specified path.
+// The clone is aborted after the git timeout or when the context is done.
+func Clone(ctx context.Context, path string, opts git.CloneOptions) (*git.Repository, error) {
+	defer measure(time.Now())
+
+	var repository *git.Repository
+
+	err := rackretry.WithTimeout(ctx, rackconfig.GetTimeouts(nil).GetGit(), "git clone",
+		func(ctx context.Context) error {
+			var err error
+			repository, err = clone(ctx, path, opts)
+
+			return err
+		})
+
+	return repository, err
+}
+
+//clone clones a repository once and asks for authentication if necessary
+func clone(ctx context.Context, path string, opts git.CloneOptions) (*git.Repository, error) {
+	repository, err := git.PlainCloneContext(ctx, path, false, &opts)
 
 	if checkForAuthenticationError(err) {
 		err = os.RemoveAll(path + ".git")
 		if err != nil {
-			log.Printf("Failed to remove .git directory: %v\n", err)
+			racklog.Warnf("Failed to remove .git directory: %v\n", err)
 		}
 
 		domain := strings.Split(opts.URL, "/")[2]
@@ -76,9 +120,9 @@ func Clone(path string, opts git.CloneOptions) (*git.Repository, error) {
 			Tags:              opts.Tags,
 		}
 
-		repository, err = git.PlainClone(path, false, &authOpts)
+		repository, err = git.PlainCloneContext(ctx, path, false, &authOpts)
 		if checkForAuthenticationError(err) {
-			log.Fatalln("Username or Password are wrong - Aborting")
+			racklog.Fatalf("Username or Password are wrong - Aborting")
 
 			err = errors.New("Username or password are wrong")
 		}
@@ -87,16 +131,27 @@ func Clone(path string, opts git.CloneOptions) (*git.Repository, error) {
 	if err != nil {
 		err = os.Remove(path)
 		if err != nil {
-			log.Printf("Failed to remove directory: %v\n", err)
+			racklog.Warnf("Failed to remove directory: %v\n", err)
 		}
 	}
 
 	return repository, err
 }
 
-// Update will update a git worktree and will ask for authentication if necessary
-func Update(worktree git.Worktree, domain string, opts git.PullOptions) error {
-	err := worktree.Pull(&opts)
+// Update will update a git worktree and will ask for authentication if necessary.
+// The update is aborted after the git timeout or when the context is done.
+func Update(ctx context.Context, worktree git.Worktree, domain string, opts git.PullOptions) error {
+	defer measure(time.Now())
+
+	return rackretry.WithTimeout(ctx, rackconfig.GetTimeouts(nil).GetGit(), "git pull",
+		func(ctx context.Context) error {
+			return update(ctx, worktree, domain, opts)
+		})
+}
+
+//update pulls a git worktree once and asks for authentication if necessary
+func update(ctx context.Context, worktree git.Worktree, domain string, opts git.PullOptions) error {
+	err := worktree.PullContext(ctx, &opts)
 
 	if checkForAuthenticationError(err) {
 		auth := rackconfig.GetGITAuth(domain)
@@ -112,10 +167,10 @@ func Update(worktree git.Worktree, domain string, opts git.PullOptions) error {
 			Force:             opts.Force,
 		}
 
-		err = worktree.Pull(&authOpts)
+		err = worktree.PullContext(ctx, &authOpts)
 
 		if checkForAuthenticationError(err) {
-			log.Fatalln("Username or Password are wrong - Aborting")
+			racklog.Fatalf("Username or Password are wrong - Aborting")
 
 			err = errors.New("Username or password are wrong")
 		}
@@ -124,9 +179,20 @@ func Update(worktree git.Worktree, domain string, opts git.PullOptions) error {
 	return err
 }
 
-// Push will push the current worktree of a repository to its origin and will ask for authentication if necessary
-func Push(repo git.Repository, domain string, opts git.PushOptions) error {
-	err := repo.Push(&opts)
+// Push will push the current worktree of a repository to its origin and will ask for authentication if necessary.
+// The push is aborted after the git timeout or when the context is done.
+func Push(ctx context.Context, repo git.Repository, domain string, opts git.PushOptions) error {
+	defer measure(time.Now())
+
+	return rackretry.WithTimeout(ctx, rackconfig.GetTimeouts(nil).GetGit(), "git push",
+		func(ctx context.Context) error {
+			return push(ctx, repo, domain, opts)
+		})
+}
+
+//push pushes a repository once and asks for authentication if necessary
+func push(ctx context.Context, repo git.Repository, domain string, opts git.PushOptions) error {
+	err := repo.PushContext(ctx, &opts)
 	if checkForAuthenticationError(err) {
 		auth := rackconfig.GetGITAuth(domain)
 
@@ -137,10 +203,10 @@ func Push(repo git.Repository, domain string, opts git.PushOptions) error {
 			Progress:   opts.Progress,
 		}
 
-		err = repo.Push(&authOpts)
+		err = repo.PushContext(ctx, &authOpts)
 
 		if checkForAuthenticationError(err) {
-			log.Fatalln("Username or Password are wrong - Aborting")
+			racklog.Fatalf("Username or Password are wrong - Aborting")
 
 			err = errors.New("Username or password are wrong")
 		}
@@ -198,17 +264,17 @@ func GetURLForRepo(repo git.Repository) (*string, error) {
 
 //ReinstallMaster deletes the Rackspec Master-Repository and clones it again
 func ReinstallMaster() error {
-	fmt.Println("updating Master Repository")
+	racklog.Infof("updating Master Repository")
 
 	path, err := getRepoStorePath()
 	if err != nil {
-		log.Printf("failed to retrieve RepoStorePath: %v\n", err)
+		racklog.Warnf("failed to retrieve RepoStorePath: %v\n", err)
 		return err
 	}
 
 	err = fileutil.DeleteDirectory(*path)
 	if err != nil {
-		log.Printf("Failed to delete Master Repository. Aborting reinstall: %v\n", err)
+		racklog.Warnf("Failed to delete Master Repository. Aborting reinstall: %v\n", err)
 		return err
 	}
 
@@ -217,7 +283,7 @@ func ReinstallMaster() error {
 		return err
 	}
 
-	fmt.Println("successfully updated Master Repository")
+	racklog.Infof("successfully updated Master Repository")
 
 	return nil
 }
@@ -226,24 +292,24 @@ func ReinstallMaster() error {
 func SetupMaster() error {
 	repoPath, err := getRepoStorePath()
 	if err != nil {
-		log.Fatalf("GetRepoPath - error: %v\n", err)
+		racklog.Fatalf("GetRepoPath - error: %v\n", err)
 		return err
 	}
 
 	if repoPath == nil {
-		log.Fatalln("RepoPath is unexpectedly nil")
+		racklog.Fatalf("RepoPath is unexpectedly nil")
 		return errors.New("Unexpected nil value")
 	}
 
 	masterPath := filepath.Join(*repoPath, "master")
 
-	_, err = Clone(masterPath, git.CloneOptions{
+	_, err = Clone(context.Background(), masterPath, git.CloneOptions{
 		URL:      MasterRepo(),
-		Progress: os.Stdout,
+		Progress: racklog.Progress(),
 	})
 
 	if err != nil {
-		log.Fatalf("PlainClone - error: %v\n", err)
+		racklog.Fatalf("PlainClone - error: %v\n", err)
 	}
 
 	return err
@@ -269,18 +335,46 @@ func GetURLWithAuth(url string) string {
 	return strings.Replace(url, "https://", "https://"+auth.Username+":"+auth.Password+"@", -1)
 }
 
-// GetHashOfLastCommit retrieves the Hash-value of the latest commit of a given repository
-func GetHashOfLastCommit(url, version string) (*string, error) {
+// StripURLAuth removes the credentials, that GetURLWithAuth added to an URL
+func StripURLAuth(url string) string {
+	const scheme = "https://"
+
+	if !strings.HasPrefix(url, scheme) {
+		return url
+	}
+
+	rest := strings.TrimPrefix(url, scheme)
+	host := strings.SplitN(rest, "/", 2)[0]
+
+	if at := strings.LastIndex(host, "@"); at >= 0 {
+		return scheme + rest[at+1:]
+	}
+
+	return url
+}
+
+// GetHashOfLastCommit retrieves the Hash-value of the latest commit of a given repository.
+// The listing is aborted after the ls-remote timeout or when the context is done, and retried with the retry policy.
+func GetHashOfLastCommit(ctx context.Context, url, version string) (*string, error) {
+	defer measure(time.Now())
+
 	filledURL := GetURLWithAuth(url)
-	command := *exec.Command("git", "ls-remote", "--tags", filledURL) //nolint, as it is a listing command
+	timeout := rackconfig.GetTimeouts(nil).GetLsRemote()
 
 	var out bytes.Buffer
 
-	command.Stdout = &out
+	err := rackretry.Do(ctx, rackconfig.GetRetryPolicy(nil), "git ls-remote", func() error {
+		return rackretry.WithTimeout(ctx, timeout, "git ls-remote", func(ctx context.Context) error {
+			out.Reset()
 
-	err := command.Run()
+			command := exec.CommandContext(ctx, "git", "ls-remote", "--tags", filledURL) //nolint, as it is a listing command
+			command.Stdout = &out
+
+			return command.Run()
+		})
+	})
 	if err != nil {
-		log.Fatalf("Failed to run 'git ls-remote' command, err: %v\n", err)
+		return nil, errors.New("failed to run 'git ls-remote': " + err.Error())
 	}
 
 	var hash string
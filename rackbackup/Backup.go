@@ -0,0 +1,179 @@
+// Package rackbackup includes functions to back up the database, the plugins and the deployment state of a shop
+// before a deployment and to restore them.
+// Backups are stored on the shop outside of its shopware directory or downloaded to the executing machine.
+package rackbackup
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racklog"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshop"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshopdb"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackssh"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackstate"
+)
+
+// Files of a backup
+const (
+	// LegacyBackupDir is the directory of the shopware directory, that older versions stored the backups in.
+	// Its backups are moved to the backup directory of the shop, when the backups are listed.
+	LegacyBackupDir = rackstate.StateDir + "/backups"
+	DatabaseFile    = "database.sql.gz"
+	PluginsFile     = "plugins.tar.gz"
+	StateFile       = "state.yaml"
+	MetaFile        = "backup.json"
+)
+
+// Locations of a backup
+const (
+	LocationShop  = "shop"
+	LocationLocal = "local"
+)
+
+// DefaultKeep is the number of backups, that are kept of a shop by default
+const DefaultKeep = 5
+
+// Backup describes a backup of a shop
+type Backup struct {
+	ID        string    `json:"id"`
+	Shop      string    `json:"shop"`
+	CreatedAt time.Time `json:"createdAt"`
+	User      string    `json:"user,omitempty"`
+	Host      string    `json:"host,omitempty"`
+	// RunID is the run, that the backup was created before
+	RunID string `json:"runId,omitempty"`
+	// HasState is set, if the shop had a deployment state, when the backup was created
+	HasState bool `json:"hasState"`
+	// Location is where the backup is stored, it is set when the backups are listed
+	Location string `json:"location"`
+}
+
+// Options decide where backups are stored and how many of them are kept
+type Options struct {
+	// Local downloads the backup to the executing machine and removes it from the shop
+	Local bool
+	// Keep is the number of backups of the shop kept at the location of the new backup, older ones are deleted.
+	// All backups are kept, if it is not positive.
+	Keep int
+}
+
+// Create backs up the database, the plugins and the deployment state of a shop
+func Create(executor rackssh.Executor, shop *rackshop.RackShop, runID string, opts Options) (*Backup, error) {
+	user, host := rackstate.Deployer()
+	backup := &Backup{
+		ID:        rackstate.NewRunID(),
+		Shop:      shop.Name,
+		CreatedAt: time.Now().UTC(),
+		User:      user,
+		Host:      host,
+		RunID:     runID,
+		Location:  LocationShop,
+	}
+
+	dir := shopBackupDir(shop, backup.ID)
+	statePath := filepath.Join(shop.ShopwareDir, rackstate.StateFile)
+	database := filepath.Join(dir, strings.TrimSuffix(DatabaseFile, ".gz"))
+
+	racklog.With("shop", shop.Name).Infof("Creating backup %v of %v.", backup.ID, shop.Name)
+
+	// the files are only readable by the user of the shop, the dump contains the data of the customers
+	commands := []string{
+		"umask 077 && mkdir -p " + rackssh.QuoteArgument(dir) + " && chmod 700 " +
+			rackssh.QuoteArgument(shop.GetBackupDir()) + " " + rackssh.QuoteArgument(dir),
+		"umask 077 && " + rackshopdb.DumpCommand(shop) + " > " + rackssh.QuoteArgument(database) +
+			" && gzip -f " + rackssh.QuoteArgument(database) +
+			" && chmod 600 " + rackssh.QuoteArgument(filepath.Join(dir, DatabaseFile)),
+		"umask 077 && tar -czf " + rackssh.QuoteArgument(filepath.Join(dir, PluginsFile)) +
+			" -C " + rackssh.QuoteArgument(filepath.Join(shop.ShopwareDir, "custom")) + " plugins",
+	}
+
+	for _, command := range commands {
+		if out, err := executor.Run(command); err != nil {
+			executor.Run("rm -rf " + rackssh.QuoteArgument(dir)) //nolint, the failed backup is removed as far as possible
+			return nil, errors.New("backup failed: " + strings.TrimSpace(out))
+		}
+	}
+
+	if _, err := executor.Run("umask 077 && cp " + rackssh.QuoteArgument(statePath) + " " +
+		rackssh.QuoteArgument(filepath.Join(dir, StateFile))); err == nil {
+		backup.HasState = true
+	}
+
+	data, err := json.MarshalIndent(backup, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	if err = executor.WriteFile(filepath.Join(dir, MetaFile), data); err != nil {
+		return nil, err
+	}
+
+	if opts.Local {
+		if err = download(executor, shop, backup); err != nil {
+			return nil, err
+		}
+	}
+
+	return backup, prune(executor, shop, backup.Location, opts.Keep)
+}
+
+// Restore restores the database, the plugins and the deployment state of a shop from a backup
+// and clears the cache of the shop
+func Restore(executor rackssh.Executor, shop *rackshop.RackShop, backup Backup) error {
+	dir := shopBackupDir(shop, backup.ID)
+
+	if backup.Location == LocationLocal {
+		if err := upload(executor, shop, backup); err != nil {
+			return err
+		}
+
+		defer executor.Run("rm -rf " + rackssh.QuoteArgument(dir)) //nolint, the uploaded copy is removed as far as possible
+	}
+
+	custom := filepath.Join(shop.ShopwareDir, "custom")
+	plugins := rackssh.QuoteArgument(filepath.Join(custom, "plugins"))
+	restoreDir := rackssh.QuoteArgument(filepath.Join(custom, ".plugins-restore"))
+	restoredPlugins := rackssh.QuoteArgument(filepath.Join(custom, ".plugins-restore", "plugins"))
+
+	commands := []string{
+		"gunzip -c " + rackssh.QuoteArgument(filepath.Join(dir, DatabaseFile)) + " | " + rackshopdb.RestoreCommand(shop),
+		"rm -rf " + restoreDir + " && mkdir -p " + restoreDir +
+			" && tar -xzf " + rackssh.QuoteArgument(filepath.Join(dir, PluginsFile)) + " -C " + restoreDir,
+		"rm -rf " + plugins + " && mv " + restoredPlugins + " " + plugins + " && rm -rf " + restoreDir,
+	}
+
+	if backup.HasState {
+		commands = append(commands, "mkdir -p "+rackssh.QuoteArgument(filepath.Join(shop.ShopwareDir, rackstate.StateDir))+
+			" && cp "+rackssh.QuoteArgument(filepath.Join(dir, StateFile))+" "+
+			rackssh.QuoteArgument(filepath.Join(shop.ShopwareDir, rackstate.StateFile)))
+	}
+
+	commands = append(commands, rackssh.ConsoleCommand(shop, "sw:cache:clear"))
+
+	for _, command := range commands {
+		if out, err := executor.Run(command); err != nil {
+			return errors.New("restore failed: " + strings.TrimSpace(out))
+		}
+	}
+
+	return nil
+}
+
+//shopBackupDir returns the directory of a backup on the shop
+func shopBackupDir(shop *rackshop.RackShop, id string) string {
+	return filepath.Join(shop.GetBackupDir(), id)
+}
+
+//backupFiles returns the files of a backup
+func backupFiles(backup Backup) []string {
+	files := []string{MetaFile, DatabaseFile, PluginsFile}
+	if backup.HasState {
+		files = append(files, StateFile)
+	}
+
+	return files
+}
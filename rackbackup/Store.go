@@ -0,0 +1,293 @@
+package rackbackup
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/fileutil"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshop"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackssh"
+)
+
+// List returns the backups of a shop stored on the shop and on the executing machine, the oldest first
+func List(executor rackssh.Executor, shop *rackshop.RackShop) ([]Backup, error) {
+	backups, err := listShopBackups(executor, shop)
+	if err != nil {
+		return nil, err
+	}
+
+	localBackups, err := listLocalBackups(shop)
+	if err != nil {
+		return nil, err
+	}
+
+	backups = append(backups, localBackups...)
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].ID < backups[j].ID
+	})
+
+	return backups, nil
+}
+
+// Find returns the backup with the given ID, a unique prefix of it or "latest"
+func Find(backups []Backup, backupID string) (*Backup, error) {
+	if len(backups) == 0 {
+		return nil, errors.New("the shop has no backups")
+	}
+
+	if backupID == "latest" {
+		return &backups[len(backups)-1], nil
+	}
+
+	matches := []Backup{}
+
+	for _, backup := range backups {
+		if backup.ID == backupID {
+			return &backup, nil
+		}
+
+		if strings.HasPrefix(backup.ID, backupID) {
+			matches = append(matches, backup)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, errors.New("no backup found for " + backupID)
+	case 1:
+		return &matches[0], nil
+	default:
+		return nil, errors.New("the backup " + backupID + " is ambiguous")
+	}
+}
+
+//listShopBackups returns the backups stored on the shop
+func listShopBackups(executor rackssh.Executor, shop *rackshop.RackShop) ([]Backup, error) {
+	dir := shop.GetBackupDir()
+
+	out, err := executor.Run(moveLegacyBackupsCommand(shop) + " && ls -1 " + rackssh.QuoteArgument(dir))
+	if err != nil {
+		return nil, errors.New("failed to list the backups: " + strings.TrimSpace(out))
+	}
+
+	backups := []Backup{}
+
+	for _, id := range strings.Split(out, "\n") {
+		if id = strings.TrimSpace(id); len(id) == 0 {
+			continue
+		}
+
+		data, err := executor.ReadFile(filepath.Join(dir, id, MetaFile))
+		if err != nil {
+			continue
+		}
+
+		backup := Backup{}
+		if err = json.Unmarshal(data, &backup); err != nil {
+			return nil, errors.New("failed to parse backup " + id + ": " + err.Error())
+		}
+
+		backup.Location = LocationShop
+		backups = append(backups, backup)
+	}
+
+	return backups, nil
+}
+
+//moveLegacyBackupsCommand returns the command, that creates the backup directory of the shop and moves the backups
+//of the legacy directory inside of the shopware directory to it, only readable by the user of the shop
+func moveLegacyBackupsCommand(shop *rackshop.RackShop) string {
+	dir := rackssh.QuoteArgument(shop.GetBackupDir())
+	legacy := rackssh.QuoteArgument(filepath.Join(shop.ShopwareDir, LegacyBackupDir))
+
+	return "umask 077 && mkdir -p " + dir + " && chmod 700 " + dir +
+		" && if [ -d " + legacy + " ]; then for backup in " + legacy + "/*; do if [ -e \"$backup\" ]; then mv \"$backup\" " +
+		dir + "/ || exit 1; fi; done; rmdir " + legacy + " 2>/dev/null; chmod -R go= " + dir + "; fi"
+}
+
+//listLocalBackups returns the backups of the shop downloaded to the executing machine
+func listLocalBackups(shop *rackshop.RackShop) ([]Backup, error) {
+	dir, err := localBackupDir(shop, "")
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return []Backup{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	backups := []Backup{}
+
+	for _, entry := range entries {
+		data, err := ioutil.ReadFile(filepath.Join(dir, entry.Name(), MetaFile)) //nolint, the path belongs to a backup
+		if err != nil {
+			continue
+		}
+
+		backup := Backup{}
+		if err = json.Unmarshal(data, &backup); err != nil {
+			return nil, errors.New("failed to parse backup " + entry.Name() + ": " + err.Error())
+		}
+
+		backup.Location = LocationLocal
+		backups = append(backups, backup)
+	}
+
+	return backups, nil
+}
+
+//localBackupDir returns the directory of a backup of the shop on the executing machine,
+//or the directory of all its backups if the id is empty
+func localBackupDir(shop *rackshop.RackShop, id string) (string, error) {
+	appFolder, err := fileutil.GetAppFolderPath()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(*appFolder, "backups", shop.Name, id), nil
+}
+
+//download copies a backup from the shop to the executing machine and removes it from the shop
+func download(executor rackssh.Executor, shop *rackshop.RackShop, backup *Backup) error {
+	dir, err := localBackupDir(shop, backup.ID)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	for _, file := range backupFiles(*backup) {
+		if err := downloadFile(executor, filepath.Join(shopBackupDir(shop, backup.ID), file),
+			filepath.Join(dir, file)); err != nil {
+			return errors.New("failed to download " + file + " of backup " + backup.ID + ": " + err.Error())
+		}
+	}
+
+	backup.Location = LocationLocal
+
+	if out, err := executor.Run("rm -rf " + rackssh.QuoteArgument(shopBackupDir(shop, backup.ID))); err != nil {
+		return errors.New("failed to remove the downloaded backup from the shop: " + strings.TrimSpace(out))
+	}
+
+	return nil
+}
+
+//downloadFile streams a file of the shop to a local path, a partially written file is removed
+func downloadFile(executor rackssh.Executor, remotePath string, localPath string) error {
+	file, err := os.OpenFile(localPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	err = executor.CopyFileTo(remotePath, file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(localPath) //nolint, the partial file is only removed as far as possible
+	}
+
+	return err
+}
+
+//upload copies a downloaded backup back to the shop
+func upload(executor rackssh.Executor, shop *rackshop.RackShop, backup Backup) error {
+	dir, err := localBackupDir(shop, backup.ID)
+	if err != nil {
+		return err
+	}
+
+	if out, err := executor.Run(moveLegacyBackupsCommand(shop) + " && mkdir -p " +
+		rackssh.QuoteArgument(shopBackupDir(shop, backup.ID))); err != nil {
+		return errors.New("failed to upload the backup: " + strings.TrimSpace(out))
+	}
+
+	for _, file := range backupFiles(backup) {
+		if err := uploadFile(executor, filepath.Join(dir, file),
+			filepath.Join(shopBackupDir(shop, backup.ID), file)); err != nil {
+			return errors.New("failed to upload " + file + " of backup " + backup.ID + ": " + err.Error())
+		}
+	}
+
+	return nil
+}
+
+//uploadFile streams a local file to a path on the shop
+func uploadFile(executor rackssh.Executor, localPath string, remotePath string) error {
+	file, err := os.Open(localPath) //nolint, the path belongs to a backup
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	return executor.CopyFileFrom(remotePath, file)
+}
+
+//prune deletes the oldest backups of the shop at the location, so only keep backups remain
+func prune(executor rackssh.Executor, shop *rackshop.RackShop, location string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+
+	backups, err := List(executor, shop)
+	if err != nil {
+		return err
+	}
+
+	for _, backup := range expiredBackups(backups, location, keep) {
+		if err := Delete(executor, shop, backup); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//expiredBackups returns the backups at the location, that are older than the latest keep backups there.
+//The backups have to be sorted, the oldest first.
+func expiredBackups(backups []Backup, location string, keep int) []Backup {
+	atLocation := []Backup{}
+
+	for _, backup := range backups {
+		if backup.Location == location {
+			atLocation = append(atLocation, backup)
+		}
+	}
+
+	if keep <= 0 || len(atLocation) <= keep {
+		return []Backup{}
+	}
+
+	return atLocation[:len(atLocation)-keep]
+}
+
+// Delete removes a backup from its location
+func Delete(executor rackssh.Executor, shop *rackshop.RackShop, backup Backup) error {
+	if backup.Location == LocationLocal {
+		dir, err := localBackupDir(shop, backup.ID)
+		if err != nil {
+			return err
+		}
+
+		return os.RemoveAll(dir)
+	}
+
+	if out, err := executor.Run("rm -rf " + rackssh.QuoteArgument(shopBackupDir(shop, backup.ID))); err != nil {
+		return errors.New("failed to delete backup " + backup.ID + ": " + strings.TrimSpace(out))
+	}
+
+	return nil
+}
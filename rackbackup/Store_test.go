@@ -0,0 +1,132 @@
+package rackbackup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/fileutil"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshop"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackssh"
+)
+
+func TestExpiredBackups(t *testing.T) {
+	backups := []Backup{
+		{ID: "1", Location: LocationShop},
+		{ID: "2", Location: LocationLocal},
+		{ID: "3", Location: LocationShop},
+		{ID: "4", Location: LocationShop},
+		{ID: "5", Location: LocationLocal},
+	}
+
+	tests := []struct {
+		name     string
+		location string
+		keep     int
+		want     []string
+	}{
+		{name: "keep all", location: LocationShop, keep: 0, want: []string{}},
+		{name: "fewer than kept", location: LocationShop, keep: 5, want: []string{}},
+		{name: "exactly kept", location: LocationLocal, keep: 2, want: []string{}},
+		{name: "oldest of the shop", location: LocationShop, keep: 2, want: []string{"1"}},
+		{name: "only the latest of the shop", location: LocationShop, keep: 1, want: []string{"1", "3"}},
+		{name: "other location untouched", location: LocationLocal, keep: 1, want: []string{"2"}},
+	}
+
+	for _, test := range tests {
+		ids := []string{}
+		for _, backup := range expiredBackups(backups, test.location, test.keep) {
+			ids = append(ids, backup.ID)
+		}
+
+		if !reflect.DeepEqual(ids, test.want) {
+			t.Errorf("%v: got %v, want %v", test.name, ids, test.want)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	backups := []Backup{{ID: "20260101-aaa"}, {ID: "20260102-bbb"}, {ID: "20260102-bbc"}}
+
+	tests := []struct {
+		id      string
+		want    string
+		wantErr bool
+	}{
+		{id: "latest", want: "20260102-bbc"},
+		{id: "20260101-aaa", want: "20260101-aaa"},
+		{id: "20260101", want: "20260101-aaa"},
+		{id: "20260102-bb", wantErr: true},
+		{id: "2025", wantErr: true},
+	}
+
+	for _, test := range tests {
+		backup, err := Find(backups, test.id)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("Find(%q): expected an error", test.id)
+			}
+
+			continue
+		}
+
+		if err != nil || backup.ID != test.want {
+			t.Errorf("Find(%q) = %+v, %v, want %v", test.id, backup, err, test.want)
+		}
+	}
+
+	if _, err := Find([]Backup{}, "latest"); err == nil {
+		t.Error("expected an error for a shop without backups")
+	}
+}
+
+func TestListMovesLegacyBackups(t *testing.T) {
+	root, err := ioutil.TempDir("", "rackjobber")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(root)
+
+	if err := fileutil.SetHome(filepath.Join(root, "home")); err != nil {
+		t.Fatal(err)
+	}
+
+	shop := &rackshop.RackShop{Name: "my-shop", Local: true, ShopwareDir: filepath.Join(root, "html")}
+	legacy := filepath.Join(shop.ShopwareDir, LegacyBackupDir, "20260101-aaa")
+
+	if err := os.MkdirAll(legacy, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	meta := []byte(`{"id": "20260101-aaa", "shop": "my-shop"}`)
+	if err := ioutil.WriteFile(filepath.Join(legacy, MetaFile), meta, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	backups, err := List(rackssh.NewExecutor(shop), shop)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(backups) != 1 || backups[0].ID != "20260101-aaa" || backups[0].Location != LocationShop {
+		t.Errorf("got backups %+v, want the moved legacy backup", backups)
+	}
+
+	if exists, _ := fileutil.ObjectExists(filepath.Join(shop.ShopwareDir, LegacyBackupDir)); exists {
+		t.Error("expected the legacy backup directory to be removed")
+	}
+
+	dir := filepath.Join(root, "rackjobber-backups", "my-shop")
+
+	for path, mode := range map[string]os.FileMode{
+		dir: 0700,
+		filepath.Join(dir, "20260101-aaa", MetaFile): 0600,
+	} {
+		if info, err := os.Stat(path); err != nil || info.Mode().Perm() != mode {
+			t.Errorf("%v: got %v, %v, want mode %v", path, info, err, mode)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package rackcommands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/urfave/cli"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackbackup"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackinput"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshopstore"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackssh"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackup"
+)
+
+// BackupCommand is used to create, list and restore backups of a shop
+func BackupCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "backup",
+		Usage: "Create, list and restore backups of the database, the plugins and the deployment state of a shop",
+		Subcommands: []*cli.Command{
+			backupCreateSubcommand(),
+			backupListSubcommand(),
+			backupRestoreSubcommand(),
+		},
+	}
+}
+
+func backupShopFlags() []cli.Flag {
+	return shopFlags("The name of the shop or group, whose backups shall be managed")
+}
+
+//backupStorageFlags returns the flags deciding where backups are stored and how many are kept
+func backupStorageFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "backupLocal",
+			Usage: "Download the backup to this machine instead of keeping it on the shop",
+		},
+		&cli.IntFlag{
+			Name:  "backupKeep",
+			Usage: "The number of backups of the shop to keep, older ones are deleted. 0 keeps all backups",
+			Value: rackbackup.DefaultKeep,
+		},
+	}
+}
+
+//backupOptions returns the options given by the backupStorageFlags
+func backupOptions(c *cli.Context) rackbackup.Options {
+	return rackbackup.Options{Local: c.Bool("backupLocal"), Keep: c.Int("backupKeep")}
+}
+
+func backupCreateSubcommand() *cli.Command {
+	return &cli.Command{
+		Name:  "create",
+		Usage: "Backs up the database, the plugins and the deployment state of a shop",
+		Flags: append(backupShopFlags(), backupStorageFlags()...),
+		Action: func(c *cli.Context) error {
+			shops, err := resolveShopNames(c)
+			if err != nil {
+				return err
+			}
+
+			created := []rackbackup.Backup{}
+
+			err = forEachShop(c, shops, func(shopName string) error {
+				shop, err := rackshopstore.GetShopFromStore(shopName)
+				if err != nil {
+					return err
+				}
+
+				backup, err := rackbackup.Create(rackssh.NewExecutor(shop), shop, "", backupOptions(c))
+				if err != nil {
+					return err
+				}
+
+				created = append(created, *backup)
+				fmt.Fprintf(textWriter(c), "Created backup %v of %v (%v).\n", backup.ID, shopName, backup.Location)
+
+				return nil
+			})
+
+			if jsonOutput(c) {
+				if jsonErr := printJSON(created); jsonErr != nil {
+					return jsonErr
+				}
+			}
+
+			return err
+		},
+	}
+}
+
+func backupListSubcommand() *cli.Command {
+	return &cli.Command{
+		Name:  "list",
+		Usage: "Lists the backups of a shop stored on the shop and on this machine",
+		Flags: backupShopFlags(),
+		Action: func(c *cli.Context) error {
+			asJSON := jsonOutput(c)
+
+			shops, err := resolveShopNames(c)
+			if err != nil {
+				return err
+			}
+
+			listed := []rackbackup.Backup{}
+
+			err = forEachShop(c, shops, func(shopName string) error {
+				shop, err := rackshopstore.GetShopFromStore(shopName)
+				if err != nil {
+					return err
+				}
+
+				backups, err := rackbackup.List(rackssh.NewExecutor(shop), shop)
+				if err != nil {
+					return err
+				}
+
+				if asJSON {
+					listed = append(listed, backups...)
+					return nil
+				}
+
+				if len(backups) == 0 {
+					fmt.Println("No backups of " + shopName + ".")
+				}
+
+				for _, backup := range backups {
+					fmt.Printf("%v  %-5v  %v@%v  run %v\n", backup.ID, backup.Location, backup.User, backup.Host,
+						backup.RunID)
+				}
+
+				return nil
+			})
+
+			if asJSON {
+				if jsonErr := printJSON(listed); jsonErr != nil {
+					return jsonErr
+				}
+			}
+
+			return err
+		},
+	}
+}
+
+func backupRestoreSubcommand() *cli.Command {
+	return &cli.Command{
+		Name:  "restore",
+		Usage: "Restores the database, the plugins and the deployment state of a shop from a backup",
+		Flags: append(backupShopFlags(),
+			&cli.StringFlag{
+				Name:  "backup, b",
+				Usage: "The ID of the backup, a unique prefix of it or latest",
+				Value: "latest",
+			},
+			&cli.BoolFlag{
+				Name:  "yes, y",
+				Usage: "Restore without asking for confirmation",
+			},
+			lockWaitFlag(),
+		),
+		Action: func(c *cli.Context) error {
+			shops, err := resolveShopNames(c)
+			if err != nil {
+				return err
+			}
+
+			return forEachShop(c, shops, func(shopName string) error {
+				return restoreBackup(textWriter(c), shopName, c.String("backup"), c.Bool("yes"), c.Duration("wait"))
+			})
+		},
+	}
+}
+
+//restoreBackup restores the backup of the shop after asking for confirmation, unless yes is set.
+//The shop is locked like by up, wait is how long to wait for the lock. The outcome is printed to out.
+func restoreBackup(out io.Writer, shopName string, backupID string, yes bool, wait time.Duration) error {
+	shop, err := rackshopstore.GetShopFromStore(shopName)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := rackup.LockShop(context.Background(), shop, "backup restore", wait)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	executor := rackssh.NewExecutor(shop)
+
+	backups, err := rackbackup.List(executor, shop)
+	if err != nil {
+		return err
+	}
+
+	backup, err := rackbackup.Find(backups, backupID)
+	if err != nil {
+		return err
+	}
+
+	if !yes && !confirmRestore(shopName, backup.ID) {
+		fmt.Fprintln(out, "Restore aborted.")
+		return nil
+	}
+
+	if err = rackbackup.Restore(executor, shop, *backup); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out, "Restored backup "+backup.ID+" on "+shopName+".")
+
+	return nil
+}
+
+//confirmRestore asks the user to confirm, that the database and the plugins of the shop are replaced
+func confirmRestore(shopName string, backupID string) bool {
+	in := ""
+	for in != "y" && in != "n" {
+		in = rackinput.AwaitTextInput("Do you want to replace the database and plugins of " + shopName +
+			" with backup " + backupID + "? (y/n)")
+	}
+
+	return in == "y"
+}
@@ -0,0 +1,64 @@
+package rackcommands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/urfave/cli"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackhealth"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshopstore"
+)
+
+// HealthCommand is used to run the health checks of a shop
+func HealthCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "health",
+		Usage: "Runs the health checks of a shop, that are checked after every up",
+		Flags: shopFlags("The name of the shop or group, that shall be checked"),
+		Action: func(c *cli.Context) error {
+			asJSON := jsonOutput(c)
+
+			shops, err := resolveShopNames(c)
+			if err != nil {
+				return err
+			}
+
+			return forEachShop(c, shops, func(shopName string) error {
+				return checkHealth(shopName, asJSON)
+			})
+		},
+	}
+}
+
+//checkHealth runs the health checks of the shop and prints their results
+func checkHealth(shopName string, asJSON bool) error {
+	shop, err := rackshopstore.GetShopFromStore(shopName)
+	if err != nil {
+		return err
+	}
+
+	if len(shop.HealthChecks) == 0 {
+		return errors.New("the shop " + shopName + " has no health checks")
+	}
+
+	results := rackhealth.CheckShop(context.Background(), shop)
+
+	if asJSON {
+		if err = printJSON(results); err != nil {
+			return err
+		}
+	} else {
+		for _, result := range results {
+			fmt.Println(result.String())
+		}
+	}
+
+	for _, result := range results {
+		if !result.Healthy {
+			return errors.New("the shop " + shopName + " is unhealthy")
+		}
+	}
+
+	return nil
+}
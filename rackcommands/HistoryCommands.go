@@ -0,0 +1,154 @@
+package rackcommands
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/urfave/cli"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackhistory"
+)
+
+// HistoryCommand is used to browse the recorded runs of a shop
+func HistoryCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "history",
+		Usage: "Browse the runs of rackjobber recorded on a shop",
+		Subcommands: []*cli.Command{
+			historyListSubcommand(),
+			historyShowSubcommand(),
+			historyDiffSubcommand(),
+		},
+	}
+}
+
+func historyFlags() []cli.Flag {
+	return shopFlags("The name of the shop or group, whose history shall be shown")
+}
+
+func historyListSubcommand() *cli.Command {
+	return &cli.Command{
+		Name:  "list",
+		Usage: "Lists the recorded runs of a shop",
+		Flags: historyFlags(),
+		Action: func(c *cli.Context) error {
+			asJSON := jsonOutput(c)
+
+			shops, err := resolveShopNames(c)
+			if err != nil {
+				return err
+			}
+
+			listed := []rackhistory.Run{}
+
+			err = forEachShop(c, shops, func(shopName string) error {
+				runs, err := rackhistory.ListRunsOfShop(shopName)
+				if err != nil {
+					return err
+				}
+
+				if asJSON {
+					listed = append(listed, runs...)
+					return nil
+				}
+
+				if len(runs) == 0 {
+					fmt.Println("No runs recorded for " + shopName + ".")
+				}
+
+				for _, run := range runs {
+					run.PrintSummary()
+				}
+
+				return nil
+			})
+
+			if asJSON {
+				if jsonErr := printJSON(listed); jsonErr != nil {
+					return jsonErr
+				}
+			}
+
+			return err
+		},
+	}
+}
+
+func historyShowSubcommand() *cli.Command {
+	return &cli.Command{
+		Name:      "show",
+		Usage:     "Shows the plan, steps and outputs of a recorded run",
+		ArgsUsage: "<run>",
+		Flags:     historyFlags(),
+		Action: func(c *cli.Context) error {
+			asJSON := jsonOutput(c)
+
+			shops, err := resolveShopNames(c)
+			if err != nil {
+				return err
+			}
+
+			runID := c.Args().Get(0)
+			if len(runID) == 0 {
+				runID = "latest"
+			}
+
+			return forEachShop(c, shops, func(shopName string) error {
+				run, err := rackhistory.ReadRunOfShop(shopName, runID)
+				if err != nil {
+					return err
+				}
+
+				if asJSON {
+					return printJSON(run)
+				}
+
+				run.Print()
+
+				return nil
+			})
+		},
+	}
+}
+
+func historyDiffSubcommand() *cli.Command {
+	return &cli.Command{
+		Name:      "diff",
+		Usage:     "Shows the differences of the deployed plugins after two recorded runs",
+		ArgsUsage: "<run1> <run2>",
+		Flags:     historyFlags(),
+		Action: func(c *cli.Context) error {
+			asJSON := jsonOutput(c)
+
+			shops, err := resolveShopNames(c)
+			if err != nil {
+				return err
+			}
+
+			if c.Args().Len() != 2 {
+				return errors.New("two runs are required")
+			}
+
+			return forEachShop(c, shops, func(shopName string) error {
+				from, err := rackhistory.ReadRunOfShop(shopName, c.Args().Get(0))
+				if err != nil {
+					return err
+				}
+
+				to, err := rackhistory.ReadRunOfShop(shopName, c.Args().Get(1))
+				if err != nil {
+					return err
+				}
+
+				diffs := rackhistory.Diff(from, to)
+
+				if asJSON {
+					return printJSON(diffs)
+				}
+
+				rackhistory.PrintDiff(from, to, diffs)
+
+				return nil
+			})
+		},
+	}
+}
@@ -0,0 +1,76 @@
+package rackcommands
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/urfave/cli"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/fileutil"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackinput"
+)
+
+// MigrateCommand is used to move the rackresource folder of older versions to the home of rackjobber
+func MigrateCommand() *cli.Command {
+	return &cli.Command{
+		Name: "migrate",
+		Usage: "Moves the config, the shops and the repositories of a rackresource folder to --home, " +
+			fileutil.HomeEnv + " or the XDG folders",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "from",
+				Usage: "The rackresource folder to migrate, default is the one next to the executable",
+			},
+			&cli.BoolFlag{
+				Name:  "yes, y",
+				Usage: "Migrate without asking for confirmation",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			source := c.String("from")
+			if len(source) == 0 {
+				legacyFolder, err := fileutil.GetLegacyFolderPath()
+				if err != nil {
+					return err
+				}
+
+				source = *legacyFolder
+			}
+
+			if exists, _ := fileutil.ObjectExists(source); !exists {
+				return errors.New("there is no folder to migrate at " + source)
+			}
+
+			moves, err := fileutil.PlanMigration(source)
+			if err != nil {
+				return err
+			}
+
+			for _, move := range moves {
+				fmt.Printf("%v -> %v\n", move.From, move.To)
+			}
+
+			if !c.Bool("yes") && !confirmMigration(source) {
+				fmt.Println("Migration aborted.")
+				return nil
+			}
+
+			if err := fileutil.Migrate(source, moves); err != nil {
+				return err
+			}
+
+			fmt.Println("Migrated " + source + ".")
+
+			return nil
+		},
+	}
+}
+
+//confirmMigration asks the user to confirm, that the entries of the folder are moved
+func confirmMigration(source string) bool {
+	in := ""
+	for in != "y" && in != "n" {
+		in = rackinput.AwaitTextInput("Do you want to move the contents of " + source + "? (y/n)")
+	}
+
+	return in == "y"
+}
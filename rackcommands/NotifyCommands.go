@@ -0,0 +1,75 @@
+package rackcommands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/urfave/cli"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racknotify"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshopstore"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackup"
+)
+
+// NotifyCommand is used to check the notifications, that are sent for the runs on a shop
+func NotifyCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "notify",
+		Usage: "Check the webhooks and chats, that are notified of the runs on a shop",
+		Subcommands: []*cli.Command{
+			notifyTestSubcommand(),
+		},
+	}
+}
+
+//notifyTestSubcommand sends a test notification to every target of a shop
+func notifyTestSubcommand() *cli.Command {
+	return &cli.Command{
+		Name:  "test",
+		Usage: "Send a test notification to the targets of the config, the groups of the shop and the shop",
+		Flags: shopFlags("The name of the shop or group, whose notification targets shall be tested"),
+		Action: func(c *cli.Context) error {
+			shops, err := resolveShopNames(c)
+			if err != nil {
+				return err
+			}
+
+			return forEachShop(c, shops, testNotifications)
+		},
+	}
+}
+
+//testNotifications sends a test notification to every target of the shop
+func testNotifications(shopName string) error {
+	shop, err := rackshopstore.GetShopFromStore(shopName)
+	if err != nil {
+		return err
+	}
+
+	targets := rackup.NotificationTargets(shop)
+	if len(targets) == 0 {
+		return errors.New("no notifications are configured for " + shopName)
+	}
+
+	payload := racknotify.Payload{Event: racknotify.EventTest, Shop: shopName, StartedAt: time.Now().UTC(),
+		Plugins: []racknotify.PluginChange{}}
+	failed := 0
+
+	for _, target := range targets {
+		if err := target.Send(context.Background(), payload); err != nil {
+			fmt.Printf("%v: %v\n", target, err)
+			failed++
+
+			continue
+		}
+
+		fmt.Printf("%v: notified\n", target)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%v of %v notifications failed", failed, len(targets))
+	}
+
+	return nil
+}
@@ -0,0 +1,148 @@
+package rackcommands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/urfave/cli"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/fileutil"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackevent"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racklog"
+)
+
+// Output formats of the commands
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+)
+
+//jsonWriter receives the JSON output and the events of the commands
+var jsonWriter io.Writer = os.Stdout
+
+// GlobalFlags returns the flags, that apply to all commands
+func GlobalFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  "output, o",
+			Usage: "The output format of the commands, text or json",
+			Value: OutputText,
+		},
+		&cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Log only warnings and errors",
+		},
+		&cli.BoolFlag{
+			Name:  "verbose",
+			Usage: "Log details of the commands",
+		},
+		&cli.BoolFlag{
+			Name:  "debug",
+			Usage: "Log everything, including the commands run on the shops and the progress of git",
+		},
+		&cli.StringFlag{
+			Name:  "logFile",
+			Usage: "Append the log to the file, at least with the info messages",
+		},
+		&cli.StringFlag{
+			Name: "home",
+			Usage: "The folder of the config, the shops and the repositories, replaces " + fileutil.HomeEnv +
+				" and the XDG folders",
+		},
+	}
+}
+
+// ValidateGlobalFlags checks the values of the GlobalFlags before a command runs
+func ValidateGlobalFlags(c *cli.Context) error {
+	if output := c.String("output"); output != OutputText && output != OutputJSON {
+		return errors.New("unknown output format " + output + ", must be text or json")
+	}
+
+	level, err := logLevel(c)
+	if err != nil {
+		return err
+	}
+
+	racklog.SetLevel(level)
+
+	if path := c.String("logFile"); path != "" {
+		if err := racklog.OpenFile(path, level); err != nil {
+			return errors.New("failed to open the log file " + path + ": " + err.Error())
+		}
+	}
+
+	if err := fileutil.SetHome(c.String("home")); err != nil {
+		return errors.New("invalid home " + c.String("home") + ": " + err.Error())
+	}
+
+	return nil
+}
+
+//logLevel returns the level of the log set by the quiet, verbose and debug flags, of which only one may be set
+func logLevel(c *cli.Context) (racklog.Level, error) {
+	level := racklog.LevelInfo
+	set := 0
+
+	if c.Bool("quiet") {
+		level = racklog.LevelWarn
+		set++
+	}
+
+	if c.Bool("verbose") {
+		level = racklog.LevelVerbose
+		set++
+	}
+
+	if c.Bool("debug") {
+		level = racklog.LevelDebug
+		set++
+	}
+
+	if set > 1 {
+		return level, errors.New("only one of --quiet, --verbose and --debug may be set")
+	}
+
+	return level, nil
+}
+
+//jsonOutput returns if the command prints JSON because of the global output flag
+func jsonOutput(c *cli.Context) bool {
+	return c.String("output") == OutputJSON
+}
+
+//textWriter returns the writer of the text printed by the command.
+//The text is written to stderr, if the standard output carries JSON or events, so the standard output only contains JSON.
+func textWriter(c *cli.Context) io.Writer {
+	if jsonOutput(c) || c.String("events") == "-" {
+		return os.Stderr
+	}
+
+	return os.Stdout
+}
+
+//openEvents returns the stream of events written to the target, a file or - for stdout.
+//It returns nil, if the target is empty.
+func openEvents(target string) (*rackevent.Stream, error) {
+	switch target {
+	case "":
+		return nil, nil
+	case "-":
+		return rackevent.NewStream(jsonWriter), nil
+	default:
+		return rackevent.CreateStream(target)
+	}
+}
+
+//printJSON prints a value as indented JSON
+func printJSON(value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(jsonWriter, string(data))
+
+	return nil
+}
@@ -2,17 +2,27 @@
 package rackcommands
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"log"
+	"io"
+	"path/filepath"
 	"strings"
 
 	"github.com/urfave/cli"
 	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackconfig"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackfreeze"
 	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackinput"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racklock"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racklog"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackmetrics"
 	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackplugin"
 	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racksetup"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshop"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshopinit"
 	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshopstore"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackssh"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackstatus"
 	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackup"
 	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/repository"
 )
@@ -26,7 +36,7 @@ func SetupCommand() *cli.Command {
 		Action: func(c *cli.Context) error {
 			err := racksetup.Setup()
 			if err != nil {
-				log.Fatalf("Setup - error: %v\n", err)
+				racklog.Fatalf("Setup - error: %v\n", err)
 			}
 			return err
 		},
@@ -102,12 +112,29 @@ func accountRemoveSubcommand() *cli.Command {
 	}
 }
 
+// accountListEntry defines the JSON structure of an account printed by account list, leaving out the password
+type accountListEntry struct {
+	Domain     string `json:"domain"`
+	Username   string `json:"username"`
+	InKeychain bool   `json:"inKeychain"`
+}
+
 func accountListSubcommand() *cli.Command {
 	return &cli.Command{
 		Name:  "list",
 		Usage: "List all existing GIT accounts",
 		Action: func(c *cli.Context) error {
 			config := rackconfig.GetConfig()
+
+			if jsonOutput(c) {
+				entries := []accountListEntry{}
+				for _, account := range config.GITAccounts {
+					entries = append(entries, accountListEntry{account.Domain, account.Username, account.Inkeychain})
+				}
+
+				return printJSON(entries)
+			}
+
 			for _, account := range config.GITAccounts {
 				fmt.Println(" - Domain: " + account.Domain)
 				fmt.Println("\tUsername: " + account.Username)
@@ -156,7 +183,7 @@ func repoAddSubcommand() *cli.Command {
 
 			err := repository.AddRepo(repoName, repoSource)
 			if err != nil {
-				log.Fatalf("AddRepo - error: %v\n", err)
+				racklog.Fatalf("AddRepo - error: %v\n", err)
 			}
 
 			return err
@@ -183,7 +210,7 @@ func repoRemoveSubcommand() *cli.Command {
 
 			err := repository.RemoveRepo(repo)
 			if err != nil {
-				log.Fatalf("RemoveRepo - error: %v\n", err)
+				racklog.Fatalf("RemoveRepo - error: %v\n", err)
 			}
 
 			return err
@@ -198,10 +225,14 @@ func repoListSubcommand() *cli.Command {
 		Action: func(c *cli.Context) error {
 			repos, err := repository.ListRepos()
 			if err != nil {
-				log.Fatalf("ListRepos - error: %v\n", err)
+				racklog.Fatalf("ListRepos - error: %v\n", err)
 				return err
 			}
 
+			if jsonOutput(c) {
+				return printJSON(*repos)
+			}
+
 			for repo, url := range *repos {
 				fmt.Printf(" - Repository: %v\n", repo)
 				fmt.Printf("\tURL: %v\n", url)
@@ -216,9 +247,9 @@ func repoUpdateSubcommand() *cli.Command {
 		Name:  "update",
 		Usage: "Updates all repos that are connected to rackjobber",
 		Action: func(c *cli.Context) error {
-			err := repository.UpdateRepos()
+			err := repository.UpdateRepos(context.Background())
 			if err != nil {
-				log.Fatalf("UpdateRepos - error: %v\n", err)
+				racklog.Fatalf("UpdateRepos - error: %v\n", err)
 			}
 			return err
 		},
@@ -243,7 +274,7 @@ func repoPushSubcommand() *cli.Command {
 
 			err := repository.PushSpecToRepo(repoName)
 			if err != nil {
-				log.Fatalf("PushSpecToRepo - error: %v\n", err)
+				racklog.Fatalf("PushSpecToRepo - error: %v\n", err)
 			}
 
 			return err
@@ -259,10 +290,14 @@ func ShopCommand() *cli.Command {
 		Subcommands: []*cli.Command{
 			shopAddSubcommand(),
 			shopRemoveSubcommand(),
+			shopEditSubcommand(),
+			shopRenameSubcommand(),
 			shopListSubcommand(),
+			shopGroupSubcommand(),
 			shopInitSubcommand(),
 			shopIntegrateSubcommand(),
 			shopDeintegrateSubcommand(),
+			shopUnlockSubcommand(),
 		},
 	}
 }
@@ -285,17 +320,18 @@ func shopAddSubcommand() *cli.Command {
 			password := c.String("password")
 			sdir := c.String("shopwareDir")
 			container := c.String("container")
+			local := c.Bool("local")
 
 			for len(name) == 0 {
 				name = rackinput.AwaitTextInput("Name (must not be empty):")
 			}
-			for len(address) == 0 {
+			for len(address) == 0 && !local {
 				address = rackinput.AwaitTextInput("Address (must not be empty):")
 			}
-			for len(sshUser) == 0 {
+			for len(sshUser) == 0 && !local {
 				sshUser = rackinput.AwaitTextInput("SSH user (must not be empty):")
 			}
-			for len(password) == 0 {
+			for len(password) == 0 && !local {
 				password = rackinput.AwaitPasswordInput("Password:")
 			}
 			for len(sdir) == 0 {
@@ -304,12 +340,32 @@ func shopAddSubcommand() *cli.Command {
 			for len(container) == 0 {
 				container = rackinput.AwaitTextInput("Docker container (must not be empty):")
 			}
-			return rackshopstore.AddShop(name, address, sshUser, password, sdir, container)
+			return rackshopstore.AddShop(rackshop.RackShop{
+				Name:          name,
+				Address:       address,
+				User:          sshUser,
+				Password:      password,
+				ShopwareDir:   sdir,
+				Container:     container,
+				Tags:          splitList(c.String("tags")),
+				Environment:   c.String("env"),
+				Local:         local,
+				ThemeStrategy: c.String("themeStrategy"),
+			})
 		},
 	}
 }
 
 func shopAddFlags() []cli.Flag {
+	return append(shopConfigFlags(),
+		&cli.StringFlag{
+			Name:  "file, f",
+			Usage: "File that contains the informations to add a shop to rackjobber",
+		},
+	)
+}
+
+func shopConfigFlags() []cli.Flag {
 	return []cli.Flag{
 		&cli.StringFlag{
 			Name:  "shopName, sn",
@@ -327,10 +383,6 @@ func shopAddFlags() []cli.Flag {
 			Name:  "password, p",
 			Usage: "Password for the ssh user",
 		},
-		&cli.StringFlag{
-			Name:  "file, f",
-			Usage: "File that contains the informations to add a shop to rackjobber",
-		},
 		&cli.StringFlag{
 			Name:  "container, c",
 			Usage: "Name of the docker container",
@@ -339,6 +391,26 @@ func shopAddFlags() []cli.Flag {
 			Name:  "shopwareDir, sdir",
 			Usage: "Shopware directory on the remote machine",
 		},
+		&cli.StringFlag{
+			Name:  "tags, t",
+			Usage: "Comma separated list of tags for the shop",
+		},
+		&cli.StringFlag{
+			Name:  "env, e",
+			Usage: "Environment of the shop (dev, staging or production)",
+		},
+		&cli.BoolFlag{
+			Name:  "local",
+			Usage: "The shop runs in docker on this machine and is managed without ssh",
+		},
+		themeStrategyFlag("Strategy to assign themes"),
+	}
+}
+
+func themeStrategyFlag(usage string) cli.Flag {
+	return &cli.StringFlag{
+		Name:  "themeStrategy",
+		Usage: usage + " (" + strings.Join(rackshop.ThemeStrategies, ", ") + "), default is auto",
 	}
 }
 
@@ -359,28 +431,390 @@ func shopRemoveSubcommand() *cli.Command {
 				return rackshopstore.RemoveShopFromStore(name)
 			}
 
-			fmt.Println("Required Flag is missing")
+			racklog.Errorf("Required Flag is missing")
 			return errors.New("missing Required Flag")
 		},
 	}
 }
 
+func shopEditSubcommand() *cli.Command {
+	return &cli.Command{
+		Name:    "edit",
+		Aliases: []string{"update"},
+		Usage:   "Changes the given fields of a shop in the shop store, an empty value clears a field",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "shopName, sn",
+				Usage: "Name of the shop that should be changed",
+			},
+			&cli.StringFlag{
+				Name:  "address, a",
+				Usage: "New address of the shop",
+			},
+			&cli.StringFlag{
+				Name:  "sshuser, u",
+				Usage: "New SSH user to connect to the address of the shop",
+			},
+			&cli.StringFlag{
+				Name:  "password, p",
+				Usage: "New password for the ssh user",
+			},
+			&cli.StringFlag{
+				Name:  "container, c",
+				Usage: "New name of the docker container",
+			},
+			&cli.StringFlag{
+				Name:  "shopwareDir, sdir",
+				Usage: "New shopware directory on the remote machine",
+			},
+			&cli.StringFlag{
+				Name:  "tags, t",
+				Usage: "Comma separated list of tags, that replaces the current tags of the shop",
+			},
+			&cli.StringFlag{
+				Name:  "env, e",
+				Usage: "New environment of the shop (dev, staging or production)",
+			},
+			themeStrategyFlag("New strategy to assign themes"),
+			&cli.StringFlag{
+				Name:  "maintenance",
+				Usage: "New way to switch the shop into maintenance mode (setting, file or command)",
+			},
+			&cli.StringFlag{
+				Name:  "maintenanceFile",
+				Usage: "New maintenance file relative to the shopware directory",
+			},
+			&cli.StringFlag{
+				Name:  "maintenanceOn",
+				Usage: "New command to switch the maintenance mode on",
+			},
+			&cli.StringFlag{
+				Name:  "maintenanceOff",
+				Usage: "New command to switch the maintenance mode off",
+			},
+			&cli.StringFlag{
+				Name:  "maintenanceWhitelist",
+				Usage: "Comma separated IPs, that replace the IPs allowed to use the shop during maintenance",
+			},
+			&cli.StringFlag{
+				Name:  "backupDir",
+				Usage: "New absolute directory on the shop outside of the shopware directory, that the backups are stored in",
+			},
+			&cli.StringFlag{
+				Name:  "dialTimeout",
+				Usage: "New time to establish the ssh connection to the shop, like 30s",
+			},
+			&cli.StringFlag{
+				Name:  "commandTimeout",
+				Usage: "New time a single command on the shop may take, like 30m",
+			},
+			&cli.StringFlag{
+				Name:  "gitTimeout",
+				Usage: "New time a git clone, fetch or pull may take, like 10m",
+			},
+			&cli.StringFlag{
+				Name:  "lsRemoteTimeout",
+				Usage: "New time git ls-remote may take, like 1m",
+			},
+			&cli.IntFlag{
+				Name:  "retryAttempts",
+				Usage: "New number of times a failing connection, fetch or refresh is tried",
+			},
+			&cli.StringFlag{
+				Name:  "retryDelay",
+				Usage: "New time to wait before the first retry, like 2s. It doubles for every further retry",
+			},
+			&cli.StringFlag{
+				Name:  "retryMaxDelay",
+				Usage: "New longest time to wait between two attempts, like 30s",
+			},
+			&cli.BoolFlag{
+				Name:  "check",
+				Usage: "Check the connection to the shop after the change",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			exists, name := proveStringCLI(c, "shopName")
+			if !exists {
+				return errors.New("required flag not provided")
+			}
+
+			err := rackshopstore.EditShopInStore(name, func(shop *rackshop.RackShop) {
+				setIfSet(c, &shop.Address, "address")
+				setIfSet(c, &shop.User, "sshuser")
+				setIfSet(c, &shop.Password, "password")
+				setIfSet(c, &shop.Container, "container")
+				setIfSet(c, &shop.ShopwareDir, "shopwareDir")
+				setIfSet(c, &shop.Environment, "env")
+				setIfSet(c, &shop.ThemeStrategy, "themeStrategy")
+				setIfSet(c, &shop.Maintenance.Mode, "maintenance")
+				setIfSet(c, &shop.Maintenance.File, "maintenanceFile")
+				setIfSet(c, &shop.Maintenance.On, "maintenanceOn")
+				setIfSet(c, &shop.Maintenance.Off, "maintenanceOff")
+				setIfSet(c, &shop.BackupDir, "backupDir")
+				setIfSet(c, &shop.Timeouts.Dial, "dialTimeout")
+				setIfSet(c, &shop.Timeouts.Command, "commandTimeout")
+				setIfSet(c, &shop.Timeouts.Git, "gitTimeout")
+				setIfSet(c, &shop.Timeouts.LsRemote, "lsRemoteTimeout")
+				setIfSet(c, &shop.Retry.Delay, "retryDelay")
+				setIfSet(c, &shop.Retry.MaxDelay, "retryMaxDelay")
+
+				if c.IsSet("retryAttempts") {
+					shop.Retry.Attempts = c.Int("retryAttempts")
+				}
+
+				if c.IsSet("tags") {
+					shop.Tags = splitList(c.String("tags"))
+				}
+
+				if c.IsSet("maintenanceWhitelist") {
+					shop.Maintenance.Whitelist = splitList(c.String("maintenanceWhitelist"))
+				}
+			})
+			if err != nil {
+				return err
+			}
+
+			if c.Bool("check") {
+				return rackshopstore.ProveShopConnection(name)
+			}
+
+			return nil
+		},
+	}
+}
+
+func shopRenameSubcommand() *cli.Command {
+	return &cli.Command{
+		Name:  "rename",
+		Usage: "Renames a shop in the shop store",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "shopName, sn",
+				Usage: "Current name of the shop",
+			},
+			&cli.StringFlag{
+				Name:  "newName, nn",
+				Usage: "New name of the shop",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			nameExists, name := proveStringCLI(c, "shopName")
+			newNameExists, newName := proveStringCLI(c, "newName")
+
+			if !nameExists || !newNameExists {
+				return errors.New("required flag not provided")
+			}
+
+			return rackshopstore.RenameShopInStore(name, newName)
+		},
+	}
+}
+
 func shopListSubcommand() *cli.Command {
 	return &cli.Command{
 		Name:  "list",
 		Usage: "Lists all shops that are currently in the shop store",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "selector, l",
+				Usage: "Only list the shops matching a shop name, group name or selector like env=staging,tag=b2b",
+			},
+		},
 		Action: func(c *cli.Context) error {
-			shops, err := rackshopstore.ListShopsFromStore()
-			if err != nil {
-				return err
+			asJSON := jsonOutput(c)
+
+			var shops []rackshop.RackShop
+
+			if expression := c.String("selector"); len(expression) > 0 {
+				selected, err := rackshopstore.SelectShopsFromStore(expression)
+				if err != nil {
+					return err
+				}
+
+				shops = selected
+			} else {
+				all, err := rackshopstore.ListShopsFromStore()
+				if err != nil {
+					return err
+				}
+
+				shops = *all
+			}
+
+			if asJSON {
+				return printShopsAsJSON(shops)
 			}
 
-			for _, shop := range *shops {
+			for _, shop := range shops {
 				fmt.Printf(" - Shop: %v\n", shop.Name)
 				fmt.Printf("\tAddress: %v\n", shop.Address)
 				fmt.Printf("\tUser: %v\n", shop.User)
 				fmt.Printf("\tShopwareDir: %v\n", shop.ShopwareDir)
 				fmt.Printf("\tContainer: %v\n", shop.Container)
+
+				if len(shop.Environment) > 0 {
+					fmt.Printf("\tEnvironment: %v\n", shop.Environment)
+				}
+
+				if len(shop.ThemeStrategy) > 0 {
+					fmt.Printf("\tThemeStrategy: %v\n", shop.ThemeStrategy)
+				}
+
+				if len(shop.Maintenance.Mode) > 0 {
+					fmt.Printf("\tMaintenance: %v\n", shop.Maintenance.Mode)
+				}
+
+				if len(shop.Tags) > 0 {
+					fmt.Printf("\tTags: %v\n", strings.Join(shop.Tags, ", "))
+				}
+			}
+
+			return nil
+		},
+	}
+}
+
+// shopListEntry defines the JSON structure of a shop printed by shop list, leaving out the password
+type shopListEntry struct {
+	Name          string   `json:"name"`
+	Address       string   `json:"address"`
+	User          string   `json:"user"`
+	ShopwareDir   string   `json:"shopwareDir"`
+	Container     string   `json:"container"`
+	Environment   string   `json:"environment,omitempty"`
+	Tags          []string `json:"tags,omitempty"`
+	ThemeStrategy string   `json:"themeStrategy,omitempty"`
+	Maintenance   string   `json:"maintenance,omitempty"`
+}
+
+func printShopsAsJSON(shops []rackshop.RackShop) error {
+	entries := []shopListEntry{}
+
+	for _, shop := range shops {
+		entries = append(entries, shopListEntry{
+			Name:          shop.Name,
+			Address:       shop.Address,
+			User:          shop.User,
+			ShopwareDir:   shop.ShopwareDir,
+			Container:     shop.Container,
+			Environment:   shop.Environment,
+			Tags:          shop.Tags,
+			ThemeStrategy: shop.ThemeStrategy,
+			Maintenance:   shop.Maintenance.Mode,
+		})
+	}
+
+	return printJSON(entries)
+}
+
+//groupListEntry is a group of the shop store as printed by shop group list --output json
+type groupListEntry struct {
+	Name     string   `json:"name"`
+	Shops    []string `json:"shops,omitempty"`
+	Selector string   `json:"selector,omitempty"`
+}
+
+func printGroupsAsJSON(groups []rackshopstore.ShopGroup) error {
+	entries := []groupListEntry{}
+
+	for _, group := range groups {
+		entries = append(entries, groupListEntry{Name: group.Name, Shops: group.Shops, Selector: group.Selector})
+	}
+
+	return printJSON(entries)
+}
+
+func shopGroupSubcommand() *cli.Command {
+	return &cli.Command{
+		Name:  "group",
+		Usage: "Manages named groups of shops",
+		Subcommands: []*cli.Command{
+			shopGroupAddSubcommand(),
+			shopGroupRemoveSubcommand(),
+			shopGroupListSubcommand(),
+		},
+	}
+}
+
+func shopGroupAddSubcommand() *cli.Command {
+	return &cli.Command{
+		Name:  "add",
+		Usage: "Adds a group of shops or replaces an existing one",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "groupName, gn",
+				Usage: "Name of the group",
+			},
+			&cli.StringFlag{
+				Name:  "shops, s",
+				Usage: "Comma separated list of shop names that belong to the group",
+			},
+			&cli.StringFlag{
+				Name:  "selector, l",
+				Usage: "Selector like env=staging,tag=b2b that selects the shops of the group",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			name := c.String("groupName")
+			for len(name) == 0 {
+				name = rackinput.AwaitTextInput("Group name (must not be empty):")
+			}
+
+			return rackshopstore.AddGroupToStore(rackshopstore.ShopGroup{
+				Name:     name,
+				Shops:    splitList(c.String("shops")),
+				Selector: c.String("selector"),
+			})
+		},
+	}
+}
+
+func shopGroupRemoveSubcommand() *cli.Command {
+	return &cli.Command{
+		Name:  "remove",
+		Usage: "Removes a group from the shopStore, the shops of the group are kept",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "groupName, gn",
+				Usage: "Name of the group that should be removed",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			exists, name := proveStringCLI(c, "groupName")
+			if !exists {
+				return errors.New("required flag not provided")
+			}
+
+			return rackshopstore.RemoveGroupFromStore(name)
+		},
+	}
+}
+
+func shopGroupListSubcommand() *cli.Command {
+	return &cli.Command{
+		Name:  "list",
+		Usage: "Lists all groups of the shop store",
+		Action: func(c *cli.Context) error {
+			groups, err := rackshopstore.ListGroupsFromStore()
+			if err != nil {
+				return err
+			}
+
+			if jsonOutput(c) {
+				return printGroupsAsJSON(groups)
+			}
+
+			for _, group := range groups {
+				fmt.Printf(" - Group: %v\n", group.Name)
+
+				if len(group.Shops) > 0 {
+					fmt.Printf("\tShops: %v\n", strings.Join(group.Shops, ", "))
+				}
+
+				if len(group.Selector) > 0 {
+					fmt.Printf("\tSelector: %v\n", group.Selector)
+				}
 			}
 
 			return nil
@@ -392,20 +826,91 @@ func shopInitSubcommand() *cli.Command {
 	return &cli.Command{
 		Name:  "init",
 		Usage: "Initializes a shopware installation, that is compatible with rackjobber.",
+		Flags: shopInitFlags(),
 		Action: func(c *cli.Context) error {
-			fmt.Println("Sorry, but not implemented yet.")
-			return nil
+			shop := rackshop.RackShop{
+				Name:          c.String("shopName"),
+				Address:       c.String("address"),
+				User:          c.String("sshuser"),
+				Password:      c.String("password"),
+				ShopwareDir:   c.String("shopwareDir"),
+				Container:     c.String("container"),
+				Tags:          splitList(c.String("tags")),
+				Environment:   c.String("env"),
+				Local:         c.Bool("local"),
+				ThemeStrategy: c.String("themeStrategy"),
+			}
+
+			for len(shop.Name) == 0 {
+				shop.Name = rackinput.AwaitTextInput("Name (must not be empty):")
+			}
+
+			if !shop.Local {
+				for len(shop.Address) == 0 {
+					shop.Address = rackinput.AwaitTextInput("Address (must not be empty):")
+				}
+				for len(shop.User) == 0 {
+					shop.User = rackinput.AwaitTextInput("SSH user (must not be empty):")
+				}
+			}
+
+			for len(shop.ShopwareDir) == 0 {
+				shop.ShopwareDir = rackinput.AwaitTextInput("Shopware directory (must not be empty):")
+			}
+
+			return rackshopinit.InitShop(rackshopinit.Options{
+				Shop:          shop,
+				Image:         c.String("image"),
+				DatabaseImage: c.String("databaseImage"),
+				HTTPPort:      c.String("httpPort"),
+				ShopURL:       c.String("shopURL"),
+			})
 		},
 	}
 }
 
+func shopInitFlags() []cli.Flag {
+	return append(shopConfigFlags(),
+		&cli.StringFlag{
+			Name:  "image, i",
+			Usage: "Docker image of shopware, default is " + rackshopinit.DefaultShopwareImage,
+		},
+		&cli.StringFlag{
+			Name:  "databaseImage, di",
+			Usage: "Docker image of the database, default is " + rackshopinit.DefaultDatabaseImage,
+		},
+		&cli.StringFlag{
+			Name:  "httpPort, hp",
+			Usage: "Port the shop will be available at, default is 80",
+		},
+		&cli.StringFlag{
+			Name:  "shopURL, url",
+			Usage: "URL the shop will be available at",
+		},
+	)
+}
+
 func shopIntegrateSubcommand() *cli.Command {
 	return &cli.Command{
 		Name:  "integrate",
 		Usage: "Integrates rackjobber into an existing shopware installation.",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "shopName, sn",
+				Usage: "The name of the shop, rackjobber shall be integrated into",
+			},
+			&cli.BoolFlag{
+				Name:  "force",
+				Usage: "Override an existing rackfile of the shop",
+			},
+		},
 		Action: func(c *cli.Context) error {
-			fmt.Println("Sorry, but not implemented yet.")
-			return nil
+			exists, shopName := proveStringCLI(c, "shopName")
+			if !exists {
+				return errors.New("required flag not provided")
+			}
+
+			return rackshopinit.IntegrateShop(shopName, c.Bool("force"))
 		},
 	}
 }
@@ -414,13 +919,83 @@ func shopDeintegrateSubcommand() *cli.Command {
 	return &cli.Command{
 		Name:  "deintegrate",
 		Usage: "Deintegrates rackjobber from this shopware installation",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "shopName, sn",
+				Usage: "The name of the shop, rackjobber shall be removed from",
+			},
+			&cli.BoolFlag{
+				Name:  "stripGit",
+				Usage: "Remove the git metadata of all plugins as well",
+			},
+		},
 		Action: func(c *cli.Context) error {
-			fmt.Println("Sorry, but not implemented yet!")
-			return nil
+			exists, shopName := proveStringCLI(c, "shopName")
+			if !exists {
+				return errors.New("required flag not provided")
+			}
+
+			if c.Bool("stripGit") && !confirm("Remove the git metadata of all plugins of "+shopName+"? (y/n)") {
+				return nil
+			}
+
+			return rackshopinit.DeintegrateShop(shopName, c.Bool("stripGit"))
+		},
+	}
+}
+
+func shopUnlockSubcommand() *cli.Command {
+	return &cli.Command{
+		Name:  "unlock",
+		Usage: "Removes the deployment lock of a shop left behind by an aborted run",
+		Flags: append(shopFlags("The name of the shop or group, that shall be unlocked"),
+			&cli.BoolFlag{
+				Name:  "force, f",
+				Usage: "Remove the lock, even if it is not expired",
+			},
+		),
+		Action: func(c *cli.Context) error {
+			shops, err := resolveShopNames(c)
+			if err != nil {
+				return err
+			}
+
+			return forEachShop(c, shops, func(shopName string) error {
+				return unlockShop(textWriter(c), shopName, c.Bool("force"))
+			})
 		},
 	}
 }
 
+// unlockShop removes the lock of the shop, force removes it even if it is not expired.
+// What was removed is printed to out.
+func unlockShop(out io.Writer, shopName string, force bool) error {
+	shop, err := rackshopstore.GetShopFromStore(shopName)
+	if err != nil {
+		return err
+	}
+
+	executor := rackssh.NewExecutor(shop)
+
+	lock, err := racklock.Read(executor, shop)
+	if err == nil && lock == nil {
+		fmt.Fprintln(out, "The shop "+shopName+" is not locked.")
+		return nil
+	}
+
+	if err = racklock.Unlock(executor, shop, force); err != nil {
+		return err
+	}
+
+	if lock != nil {
+		fmt.Fprintln(out, "Removed the lock of "+shopName+": "+lock.String())
+	} else {
+		fmt.Fprintln(out, "Removed the lock of "+shopName+".")
+	}
+
+	return nil
+}
+
 // PluginCommand is used for plugin related operations
 func PluginCommand() *cli.Command {
 	return &cli.Command{
@@ -538,12 +1113,18 @@ func pluginListSubcommand() *cli.Command {
 			},
 		},
 		Action: func(c *cli.Context) error {
+			asJSON := jsonOutput(c)
+
 			var plugins []string
 			if len(c.String("repoName")) == 0 {
 				plugins = rackplugin.GetAllPlugins()
 			} else {
 				plugins = rackplugin.GetPluginsFromRepo(c.String("repoName"))
 			}
+
+			if asJSON {
+				return printJSON(plugins)
+			}
 			for _, plugin := range plugins {
 				fmt.Println(" - " + plugin)
 			}
@@ -558,29 +1139,349 @@ func UpCommand() *cli.Command {
 		Name:    "up",
 		Aliases: []string{"u"},
 		Usage:   "Update and install Plugins and themes that are referenced in the rackfile",
-		Flags: []cli.Flag{
+		Flags: append(append(shopFlags("The name of the shop or group, that the plugins shall be deployed to"),
 			&cli.StringFlag{
-				Name:  "shopName, sn",
-				Usage: "The name of the shop, that the plugins shall be deployed to",
+				Name:  "only",
+				Usage: "Comma separated plugins of the rackfile, that shall be deployed together with the mandatory and required plugins",
+			},
+			&cli.StringFlag{
+				Name:  "except",
+				Usage: "Comma separated plugins of the rackfile, that shall not be deployed",
+			},
+			&cli.BoolFlag{
+				Name:  "maintenance",
+				Usage: "Switch the shop into maintenance mode while plugins are deleted and deployed",
+			},
+			&cli.BoolFlag{
+				Name:  "backup",
+				Usage: "Back up the database, the plugins and the deployment state of the shop before deploying",
+			},
+			lockWaitFlag(),
+			&cli.StringFlag{
+				Name:  "events",
+				Usage: "Write the events of the runs as newline-delimited JSON to a file, or to stdout with -",
+			},
+			&cli.BoolFlag{
+				Name:  "noNotify",
+				Usage: "Do not send notifications of the runs to the webhooks and chats of the shops",
+			},
+		), append(backupStorageFlags(), metricsFlags()...)...),
+		Action: func(c *cli.Context) error {
+			asJSON := jsonOutput(c)
+
+			shops, err := resolveShopNames(c)
+			if err != nil {
+				return err
+			}
+
+			events, err := openEvents(c.String("events"))
+			if err != nil {
+				return err
+			}
+
+			defer events.Close()
+
+			opts := rackup.Options{
+				Only:          splitList(c.String("only")),
+				Except:        splitList(c.String("except")),
+				Maintenance:   c.Bool("maintenance"),
+				Backup:        c.Bool("backup"),
+				BackupOptions: backupOptions(c),
+				LockWait:      c.Duration("wait"),
+				Events:        events,
+				Notify:        !c.Bool("noNotify"),
+				Metrics:       metricsOutput(c),
+			}
+
+			if asJSON {
+				opts.JSON = jsonWriter
+			}
+
+			opts.Text = textWriter(c)
+
+			return forEachShop(c, shops, func(shopName string) error {
+				return rackup.Up(shopName, opts)
+			})
+		},
+	}
+}
+
+// RollbackCommand is used to restore the plugins of a previous run on a shop
+func RollbackCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "rollback",
+		Usage: "Restores the plugin versions, flags and theme of a previous successful run",
+		Flags: append(append(shopFlags("The name of the shop or group, that shall be rolled back"),
+			&cli.StringFlag{
+				Name:  "to",
+				Usage: "The ID of the run to restore, a unique prefix of it or latest",
+			},
+			&cli.BoolFlag{
+				Name:  "yes, y",
+				Usage: "Execute the rollback without asking for confirmation",
+			},
+			lockWaitFlag(),
+		), metricsFlags()...),
+		Action: func(c *cli.Context) error {
+			exists, runID := proveStringCLI(c, "to")
+			if !exists {
+				return errors.New("required flag not provided")
+			}
+
+			shops, err := resolveShopNames(c)
+			if err != nil {
+				return err
+			}
+
+			return forEachShop(c, shops, func(shopName string) error {
+				return rackup.Rollback(shopName, runID, c.Bool("yes"), c.Duration("wait"), metricsOutput(c))
+			})
+		},
+	}
+}
+
+// lockWaitFlag returns the flag deciding how long to wait for the lock of a shop held by another run
+func lockWaitFlag() cli.Flag {
+	return &cli.DurationFlag{
+		Name:  "wait",
+		Usage: "How long to wait for the lock of the shop, if another run holds it, e.g. 10m. Fails at once by default",
+	}
+}
+
+// metricsFlags returns the flags deciding where the metrics of the runs are written, in addition to the config
+func metricsFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  "metricsDir",
+			Usage: "Write the metrics of the runs to this directory of the textfile collector of the node exporter",
+		},
+		&cli.StringFlag{
+			Name:  "pushgateway",
+			Usage: "Push the metrics of the runs to the Pushgateway at this URL",
+		},
+	}
+}
+
+// metricsOutput returns the metrics output of the flags, that replaces the output of the config
+func metricsOutput(c *cli.Context) rackmetrics.Output {
+	return rackmetrics.Output{Textfile: c.String("metricsDir"), Pushgateway: c.String("pushgateway")}
+}
+
+// FreezeCommand is used to export the plugins of a running shop as a rackfile
+func FreezeCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "freeze",
+		Usage: "Writes a rackfile, that reproduces the plugins currently present on a shop",
+		Flags: append(shopFlags("The name of the shop or group, whose plugins shall be exported"),
+			&cli.StringFlag{
+				Name: "file, f",
+				Usage: "Path of the written rackfile, default is rackfile.yaml. " +
+					"With several shops, the name of each shop is added, like rackfile-shop.yaml",
+			},
+			&cli.BoolFlag{
+				Name:  "toShop",
+				Usage: "Write the rackfile to the custom/rackfile.yaml of the shop",
+			},
+			&cli.BoolFlag{
+				Name:  "force",
+				Usage: "Override an existing rackfile",
 			},
+		),
+		Action: func(c *cli.Context) error {
+			shops, err := resolveShopNames(c)
+			if err != nil {
+				return err
+			}
+
+			path := c.String("file")
+			if len(path) == 0 {
+				path = "rackfile.yaml"
+			}
+
+			return forEachShop(c, shops, func(shopName string) error {
+				shopPath := path
+				if len(shops) > 1 {
+					extension := filepath.Ext(path)
+					shopPath = strings.TrimSuffix(path, extension) + "-" + shopName + extension
+				}
+
+				return rackfreeze.FreezeToFile(shopName, shopPath, c.Bool("toShop"), c.Bool("force"))
+			})
 		},
+	}
+}
+
+// StatusCommand is used to show the drift between the rackfile of a shop and its actual state
+func StatusCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "status",
+		Usage: "Compares the rackfile and deployment state of a shop with the plugins actually running on it",
+		Flags: shopFlags("The name of the shop or group, whose status shall be shown"),
 		Action: func(c *cli.Context) error {
-			shop := c.String("shopName")
+			asJSON := jsonOutput(c)
 
-			for len(shop) == 0 {
-				shop = rackinput.AwaitTextInput("Shop name (must not be empty):")
+			shops, err := resolveShopNames(c)
+			if err != nil {
+				return err
 			}
 
-			rackup.Up(shop)
-			return nil
+			reports := []rackstatus.Report{}
+
+			err = forEachShop(c, shops, func(shopName string) error {
+				report, err := rackstatus.GetStatus(shopName)
+				if err != nil {
+					return err
+				}
+
+				if asJSON {
+					reports = append(reports, *report)
+				} else {
+					report.Print()
+				}
+
+				return nil
+			})
+
+			if asJSON {
+				if jsonErr := printJSON(reports); jsonErr != nil {
+					return jsonErr
+				}
+			}
+
+			return err
 		},
 	}
 }
 
+// resolveShopNames returns the names of all shops addressed by the shopName and selector flags.
+// The user is asked for a shop name, if none of these flags is set.
+func resolveShopNames(c *cli.Context) ([]string, error) {
+	expression := c.String("selector")
+	if len(expression) == 0 {
+		expression = c.String("shopName")
+	}
+
+	for len(expression) == 0 {
+		expression = rackinput.AwaitTextInput("Shop name (must not be empty):")
+	}
+
+	shops, err := rackshopstore.SelectShopsFromStore(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(shops) == 0 {
+		return nil, errors.New("no shops match " + expression)
+	}
+
+	names := []string{}
+	for _, shop := range shops {
+		names = append(names, shop.Name)
+	}
+
+	return names, nil
+}
+
+// shopFlags returns the flags addressing the shops of a command by name, group or selector.
+// The usage describes the shops, like "The shop or group, that shall be checked".
+func shopFlags(usage string) []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  "shopName, sn",
+			Usage: usage,
+		},
+		&cli.StringFlag{
+			Name:  "selector, l",
+			Usage: "Run for all shops matching a selector like env=staging,tag=b2b",
+		},
+		&cli.BoolFlag{
+			Name:  "failFast",
+			Usage: "Stop at the first shop, that fails, instead of continuing with the other shops",
+		},
+	}
+}
+
+// forEachShop runs the action for every shop. A failing shop does not stop the other shops,
+// unless the failFast flag is set or the run was cancelled. The result of every shop is logged,
+// if there are several shops, and an error is returned, if any of them failed.
+func forEachShop(c *cli.Context, shops []string, action func(shopName string) error) error {
+	if len(shops) == 1 {
+		return action(shops[0])
+	}
+
+	results := map[string]error{}
+	done := 0
+
+	for _, shopName := range shops {
+		err := action(shopName)
+		results[shopName] = err
+		done++
+
+		if err != nil {
+			racklog.With("shop", shopName).Errorf("%v failed: %v", shopName, err)
+
+			if c.Bool("failFast") || err == rackup.ErrCancelled {
+				break
+			}
+		}
+	}
+
+	failed := 0
+
+	for i, shopName := range shops {
+		switch err, ok := results[shopName]; {
+		case i >= done || !ok:
+			racklog.Infof("%v: not run", shopName)
+		case err != nil:
+			failed++
+			racklog.Infof("%v: failed: %v", shopName, err)
+		default:
+			racklog.Infof("%v: succeeded", shopName)
+		}
+	}
+
+	if failed > 0 || done < len(shops) {
+		return fmt.Errorf("%v of %v shops failed, %v were not run", failed, len(shops), len(shops)-done)
+	}
+
+	return nil
+}
+
+// confirm asks the user a yes/no question and returns true if it was answered with yes
+func confirm(question string) bool {
+	in := ""
+	for in != "y" && in != "n" {
+		in = rackinput.AwaitTextInput(question)
+	}
+
+	return in == "y"
+}
+
+// setIfSet overrides the field with the value of the flag, if the flag is given, so an empty value clears the field
+func setIfSet(c *cli.Context, field *string, flag string) {
+	if c.IsSet(flag) {
+		*field = c.String(flag)
+	}
+}
+
+// splitList splits a comma separated flag value into its trimmed, non-empty parts
+func splitList(value string) []string {
+	list := []string{}
+
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if len(part) > 0 {
+			list = append(list, part)
+		}
+	}
+
+	return list
+}
+
 func proveStringCLI(c *cli.Context, key string) (bool, string) {
 	value := c.String(key)
 	if strings.Compare(value, "") == 0 {
-		log.Fatalf("No value for key: %v\n", key)
+		racklog.Fatalf("No value for key: %v\n", key)
 		return false, value
 	}
 
@@ -4,7 +4,6 @@ package rackconfig
 import (
 	"encoding/hex"
 	"fmt"
-	"log"
 	"path/filepath"
 	"strings"
 
@@ -14,12 +13,25 @@ import (
 
 	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/fileutil"
 	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackinput"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racklog"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackmetrics"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racknotify"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackretry"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshop"
 )
 
 //Config contains configuration information for Rackjobber
 type Config struct {
 	GITAccounts      []GITAccount `yaml:"GIT"`
 	MandatoryPlugins []string     `yaml:"plugins"`
+	// Timeouts limit how long rackjobber waits for shops and git repositories, shops can replace them
+	Timeouts rackretry.Timeouts `yaml:"timeouts,omitempty"`
+	// Retry is the retry policy of operations, that may fail because of the network, shops can replace it
+	Retry rackretry.Policy `yaml:"retry,omitempty"`
+	// Notifications are notified of every up, groups and shops can add more
+	Notifications []racknotify.Target `yaml:"notifications,omitempty"`
+	// Metrics is where the metrics of every up and rollback are written
+	Metrics rackmetrics.Output `yaml:"metrics,omitempty"`
 }
 
 //GITAccount contains GIT account information for a user
@@ -33,12 +45,12 @@ type GITAccount struct {
 //AddAccount adds or modifies an account
 //Password will be hashed. Only implemented to prevent clearly visible passwords in the config. This is no encryption!
 func AddAccount(domain string, username string, password string, fromkeychain bool) {
-	appFolderPath, err := fileutil.GetAppFolderPath()
+	configFolderPath, err := fileutil.GetConfigFolderPath()
 	if err != nil {
-		log.Fatalf("Application directory could not be found: %v\n", err)
+		racklog.Fatalf("Config directory could not be found: %v\n", err)
 	}
 
-	configPath := filepath.Join(*appFolderPath, "config.yaml")
+	configPath := filepath.Join(*configFolderPath, "config.yaml")
 	cfg := Config{}
 	replaced := false
 
@@ -65,18 +77,18 @@ func AddAccount(domain string, username string, password string, fromkeychain bo
 
 	err = fileutil.CreateOrWriteFile(configPath, configData)
 	if err != nil {
-		log.Fatalf("CreateOrWriteFile - error: %v\n", err)
+		racklog.Fatalf("CreateOrWriteFile - error: %v\n", err)
 	}
 }
 
 //RemoveAccount removes an existing account
 func RemoveAccount(domain string) {
-	appFolderPath, err := fileutil.GetAppFolderPath()
+	configFolderPath, err := fileutil.GetConfigFolderPath()
 	if err != nil {
-		log.Fatalf("Application directory could not be found: %v\n", err)
+		racklog.Fatalf("Config directory could not be found: %v\n", err)
 	}
 
-	configPath := filepath.Join(*appFolderPath, "config.yaml")
+	configPath := filepath.Join(*configFolderPath, "config.yaml")
 	cfg := Config{}
 	found := false
 
@@ -100,23 +112,23 @@ func RemoveAccount(domain string) {
 
 	configData, err := yaml.Marshal(&cfg)
 	if err != nil {
-		log.Fatalf("yaml.Marshal - error: %v\n", err)
+		racklog.Fatalf("yaml.Marshal - error: %v\n", err)
 	}
 
 	err = fileutil.CreateOrWriteFile(configPath, configData)
 	if err != nil {
-		log.Fatalf("CreateOrWriteFile - error: %v\n", err)
+		racklog.Fatalf("CreateOrWriteFile - error: %v\n", err)
 	}
 }
 
 //GetConfig returns the current configuration
 func GetConfig() Config {
-	appFolderPath, err := fileutil.GetAppFolderPath()
+	configFolderPath, err := fileutil.GetConfigFolderPath()
 	if err != nil {
-		log.Fatalf("Application directory could not be found: %v\n", err)
+		racklog.Fatalf("Config directory could not be found: %v\n", err)
 	}
 
-	configPath := filepath.Join(*appFolderPath, "config.yaml")
+	configPath := filepath.Join(*configFolderPath, "config.yaml")
 
 	config, _ := fileutil.ReadFile(configPath)
 	if config != nil {
@@ -124,20 +136,59 @@ func GetConfig() Config {
 
 		err = yaml.Unmarshal(*config, &cfg)
 		if err != nil {
-			log.Fatalf("yaml.Unmarshal - error: %v\n", err)
+			racklog.Fatalf("yaml.Unmarshal - error: %v\n", err)
 		}
 
 		return *cfg
 	}
 
 	return Config{
-		[]GITAccount{},
-		[]string{},
+		GITAccounts:      []GITAccount{},
+		MandatoryPlugins: []string{},
 	}
 }
 
-//GetGITAuth returns account data if an account has been set for given domain
+//GetTimeouts returns the timeouts of the config, replaced by the timeouts set for the shop, if a shop is given
+func GetTimeouts(shop *rackshop.RackShop) rackretry.Timeouts {
+	timeouts := GetConfig().Timeouts
+	if shop != nil {
+		timeouts = timeouts.Merge(shop.Timeouts)
+	}
+
+	return timeouts
+}
+
+//GetRetryPolicy returns the retry policy of the config, replaced by the policy set for the shop, if a shop is given
+func GetRetryPolicy(shop *rackshop.RackShop) rackretry.Policy {
+	policy := GetConfig().Retry
+	if shop != nil {
+		policy = policy.Merge(shop.Retry)
+	}
+
+	return policy
+}
+
+//GetNotifications returns the notification targets of the config
+func GetNotifications() []racknotify.Target {
+	return GetConfig().Notifications
+}
+
+//GetMetrics returns where the metrics of the runs are written, replaced by the given output
+func GetMetrics(output rackmetrics.Output) rackmetrics.Output {
+	return GetConfig().Metrics.Merge(output)
+}
+
+//GetGITAuth returns account data if an account has been set for given domain.
+//The password is redacted from the log.
 func GetGITAuth(domain string) http.BasicAuth {
+	auth := getGITAuth(domain)
+	racklog.AddSecret(auth.Password)
+
+	return auth
+}
+
+//getGITAuth returns the account of the domain from the config or the keychain, or asks for it
+func getGITAuth(domain string) http.BasicAuth {
 	passInKeyChain := false
 	auth := http.BasicAuth{}
 
@@ -2,6 +2,8 @@ package rackconfig
 
 import (
 	"errors"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racklog"
 )
 
 //GetGitPassFromKeychain retrieves the password matching the given account and domain from the macOS Keychain
@@ -24,10 +26,10 @@ func getGitPassFromKeychain(account, domain string) (string, string, error) {
 
 	results, err := keychain.QueryItem(query)
 	if err != nil {
-		fmt.Println("Failed to retrieve Authentication from Keychain: ", err)
+		racklog.Warnf("Failed to retrieve Authentication from Keychain: %v", err)
 		return "", "", err
 	} else if len(results) == 0 {
-		fmt.Printf("No Authentication found for User %v on Domain %v\n", account, domain)
+		racklog.Infof("No Authentication found for User %v on Domain %v\n", account, domain)
 		return "", "", errors.New("no Authentication found")
 	}
 
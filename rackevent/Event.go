@@ -0,0 +1,101 @@
+// Package rackevent includes the events of a run, that are written as newline-delimited JSON
+// for dashboards and scripts following a deployment.
+package rackevent
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racklog"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackplan"
+)
+
+// Types of events
+const (
+	// RunStarted is emitted before the first step of a run
+	RunStarted = "runStarted"
+	// StepStarted is emitted before a step is executed
+	StepStarted = "stepStarted"
+	// StepFinished is emitted after a step is executed, with its outcome and duration
+	StepFinished = "stepFinished"
+	// PluginSkipped is emitted for a plugin, that is not deployed, like because it is up-to-date
+	PluginSkipped = "pluginSkipped"
+	// RunFinished is emitted after the state and the record of a run are written, with its outcome and duration
+	RunFinished = "runFinished"
+)
+
+// Event is a single event of a run
+type Event struct {
+	Type    string         `json:"type"`
+	Time    time.Time      `json:"time"`
+	Shop    string         `json:"shop"`
+	RunID   string         `json:"runId"`
+	Command string         `json:"command,omitempty"`
+	Step    *rackplan.Step `json:"step,omitempty"`
+	Plugin  string         `json:"plugin,omitempty"`
+	Version string         `json:"version,omitempty"`
+	Reason  string         `json:"reason,omitempty"`
+	Outcome string         `json:"outcome,omitempty"`
+	// DurationMillis is the duration of a finished step or run
+	DurationMillis int64  `json:"durationMs,omitempty"`
+	Error          string `json:"error,omitempty"`
+}
+
+// Stream writes events as newline-delimited JSON.
+// A nil stream drops all events, so runs without a stream do not need to check for it.
+type Stream struct {
+	mutex  sync.Mutex
+	writer io.Writer
+	file   *os.File
+}
+
+// NewStream returns a stream, that writes the events to the writer
+func NewStream(writer io.Writer) *Stream {
+	return &Stream{writer: writer}
+}
+
+// CreateStream returns a stream, that writes the events to a new file at the path
+func CreateStream(path string) (*Stream, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Stream{writer: file, file: file}, nil
+}
+
+// Emit writes the event as a single line, the time is set if it is empty
+func (s *Stream) Emit(event Event) {
+	if s == nil {
+		return
+	}
+
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		racklog.Warnf("failed to encode the event %v: %v\n", event.Type, err)
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, err = s.writer.Write(append(data, '\n')); err != nil {
+		racklog.Warnf("failed to write the event %v: %v\n", event.Type, err)
+	}
+}
+
+// Close closes the file of the stream, if it writes to one
+func (s *Stream) Close() error {
+	if s == nil || s.file == nil {
+		return nil
+	}
+
+	return s.file.Close()
+}
@@ -0,0 +1,111 @@
+package rackfile
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Prefixes of secret references in configuration values
+const (
+	secretEnvPrefix  = "${env:"
+	secretFilePrefix = "${file:"
+)
+
+// PluginConfig is an entry of the Config section of a rackfile, that sets configuration values of a plugin.
+// Values can reference secrets with ${env:NAME} or ${file:path}, which are resolved on the executing machine.
+type PluginConfig struct {
+	Plugin string `yaml:"Plugin"`
+	// Shop is the ID or name of the subshop, the values are set for. The default shop is used, if it is empty
+	Shop   string            `yaml:"Shop,omitempty"`
+	Values map[string]string `yaml:"Values"`
+}
+
+// Keys returns the configuration keys of the entry in a stable order
+func (c PluginConfig) Keys() []string {
+	keys := []string{}
+	for key := range c.Values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// ValidateConfig checks that every configuration entry names a plugin of the rackfile and no key is set twice
+func ValidateConfig(config []PluginConfig, plugins []string) error {
+	known := map[string]bool{}
+	for _, plugin := range plugins {
+		known[ParseEntry(plugin).Name] = true
+	}
+
+	seen := map[string]bool{}
+
+	for _, entry := range config {
+		if !known[entry.Plugin] {
+			return errors.New("the configured plugin " + entry.Plugin + " is not deployed by the rackfile")
+		}
+
+		for _, key := range entry.Keys() {
+			id := entry.Plugin + "/" + entry.Shop + "/" + key
+			if seen[id] {
+				return errors.New("the key " + key + " of " + entry.Plugin + " is configured twice")
+			}
+
+			seen[id] = true
+
+			if _, err := ResolveValue(entry.Values[key]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// IsSecret checks if a configuration value references a secret
+func IsSecret(value string) bool {
+	return strings.HasSuffix(value, "}") &&
+		(strings.HasPrefix(value, secretEnvPrefix) || strings.HasPrefix(value, secretFilePrefix))
+}
+
+// ResolveValue returns the configuration value with its secret reference resolved.
+// Environment variables have to be set, files are read from the executing machine without a trailing newline.
+func ResolveValue(value string) (string, error) {
+	if !IsSecret(value) {
+		return value, nil
+	}
+
+	if strings.HasPrefix(value, secretEnvPrefix) {
+		name := strings.TrimSuffix(strings.TrimPrefix(value, secretEnvPrefix), "}")
+
+		secret, ok := os.LookupEnv(name)
+		if !ok {
+			return "", errors.New("the environment variable " + name + " of the secret " + value + " is not set")
+		}
+
+		return secret, nil
+	}
+
+	path := strings.TrimSuffix(strings.TrimPrefix(value, secretFilePrefix), "}")
+
+	if strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+
+		path = filepath.Join(home, path[2:])
+	}
+
+	data, err := ioutil.ReadFile(path) //nolint, as the secret is only being read
+	if err != nil {
+		return "", errors.New("failed to read the secret " + value + ": " + err.Error())
+	}
+
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
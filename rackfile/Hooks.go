@@ -0,0 +1,37 @@
+package rackfile
+
+import (
+	"errors"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackhook"
+)
+
+// PluginHooks is an entry of the Hooks section of a rackfile, that runs hooks around the deployment of a plugin.
+// The hooks only run, when the plugin is deployed by up.
+type PluginHooks struct {
+	Plugin string          `yaml:"Plugin"`
+	Before []rackhook.Hook `yaml:"Before,omitempty"`
+	After  []rackhook.Hook `yaml:"After,omitempty"`
+	// SpecHooks runs the post install hooks of the plugin's rackspec after it is installed
+	SpecHooks bool `yaml:"SpecHooks,omitempty"`
+}
+
+// ValidateHooks checks that every hook entry names a plugin of the rackfile and that its hooks are valid
+func ValidateHooks(hooks []PluginHooks, plugins []string) error {
+	known := map[string]bool{}
+	for _, plugin := range plugins {
+		known[ParseEntry(plugin).Name] = true
+	}
+
+	for _, entry := range hooks {
+		if !known[entry.Plugin] {
+			return errors.New("the hooks of " + entry.Plugin + " belong to no plugin of the rackfile")
+		}
+
+		if err := rackhook.ValidateHooks(entry.Before, entry.After); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
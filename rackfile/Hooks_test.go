@@ -0,0 +1,41 @@
+package rackfile
+
+import (
+	"testing"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackhook"
+)
+
+func TestValidateHooks(t *testing.T) {
+	plugins := []string{"SwagPlugin:1.2.0:noactivate", "OtherPlugin", "LegacyPlugin::unmanaged"}
+	clearCache := rackhook.Hook{Console: "sw:cache:clear"}
+
+	tests := []struct {
+		name    string
+		hooks   []PluginHooks
+		wantErr bool
+	}{
+		{name: "no hooks", hooks: []PluginHooks{}},
+		{name: "valid hooks", hooks: []PluginHooks{
+			{Plugin: "SwagPlugin", Before: []rackhook.Hook{{Run: "php bin/check.php"}},
+				After: []rackhook.Hook{clearCache, {Console: "sw:warm:http:cache", OnFailure: rackhook.OnFailureWarn}}},
+			{Plugin: "OtherPlugin", SpecHooks: true},
+		}},
+		{name: "unknown plugin", hooks: []PluginHooks{{Plugin: "MissingPlugin", After: []rackhook.Hook{clearCache}}},
+			wantErr: true},
+		{name: "plugin given with its version", hooks: []PluginHooks{{Plugin: "SwagPlugin:1.2.0"}}, wantErr: true},
+		{name: "hook without command", hooks: []PluginHooks{{Plugin: "SwagPlugin",
+			Before: []rackhook.Hook{{Name: "empty"}}}}, wantErr: true},
+		{name: "hook with run and console", hooks: []PluginHooks{{Plugin: "OtherPlugin",
+			After: []rackhook.Hook{{Run: "ls", Console: "sw:cache:clear"}}}}, wantErr: true},
+		{name: "unknown failure policy", hooks: []PluginHooks{{Plugin: "OtherPlugin",
+			After: []rackhook.Hook{{Console: "sw:cache:clear", OnFailure: "ignore"}}}}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		err := ValidateHooks(test.hooks, plugins)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%v: got error %v, want an error %v", test.name, err, test.wantErr)
+		}
+	}
+}
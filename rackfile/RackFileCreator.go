@@ -4,18 +4,83 @@ package rackfile
 import (
 	"errors"
 	"io/ioutil"
-	"log"
 	"os"
+	"strings"
 
 	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/fileutil"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackhook"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racklog"
 
 	"gopkg.in/yaml.v2"
 )
 
 // RackFile struct that will define the yaml structure of the rackfile.yml
 type RackFile struct {
-	Plugins []string
-	Themes  []string
+	Plugins []string       `yaml:"Plugins"`
+	Themes  []ThemeEntry   `yaml:"Themes"`
+	Config  []PluginConfig `yaml:"Config,omitempty"`
+	Hooks   []PluginHooks  `yaml:"Hooks,omitempty"`
+}
+
+// FlagUnmanaged marks a plugin of a rackfile, that is kept on the shop as it is.
+// up neither deploys nor deletes it, so plugins without a rackspec can stay on integrated shops.
+const FlagUnmanaged = "unmanaged"
+
+// Entry is a parsed plugin or theme entry of a rackfile like "PluginName:1.0.0:noactivate"
+type Entry struct {
+	Name string
+	// Version is the version of the entry, empty or "latest" if the latest version shall be used
+	Version string
+	// Flag is one of noinstall, noactivate, nosettheme and unmanaged, or empty
+	Flag string
+}
+
+// ParseEntry parses a plugin or theme entry of a rackfile
+func ParseEntry(entry string) Entry {
+	split := strings.Split(entry, ":")
+	parsed := Entry{Name: split[0]}
+
+	if len(split) > 1 {
+		parsed.Version = split[1]
+	}
+
+	if len(split) > 2 {
+		parsed.Flag = split[2]
+	}
+
+	return parsed
+}
+
+// IsLatest checks if the entry asks for the latest version
+func (e Entry) IsLatest() bool {
+	return e.Version == "" || e.Version == "latest"
+}
+
+// String returns the entry in the format used by the rackfile
+func (e Entry) String() string {
+	entry := e.Name
+
+	if len(e.Version) > 0 || len(e.Flag) > 0 {
+		entry += ":" + e.Version
+	}
+
+	if len(e.Flag) > 0 {
+		entry += ":" + e.Flag
+	}
+
+	return entry
+}
+
+// UnmarshalRackFileData unmarshals a rackfile from yaml data
+func UnmarshalRackFileData(data []byte) (*RackFile, error) {
+	file := &RackFile{}
+
+	err := yaml.Unmarshal(data, file)
+	if err != nil {
+		return nil, err
+	}
+
+	return file, nil
 }
 
 // UnmarshalRackFile unmarshals a rackfile from a given yaml path
@@ -53,17 +118,25 @@ func CreateExampleRackFile() error {
 		"PluginVersionExample:1.0.0",
 	}
 
-	themes := []string{
-		"ThemeExample",
-		"ThemeLatestExample:latest",
-		"ThemeVersionExample:1.0.0",
+	themes := []ThemeEntry{
+		{Name: "ThemeExample", Version: "latest", Default: true},
+		{Name: "ThemeVersionExample", Version: "1.0.0", Shops: []string{"2", "English Shop"}},
+	}
+
+	config := []PluginConfig{
+		{Plugin: "PluginExample", Values: map[string]string{"apiUrl": "https://example.com", "apiKey": "${env:API_KEY}"}},
+		{Plugin: "PluginExample", Shop: "2", Values: map[string]string{"apiUrl": "https://en.example.com"}},
+	}
+
+	hooks := []PluginHooks{
+		{Plugin: "PluginExample", After: []rackhook.Hook{{Name: "generate theme cache", Console: "sw:theme:cache:generate"}}},
 	}
 
-	rackfile := RackFile{plugins, themes}
+	rackfile := RackFile{plugins, themes, config, hooks}
 
 	data, err := yaml.Marshal(&rackfile)
 	if err != nil {
-		log.Fatalf("Marshal - error: %v\n", err)
+		racklog.Fatalf("Marshal - error: %v\n", err)
 		return err
 	}
 
@@ -71,18 +144,18 @@ func CreateExampleRackFile() error {
 
 	exists, ok := fileutil.ObjectExists(filename)
 	if ok != nil {
-		log.Fatalf("objectExists - error: %v\n", ok)
+		racklog.Fatalf("objectExists - error: %v\n", ok)
 		return ok
 	}
 
 	if exists {
-		log.Fatalln("rackfile already exists - Aborting")
+		racklog.Fatalf("rackfile already exists - Aborting")
 		return errors.New("rackfile already exists")
 	}
 
 	err = ioutil.WriteFile(filename, data, os.ModePerm)
 	if err != nil {
-		log.Fatalf("WriteFile - error: %v\n", err)
+		racklog.Fatalf("WriteFile - error: %v\n", err)
 	}
 
 	return err
@@ -0,0 +1,32 @@
+package rackfile
+
+import "testing"
+
+func TestParseEntry(t *testing.T) {
+	tests := []struct {
+		entry  string
+		want   Entry
+		latest bool
+	}{
+		{entry: "SwagPlugin", want: Entry{Name: "SwagPlugin"}, latest: true},
+		{entry: "SwagPlugin:latest", want: Entry{Name: "SwagPlugin", Version: "latest"}, latest: true},
+		{entry: "SwagPlugin:1.2.0", want: Entry{Name: "SwagPlugin", Version: "1.2.0"}},
+		{entry: "SwagPlugin:1.2.0:noactivate", want: Entry{Name: "SwagPlugin", Version: "1.2.0", Flag: "noactivate"}},
+		{entry: "SwagPlugin::unmanaged", want: Entry{Name: "SwagPlugin", Flag: FlagUnmanaged}, latest: true},
+	}
+
+	for _, test := range tests {
+		got := ParseEntry(test.entry)
+		if got != test.want {
+			t.Errorf("ParseEntry(%q) = %+v, want %+v", test.entry, got, test.want)
+		}
+
+		if got.IsLatest() != test.latest {
+			t.Errorf("ParseEntry(%q).IsLatest() = %v, want %v", test.entry, got.IsLatest(), test.latest)
+		}
+
+		if got.String() != test.entry {
+			t.Errorf("ParseEntry(%q).String() = %q", test.entry, got.String())
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package rackfile
+
+import (
+	"errors"
+)
+
+// ThemeEntry is an entry of the Themes section of a rackfile.
+// A theme is deployed like a plugin and assigned to the listed subshops.
+// The entry can be given as a string like "ThemeName:1.0.0", which makes it the default theme.
+type ThemeEntry struct {
+	Name    string `yaml:"Name"`
+	Version string `yaml:"Version,omitempty"`
+	// Shops are the IDs or names of the subshops, the theme is assigned to
+	Shops []string `yaml:"Shops,omitempty"`
+	// Default assigns the theme to all subshops, that are not assigned to another theme
+	Default bool `yaml:"Default,omitempty"`
+}
+
+// UnmarshalYAML reads a theme entry either from a string or from a mapping
+func (t *ThemeEntry) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var entry string
+	if err := unmarshal(&entry); err == nil {
+		parsed := ParseEntry(entry)
+		*t = ThemeEntry{Name: parsed.Name, Version: parsed.Version, Default: true}
+
+		return nil
+	}
+
+	type plainThemeEntry ThemeEntry
+
+	return unmarshal((*plainThemeEntry)(t))
+}
+
+// PluginEntry returns the entry, that deploys the plugin of the theme.
+// The plugin is installed and activated, but does not set its theme by itself.
+func (t ThemeEntry) PluginEntry() string {
+	version := t.Version
+	if len(version) == 0 {
+		version = "latest"
+	}
+
+	return Entry{Name: t.Name, Version: version, Flag: "nosettheme"}.String()
+}
+
+// ValidateThemes checks that every theme is assigned to subshops and that there is at most one default theme
+func ValidateThemes(themes []ThemeEntry) error {
+	hasDefault := false
+
+	for _, theme := range themes {
+		if len(theme.Name) == 0 {
+			return errors.New("a theme of the rackfile has no name")
+		}
+
+		if !theme.Default && len(theme.Shops) == 0 {
+			return errors.New("the theme " + theme.Name + " is neither the default theme nor assigned to shops")
+		}
+
+		if theme.Default && hasDefault {
+			return errors.New("the rackfile contains more than one default theme")
+		}
+
+		hasDefault = hasDefault || theme.Default
+	}
+
+	return nil
+}
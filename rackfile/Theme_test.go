@@ -0,0 +1,28 @@
+package rackfile
+
+import (
+	"testing"
+)
+
+func TestValidateThemes(t *testing.T) {
+	tests := []struct {
+		name    string
+		themes  []ThemeEntry
+		wantErr bool
+	}{
+		{name: "no themes", themes: []ThemeEntry{}},
+		{name: "default and assigned themes", themes: []ThemeEntry{{Name: "SwagTheme", Default: true},
+			{Name: "OtherTheme", Version: "1.0.0", Shops: []string{"2", "English"}}}},
+		{name: "theme without name", themes: []ThemeEntry{{Default: true}}, wantErr: true},
+		{name: "theme without shops", themes: []ThemeEntry{{Name: "SwagTheme"}}, wantErr: true},
+		{name: "two default themes", themes: []ThemeEntry{{Name: "SwagTheme", Default: true},
+			{Name: "OtherTheme", Default: true}}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		err := ValidateThemes(test.themes)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%v: got error %v, want an error %v", test.name, err, test.wantErr)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// Package rackfreeze includes functions to export the plugin set of a running shop as a rackfile
+package rackfreeze
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/fileutil"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackfile"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackinspect"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshopstore"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackssh"
+)
+
+// Flags of a rackfile entry, that reflect the state of a plugin
+const (
+	flagNoInstall  = "noinstall"
+	flagNoActivate = "noactivate"
+)
+
+// Result contains the rackfile describing a shop and the plugins, that could not be described by it
+type Result struct {
+	RackFile rackfile.RackFile
+	// Unmatched contains the reason for every plugin, that has no matching rackspec
+	Unmatched map[string]string
+}
+
+// Freeze reads the plugins of a shop and their state in shopware and returns a rackfile reproducing them.
+// Plugins that are not installed or not active get the noinstall or noactivate flag.
+func Freeze(shopName string) (*Result, error) {
+	shop, err := rackshopstore.GetShopFromStore(shopName)
+	if err != nil {
+		return nil, err
+	}
+
+	executor := rackssh.NewExecutor(shop)
+
+	plugins, err := rackinspect.InspectPlugins(executor, shop)
+	if err != nil {
+		return nil, err
+	}
+
+	states, err := rackinspect.ReadPluginStates(executor, shop)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		RackFile:  rackfile.RackFile{Plugins: []string{}, Themes: []rackfile.ThemeEntry{}},
+		Unmatched: map[string]string{},
+	}
+
+	for _, plugin := range plugins {
+		state, known := states[plugin.Name]
+
+		match, err := rackinspect.MatchRackSpec(plugin, state.Version)
+		if err != nil {
+			result.Unmatched[plugin.Name] = err.Error()
+			continue
+		}
+
+		entry := plugin.Name + ":" + match.Version
+
+		switch {
+		case !known || !state.Installed:
+			entry += ":" + flagNoInstall
+		case !state.Active:
+			entry += ":" + flagNoActivate
+		}
+
+		result.RackFile.Plugins = append(result.RackFile.Plugins, entry)
+	}
+
+	return result, nil
+}
+
+// FreezeToFile freezes a shop and writes the rackfile to a local path.
+// If toShop is set, the rackfile is written to the custom/rackfile.yaml of the shop instead.
+func FreezeToFile(shopName string, path string, toShop bool, force bool) error {
+	if !toShop && !force {
+		if exists, _ := fileutil.ObjectExists(path); exists {
+			return errors.New(path + " already exists, use --force to override it")
+		}
+	}
+
+	result, err := Freeze(shopName)
+	if err != nil {
+		return err
+	}
+
+	data, err := result.RackFile.MarshalRackFile()
+	if err != nil {
+		return err
+	}
+
+	if toShop {
+		shop, err := rackshopstore.GetShopFromStore(shopName)
+		if err != nil {
+			return err
+		}
+
+		path = "custom/rackfile.yaml on " + shop.Name
+		err = rackssh.WriteFileToShop(shop, "custom/rackfile.yaml", *data)
+		if err != nil {
+			return err
+		}
+	} else if err = fileutil.CreateOrWriteFile(path, *data); err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote rackfile with %v plugins to %v\n", len(result.RackFile.Plugins), path)
+	result.PrintReport()
+
+	return nil
+}
+
+// PrintReport prints the plugins, that have no matching rackspec
+func (r Result) PrintReport() {
+	if len(r.Unmatched) == 0 {
+		fmt.Println("All plugins have a matching rackspec.")
+		return
+	}
+
+	names := []string{}
+	for name := range r.Unmatched {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	fmt.Println("These plugins have no matching rackspec and are not part of the rackfile:")
+
+	for _, name := range names {
+		fmt.Printf(" - %v: %v\n", name, r.Unmatched[name])
+	}
+}
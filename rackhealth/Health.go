@@ -0,0 +1,146 @@
+// Package rackhealth includes functions to check with http requests, that a shop works
+package rackhealth
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshop"
+)
+
+// defaultTimeout is the timeout of a request, if the check defines no maximum latency
+const defaultTimeout = 30 * time.Second
+
+// Result is the result of a health check
+type Result struct {
+	URL           string `json:"url"`
+	Healthy       bool   `json:"healthy"`
+	Status        int    `json:"status,omitempty"`
+	LatencyMillis int64  `json:"latencyMs"`
+	Attempts      int    `json:"attempts"`
+	Error         string `json:"error,omitempty"`
+}
+
+// String returns a human readable description of the result
+func (r Result) String() string {
+	state := "healthy"
+	if !r.Healthy {
+		state = "unhealthy: " + r.Error
+	}
+
+	return fmt.Sprintf("%v: %v (status %v, %vms, %v attempts)", r.URL, state, r.Status, r.LatencyMillis, r.Attempts)
+}
+
+// Check requests the url of the check until it succeeds, its retries are used up or the context is done
+func Check(ctx context.Context, check rackshop.HealthCheck) Result {
+	result := Result{URL: check.URL}
+
+	for attempt := 1; attempt <= check.Retries+1; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				result.Healthy = false
+				result.Error = "the check was cancelled: " + ctx.Err().Error()
+
+				return result
+			case <-time.After(check.GetRetryDelay()):
+			}
+		}
+
+		result = checkOnce(ctx, check)
+		result.Attempts = attempt
+
+		if result.Healthy {
+			break
+		}
+	}
+
+	return result
+}
+
+// CheckShop runs all health checks of a shop and returns their results
+func CheckShop(ctx context.Context, shop *rackshop.RackShop) []Result {
+	results := []Result{}
+
+	for _, check := range shop.HealthChecks {
+		results = append(results, Check(ctx, check))
+	}
+
+	return results
+}
+
+//checkOnce requests the url of the check and compares the response with the expectations of the check
+func checkOnce(ctx context.Context, check rackshop.HealthCheck) Result {
+	result := Result{URL: check.URL}
+	client := &http.Client{Timeout: defaultTimeout}
+
+	maxLatency, err := time.ParseDuration(check.MaxLatency)
+	if err == nil {
+		client.Timeout = maxLatency
+	}
+
+	request, err := http.NewRequest(http.MethodGet, check.URL, nil)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	start := time.Now()
+
+	response, err := client.Do(request.WithContext(ctx))
+	if err != nil {
+		result.LatencyMillis = int64(time.Since(start) / time.Millisecond)
+		result.Error = err.Error()
+
+		if netErr, ok := err.(net.Error); ok && netErr.Timeout() && len(check.MaxLatency) > 0 {
+			result.Error = "no response within " + check.MaxLatency
+		}
+
+		return result
+	}
+
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	result.LatencyMillis = int64(time.Since(start) / time.Millisecond)
+	result.Status = response.StatusCode
+
+	if err != nil {
+		result.Error = "failed to read the response: " + err.Error()
+		return result
+	}
+
+	result.Error = compare(check, response.StatusCode, string(body))
+	result.Healthy = len(result.Error) == 0
+
+	return result
+}
+
+//compare returns why the response does not meet the expectations of the check, or an empty string
+func compare(check rackshop.HealthCheck, status int, body string) string {
+	expected := false
+	statuses := []string{}
+
+	for _, expectedStatus := range check.GetStatus() {
+		expected = expected || expectedStatus == status
+		statuses = append(statuses, strconv.Itoa(expectedStatus))
+	}
+
+	if !expected {
+		return "status " + strconv.Itoa(status) + " is not one of " + strings.Join(statuses, ", ")
+	}
+
+	for _, text := range check.Contains {
+		if !strings.Contains(body, text) {
+			return "the response does not contain " + text
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,134 @@
+package rackhealth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshop"
+)
+
+func TestCheck(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			_, _ = w.Write([]byte("<title>Demo shop</title>"))
+		case "/maintenance":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		check   rackshop.HealthCheck
+		healthy bool
+		status  int
+		error   string
+	}{
+		{
+			name:    "expected status and body",
+			check:   rackshop.HealthCheck{URL: server.URL + "/ok", Contains: []string{"Demo shop"}},
+			healthy: true,
+			status:  http.StatusOK,
+		},
+		{
+			name:   "status mismatch",
+			check:  rackshop.HealthCheck{URL: server.URL + "/maintenance"},
+			status: http.StatusServiceUnavailable,
+			error:  "status 503 is not one of 200",
+		},
+		{
+			name:    "other expected status",
+			check:   rackshop.HealthCheck{URL: server.URL + "/maintenance", Status: []int{200, 503}},
+			healthy: true,
+			status:  http.StatusServiceUnavailable,
+		},
+		{
+			name:   "missing body text",
+			check:  rackshop.HealthCheck{URL: server.URL + "/ok", Contains: []string{"Demo shop", "Checkout"}},
+			status: http.StatusOK,
+			error:  "the response does not contain Checkout",
+		},
+		{
+			name:  "latency timeout",
+			check: rackshop.HealthCheck{URL: server.URL + "/slow", MaxLatency: "50ms"},
+			error: "no response within 50ms",
+		},
+	}
+
+	for _, test := range tests {
+		result := Check(context.Background(), test.check)
+
+		if result.Healthy != test.healthy || result.Status != test.status || result.Error != test.error {
+			t.Errorf("%v: got healthy %v, status %v, error %q, want %v, %v, %q", test.name,
+				result.Healthy, result.Status, result.Error, test.healthy, test.status, test.error)
+		}
+
+		if result.Attempts != 1 {
+			t.Errorf("%v: got %v attempts, want 1", test.name, result.Attempts)
+		}
+	}
+}
+
+func TestCheckRetries(t *testing.T) {
+	var requests int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		retries  int
+		healthy  bool
+		attempts int32
+	}{
+		{name: "retries used up", retries: 1, healthy: false, attempts: 2},
+		{name: "healthy after retries", retries: 5, healthy: true, attempts: 3},
+	}
+
+	for _, test := range tests {
+		atomic.StoreInt32(&requests, 0)
+		check := rackshop.HealthCheck{URL: server.URL, Retries: test.retries, RetryDelay: "1ms"}
+
+		result := Check(context.Background(), check)
+		made := atomic.LoadInt32(&requests)
+
+		if result.Healthy != test.healthy || result.Attempts != int(test.attempts) || made != test.attempts {
+			t.Errorf("%v: got healthy %v after %v attempts and %v requests, want %v after %v", test.name,
+				result.Healthy, result.Attempts, made, test.healthy, test.attempts)
+		}
+	}
+}
+
+func TestCheckCancelledBetweenRetries(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	result := Check(ctx, rackshop.HealthCheck{URL: server.URL, Retries: 3, RetryDelay: "1m"})
+
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("the check waited %v for its retry delay", time.Since(start))
+	}
+
+	if result.Healthy || result.Attempts != 1 || !strings.Contains(result.Error, "cancelled") {
+		t.Errorf("got healthy %v after %v attempts with error %q, want a cancelled check after 1 attempt",
+			result.Healthy, result.Attempts, result.Error)
+	}
+}
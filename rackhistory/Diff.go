@@ -0,0 +1,106 @@
+package rackhistory
+
+import (
+	"fmt"
+	"sort"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackstate"
+)
+
+// PluginDiff describes the difference of a plugin between the deployment states of two runs
+type PluginDiff struct {
+	Name string `json:"name"`
+	// From is the state after the first run, nil if the plugin was not deployed
+	From *rackstate.PluginState `json:"from,omitempty"`
+	// To is the state after the second run, nil if the plugin was not deployed
+	To *rackstate.PluginState `json:"to,omitempty"`
+}
+
+// Diff returns the plugins, that differ between the deployment states after the two runs
+func Diff(from *Run, to *Run) []PluginDiff {
+	names := map[string]bool{}
+	fromPlugins := map[string]rackstate.PluginState{}
+	toPlugins := map[string]rackstate.PluginState{}
+
+	for _, plugin := range from.Plugins {
+		fromPlugins[plugin.Name] = plugin
+		names[plugin.Name] = true
+	}
+
+	for _, plugin := range to.Plugins {
+		toPlugins[plugin.Name] = plugin
+		names[plugin.Name] = true
+	}
+
+	diffs := []PluginDiff{}
+
+	for name := range names {
+		fromPlugin, inFrom := fromPlugins[name]
+		toPlugin, inTo := toPlugins[name]
+
+		if inFrom && inTo && samePluginState(fromPlugin, toPlugin) {
+			continue
+		}
+
+		diff := PluginDiff{Name: name}
+		if inFrom {
+			diff.From = &fromPlugin
+		}
+
+		if inTo {
+			diff.To = &toPlugin
+		}
+
+		diffs = append(diffs, diff)
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Name < diffs[j].Name
+	})
+
+	return diffs
+}
+
+//samePluginState returns if the deployed plugins equal in version, commit, flag and shopware state
+func samePluginState(a rackstate.PluginState, b rackstate.PluginState) bool {
+	return a.Tag == b.Tag && a.Commit == b.Commit && a.Flag == b.Flag &&
+		a.Installed == b.Installed && a.Activated == b.Activated
+}
+
+// PrintDiff prints the differences between two runs
+func PrintDiff(from *Run, to *Run, diffs []PluginDiff) {
+	fmt.Printf("--- %v\n+++ %v\n", from.ID, to.ID)
+
+	if len(diffs) == 0 {
+		fmt.Println("No differences in the deployed plugins.")
+		return
+	}
+
+	for _, diff := range diffs {
+		switch {
+		case diff.From == nil:
+			fmt.Printf("+ %v %v\n", diff.Name, describePlugin(diff.To))
+		case diff.To == nil:
+			fmt.Printf("- %v %v\n", diff.Name, describePlugin(diff.From))
+		default:
+			fmt.Printf("~ %v %v -> %v\n", diff.Name, describePlugin(diff.From), describePlugin(diff.To))
+		}
+	}
+}
+
+//describePlugin returns the version, commit and flag of a deployed plugin
+func describePlugin(plugin *rackstate.PluginState) string {
+	const shortCommit = 8
+
+	commit := plugin.Commit
+	if len(commit) > shortCommit {
+		commit = commit[:shortCommit]
+	}
+
+	description := plugin.Tag + " (" + commit + ")"
+	if len(plugin.Flag) > 0 {
+		description += " [" + plugin.Flag + "]"
+	}
+
+	return description
+}
@@ -0,0 +1,83 @@
+package rackhistory
+
+import (
+	"testing"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackstate"
+)
+
+func TestDiff(t *testing.T) {
+	swag := rackstate.PluginState{Name: "SwagPlugin", Tag: "1.0.0", Commit: "abc", Installed: true, Activated: true}
+	other := rackstate.PluginState{Name: "OtherPlugin", Tag: "2.0.0", Commit: "def", Installed: true, Activated: true}
+
+	updated := swag
+	updated.Tag = "1.1.0"
+	updated.Commit = "ghi"
+
+	deactivated := swag
+	deactivated.Activated = false
+	deactivated.Flag = "noactivate"
+
+	redeployed := swag
+	redeployed.RunID = "run-2"
+	redeployed.DeployedBy = "someone"
+
+	tests := []struct {
+		name    string
+		from    []rackstate.PluginState
+		to      []rackstate.PluginState
+		added   []string
+		removed []string
+		changed []string
+	}{
+		{name: "no changes", from: []rackstate.PluginState{swag, other}, to: []rackstate.PluginState{other, swag}},
+		{name: "only metadata changed", from: []rackstate.PluginState{swag}, to: []rackstate.PluginState{redeployed}},
+		{name: "added", from: []rackstate.PluginState{swag}, to: []rackstate.PluginState{swag, other},
+			added: []string{"OtherPlugin"}},
+		{name: "removed", from: []rackstate.PluginState{swag, other}, to: []rackstate.PluginState{other},
+			removed: []string{"SwagPlugin"}},
+		{name: "updated", from: []rackstate.PluginState{swag}, to: []rackstate.PluginState{updated},
+			changed: []string{"SwagPlugin"}},
+		{name: "flag and state changed", from: []rackstate.PluginState{swag}, to: []rackstate.PluginState{deactivated},
+			changed: []string{"SwagPlugin"}},
+		{name: "sorted by name", from: []rackstate.PluginState{}, to: []rackstate.PluginState{swag, other},
+			added: []string{"OtherPlugin", "SwagPlugin"}},
+	}
+
+	for _, test := range tests {
+		diffs := Diff(&Run{ID: "from", Plugins: test.from}, &Run{ID: "to", Plugins: test.to})
+
+		added, removed, changed := []string{}, []string{}, []string{}
+
+		for _, diff := range diffs {
+			switch {
+			case diff.From == nil:
+				added = append(added, diff.Name)
+			case diff.To == nil:
+				removed = append(removed, diff.Name)
+			default:
+				changed = append(changed, diff.Name)
+			}
+		}
+
+		if !sameNames(added, test.added) || !sameNames(removed, test.removed) || !sameNames(changed, test.changed) {
+			t.Errorf("%v: added %v, removed %v, changed %v, want %v, %v, %v", test.name,
+				added, removed, changed, test.added, test.removed, test.changed)
+		}
+	}
+}
+
+//sameNames compares two lists of plugin names including their order
+func sameNames(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
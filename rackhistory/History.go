@@ -0,0 +1,228 @@
+// Package rackhistory includes the structs and functions to record the runs of rackjobber on a shop.
+// Every run is written to its own file in custom/rackjobber/history, existing runs are never changed.
+package rackhistory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackhealth"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackplan"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackstate"
+)
+
+// Outcomes of runs, steps and commands
+const (
+	OutcomeSucceeded = "succeeded"
+	OutcomeFailed    = "failed"
+	OutcomeSkipped   = "skipped"
+	// OutcomeCancelled is the outcome of a cancelled run and of the steps, that were left out because of it
+	OutcomeCancelled = "cancelled"
+)
+
+// Run is the record of a single run of rackjobber on a shop
+type Run struct {
+	ID             string        `json:"id"`
+	Shop           string        `json:"shop"`
+	Command        string        `json:"command"`
+	User           string        `json:"user,omitempty"`
+	Host           string        `json:"host,omitempty"`
+	StartedAt      time.Time     `json:"startedAt"`
+	FinishedAt     time.Time     `json:"finishedAt"`
+	DurationMillis int64         `json:"durationMs"`
+	Outcome        string        `json:"outcome"`
+	Error          string        `json:"error,omitempty"`
+	Plan           rackplan.Plan `json:"plan"`
+	Steps          []StepResult  `json:"steps"`
+	// Plugins is the deployment state of the shop after the run
+	Plugins []rackstate.PluginState `json:"plugins"`
+	// Health are the results of the health checks after the run
+	Health []rackhealth.Result `json:"health,omitempty"`
+}
+
+// StepResult is the record of a single executed step of the plan
+type StepResult struct {
+	Step           rackplan.Step   `json:"step"`
+	Outcome        string          `json:"outcome"`
+	StartedAt      time.Time       `json:"startedAt"`
+	DurationMillis int64           `json:"durationMs"`
+	Commands       []CommandResult `json:"commands,omitempty"`
+	Error          string          `json:"error,omitempty"`
+}
+
+// CommandResult is the record of a single command, that was run for a step
+type CommandResult struct {
+	Command        string `json:"command"`
+	Output         string `json:"output,omitempty"`
+	DurationMillis int64  `json:"durationMs"`
+	Error          string `json:"error,omitempty"`
+}
+
+// NewRun returns the record of a run, that starts executing the given plan
+func NewRun(command string, plan rackplan.Plan) *Run {
+	user, host := rackstate.Deployer()
+
+	return &Run{
+		ID:        plan.RunID,
+		Shop:      plan.Shop,
+		Command:   command,
+		User:      user,
+		Host:      host,
+		StartedAt: time.Now().UTC(),
+		Plan:      plan,
+		Steps:     []StepResult{},
+	}
+}
+
+// StartStep adds the record of a step, that starts executing, and returns it
+func (r *Run) StartStep(step rackplan.Step) *StepResult {
+	r.Steps = append(r.Steps, StepResult{Step: step, StartedAt: time.Now().UTC(), Commands: []CommandResult{}})
+	return &r.Steps[len(r.Steps)-1]
+}
+
+// Finish sets the outcome of the run and the deployment state it left the shop in.
+// The run is cancelled, if the error is context.Canceled.
+func (r *Run) Finish(state *rackstate.State, err error) {
+	r.FinishedAt = time.Now().UTC()
+	r.DurationMillis = int64(r.FinishedAt.Sub(r.StartedAt) / time.Millisecond)
+	r.Plugins = append([]rackstate.PluginState{}, state.Plugins...)
+	r.Outcome = OutcomeSucceeded
+
+	switch {
+	case err == context.Canceled:
+		r.Outcome = OutcomeCancelled
+		r.Error = err.Error()
+	case err != nil:
+		r.Outcome = OutcomeFailed
+		r.Error = err.Error()
+	}
+}
+
+// Succeeded returns if the run finished without errors
+func (r Run) Succeeded() bool {
+	return r.Outcome == OutcomeSucceeded
+}
+
+// Finish sets the outcome and duration of the step
+func (s *StepResult) Finish(outcome string, err error) {
+	s.DurationMillis = int64(time.Since(s.StartedAt) / time.Millisecond)
+	s.Outcome = outcome
+
+	if err != nil {
+		s.Outcome = OutcomeFailed
+		s.Error = err.Error()
+	}
+}
+
+// AddCommand adds the result of a command to the step
+func (s *StepResult) AddCommand(command string, output string, duration time.Duration, err error) {
+	result := CommandResult{Command: command, Output: output, DurationMillis: int64(duration / time.Millisecond)}
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	s.Commands = append(s.Commands, result)
+}
+
+// PrintSummary prints a single line describing the run
+func (r Run) PrintSummary() {
+	fmt.Printf("%v  %-9v  %-8v  %v@%v  %vs  %v changes%v\n", r.ID, r.Outcome, r.Command, r.User, r.Host,
+		r.DurationMillis/millisPerSecond, r.countChanges(), r.healthSummary())
+}
+
+// Healthy returns if all health checks of the run succeeded
+func (r Run) Healthy() bool {
+	for _, result := range r.Health {
+		if !result.Healthy {
+			return false
+		}
+	}
+
+	return true
+}
+
+//healthSummary returns the number of successful health checks of the run, if it ran any
+func (r Run) healthSummary() string {
+	if len(r.Health) == 0 {
+		return ""
+	}
+
+	healthy := 0
+
+	for _, result := range r.Health {
+		if result.Healthy {
+			healthy++
+		}
+	}
+
+	return fmt.Sprintf("  %v/%v healthy", healthy, len(r.Health))
+}
+
+// Print prints the run with all of its steps and commands
+func (r Run) Print() {
+	fmt.Printf("Run:      %v\n", r.ID)
+	fmt.Printf("Shop:     %v\n", r.Shop)
+	fmt.Printf("Command:  %v\n", r.Command)
+	fmt.Printf("By:       %v@%v\n", r.User, r.Host)
+	fmt.Printf("Started:  %v\n", r.StartedAt.Format(time.RFC3339))
+	fmt.Printf("Duration: %vms\n", r.DurationMillis)
+	fmt.Printf("Outcome:  %v\n", r.Outcome)
+
+	if len(r.Error) > 0 {
+		fmt.Printf("Error:    %v\n", r.Error)
+	}
+
+	fmt.Println("Steps:")
+
+	for _, step := range r.Steps {
+		fmt.Printf(" - %v: %v (%vms)\n", step.Step.String(), step.Outcome, step.DurationMillis)
+
+		for _, command := range step.Commands {
+			fmt.Printf("     $ %v (%vms)\n", command.Command, command.DurationMillis)
+
+			if len(command.Output) > 0 {
+				fmt.Println(indent(command.Output, "       "))
+			}
+
+			if len(command.Error) > 0 {
+				fmt.Printf("       error: %v\n", command.Error)
+			}
+		}
+
+		if len(step.Error) > 0 {
+			fmt.Printf("   error: %v\n", step.Error)
+		}
+	}
+
+	if len(r.Health) > 0 {
+		fmt.Println("Health:")
+	}
+
+	for _, result := range r.Health {
+		fmt.Println(" - " + result.String())
+	}
+}
+
+const millisPerSecond = 1000
+
+//countChanges returns the number of deployed and deleted plugins of the run
+func (r Run) countChanges() int {
+	changes := 0
+
+	for _, step := range r.Steps {
+		if step.Outcome == OutcomeSucceeded &&
+			(step.Step.Action == rackplan.ActionDeploy || step.Step.Action == rackplan.ActionDelete) {
+			changes++
+		}
+	}
+
+	return changes
+}
+
+//indent prefixes every line of the text
+func indent(text string, prefix string) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	return prefix + strings.Join(lines, "\n"+prefix)
+}
@@ -0,0 +1,162 @@
+package rackhistory
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshop"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshopstore"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackssh"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackstate"
+)
+
+// HistoryDir is the directory of a shop, that contains the records of the runs
+const HistoryDir = rackstate.StateDir + "/history"
+
+// WriteRun writes the record of a run to the history of a shop.
+// A record, that already exists, is not overridden.
+func WriteRun(executor rackssh.Executor, shop *rackshop.RackShop, run *Run) error {
+	data, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(shop.ShopwareDir, HistoryDir)
+	path := filepath.Join(dir, run.ID+".json")
+
+	if out, err := executor.Run("mkdir -p " + dir + " && test ! -e " + path); err != nil {
+		return errors.New("the run " + run.ID + " is already recorded: " + out)
+	}
+
+	return executor.WriteFile(path, data)
+}
+
+// ListRunIDs returns the IDs of all recorded runs of a shop, the oldest first
+func ListRunIDs(executor rackssh.Executor, shop *rackshop.RackShop) ([]string, error) {
+	dir := filepath.Join(shop.ShopwareDir, HistoryDir)
+
+	out, err := executor.Run("mkdir -p " + dir + " && ls -1 " + dir)
+	if err != nil {
+		return nil, errors.New("failed to list the history: " + out)
+	}
+
+	ids := []string{}
+
+	for _, name := range strings.Split(out, "\n") {
+		name = strings.TrimSpace(name)
+		if strings.HasSuffix(name, ".json") {
+			ids = append(ids, strings.TrimSuffix(name, ".json"))
+		}
+	}
+
+	sort.Strings(ids)
+
+	return ids, nil
+}
+
+// ListRuns returns all recorded runs of a shop, the oldest first
+func ListRuns(executor rackssh.Executor, shop *rackshop.RackShop) ([]Run, error) {
+	ids, err := ListRunIDs(executor, shop)
+	if err != nil {
+		return nil, err
+	}
+
+	runs := []Run{}
+
+	for _, id := range ids {
+		run, err := readRun(executor, shop, id)
+		if err != nil {
+			return nil, err
+		}
+
+		runs = append(runs, *run)
+	}
+
+	return runs, nil
+}
+
+// ReadRun returns a recorded run of a shop.
+// The run can be given by its full ID, a unique prefix of it or "latest".
+func ReadRun(executor rackssh.Executor, shop *rackshop.RackShop, runID string) (*Run, error) {
+	ids, err := ListRunIDs(executor, shop)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := resolveRunID(ids, runID)
+	if err != nil {
+		return nil, err
+	}
+
+	return readRun(executor, shop, id)
+}
+
+//readRun reads the record of the run with the given ID
+func readRun(executor rackssh.Executor, shop *rackshop.RackShop, id string) (*Run, error) {
+	data, err := executor.ReadFile(filepath.Join(shop.ShopwareDir, HistoryDir, id+".json"))
+	if err != nil {
+		return nil, err
+	}
+
+	run := &Run{}
+	if err = json.Unmarshal(data, run); err != nil {
+		return nil, errors.New("failed to parse run " + id + ": " + err.Error())
+	}
+
+	return run, nil
+}
+
+//resolveRunID returns the ID matching the given ID, prefix or "latest"
+func resolveRunID(ids []string, runID string) (string, error) {
+	if len(ids) == 0 {
+		return "", errors.New("no runs recorded for this shop")
+	}
+
+	if runID == "latest" {
+		return ids[len(ids)-1], nil
+	}
+
+	matches := []string{}
+
+	for _, id := range ids {
+		if id == runID {
+			return id, nil
+		}
+
+		if strings.HasPrefix(id, runID) {
+			matches = append(matches, id)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", errors.New("no run found for " + runID)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", errors.New("the run " + runID + " is ambiguous: " + strings.Join(matches, ", "))
+	}
+}
+
+// ListRunsOfShop returns all recorded runs of a shop in the shop store, the oldest first
+func ListRunsOfShop(shopName string) ([]Run, error) {
+	shop, err := rackshopstore.GetShopFromStore(shopName)
+	if err != nil {
+		return nil, err
+	}
+
+	return ListRuns(rackssh.NewExecutor(shop), shop)
+}
+
+// ReadRunOfShop returns a recorded run of a shop in the shop store
+func ReadRunOfShop(shopName string, runID string) (*Run, error) {
+	shop, err := rackshopstore.GetShopFromStore(shopName)
+	if err != nil {
+		return nil, err
+	}
+
+	return ReadRun(rackssh.NewExecutor(shop), shop, runID)
+}
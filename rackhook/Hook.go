@@ -0,0 +1,99 @@
+// Package rackhook includes the structs of hooks, that run commands on a shop before or after a deployment.
+// Hooks are defined in the shop store for the whole up, in the rackfile around the deployment of a plugin
+// and in the rackspec of a plugin after its installation.
+package rackhook
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Policies, that decide what happens when a hook fails
+const (
+	// OnFailureAbort stops the run, this is the default
+	OnFailureAbort = "abort"
+	// OnFailureWarn prints a warning and continues the run
+	OnFailureWarn = "warn"
+	// OnFailureRollback stops the run and restores the last successful run of the shop
+	OnFailureRollback = "rollback"
+)
+
+// Policies lists the known failure policies of a hook
+var Policies = []string{OnFailureAbort, OnFailureWarn, OnFailureRollback}
+
+// Hook is a command, that is run on the shop by up.
+// Either Run or Console is set.
+type Hook struct {
+	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+	// Run is a shell command, that is run in the shopware directory of the shop
+	Run string `yaml:"run,omitempty" json:"run,omitempty"`
+	// Console is a command of the shopware console, that is run in the container of the shop
+	Console string `yaml:"console,omitempty" json:"console,omitempty"`
+	// OnFailure is one of Policies, abort if it is empty
+	OnFailure string `yaml:"onFailure,omitempty" json:"onFailure,omitempty"`
+}
+
+// Hooks are the hooks, that run before and after something
+type Hooks struct {
+	Before []Hook `yaml:"before,omitempty"`
+	After  []Hook `yaml:"after,omitempty"`
+}
+
+// Policy returns the failure policy of the hook
+func (h Hook) Policy() string {
+	if len(h.OnFailure) == 0 {
+		return OnFailureAbort
+	}
+
+	return h.OnFailure
+}
+
+// String returns the name of the hook, or its command if it has no name
+func (h Hook) String() string {
+	switch {
+	case len(h.Name) > 0:
+		return h.Name
+	case len(h.Console) > 0:
+		return h.Console
+	default:
+		return h.Run
+	}
+}
+
+// Validate checks that the hook has exactly one command and a known failure policy
+func (h Hook) Validate() error {
+	if (len(h.Run) > 0) == (len(h.Console) > 0) {
+		return errors.New("the hook " + h.String() + " needs either run or console")
+	}
+
+	return ValidatePolicy(h.OnFailure)
+}
+
+// ValidatePolicy checks if the given failure policy is empty or one of the known Policies
+func ValidatePolicy(policy string) error {
+	if policy == "" {
+		return nil
+	}
+
+	for _, known := range Policies {
+		if policy == known {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown failure policy %v, must be one of %v", policy, strings.Join(Policies, ", "))
+}
+
+// ValidateHooks checks all given hooks
+func ValidateHooks(hooks ...[]Hook) error {
+	for _, list := range hooks {
+		for _, hook := range list {
+			if err := hook.Validate(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -22,9 +22,10 @@ func InputAuth(domain string) http.BasicAuth {
 	return auth
 }
 
-//AwaitTextInput returns the user input
+//AwaitTextInput returns the user input.
+//The label is written to stderr, so it does not mix with the JSON on the standard output.
 func AwaitTextInput(label string) string {
-	fmt.Println(label)
+	fmt.Fprintln(os.Stderr, label)
 
 	scanner := bufio.NewScanner(os.Stdin)
 	scanner.Scan()
@@ -34,7 +35,7 @@ func AwaitTextInput(label string) string {
 
 //AwaitPasswordInput returns the user input, which is hidden in the console
 func AwaitPasswordInput(label string) string {
-	fmt.Println(label)
+	fmt.Fprintln(os.Stderr, label)
 
 	password, _ := terminal.ReadPassword(int(syscall.Stdin)) //nolint, conversion necessary for Windows compilation
 	encPassword := hex.EncodeToString(password)
@@ -0,0 +1,111 @@
+// Package rackinspect includes functions to inspect the plugins that are actually present on a shop
+package rackinspect
+
+import (
+	"encoding/xml"
+	"path/filepath"
+	"strings"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/gitutil"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackplugin"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshop"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackssh"
+)
+
+// PluginDir is the directory of a shop, that contains the plugins managed by rackjobber
+const PluginDir = "custom/plugins"
+
+// InstalledPlugin contains the information about a plugin directory on a shop
+type InstalledPlugin struct {
+	Name string
+	// Version is the version of the plugin.xml, empty if the plugin has no plugin.xml
+	Version string
+	// IsGit is set, if the plugin directory is a git checkout
+	IsGit bool
+	// Commit is the hash of the checked out commit
+	Commit string
+	// Tag is the tag pointing at the checked out commit, if there is one
+	Tag string
+	// Origin is the url of the origin remote
+	Origin string
+	// Modified is set, if the checkout contains uncommitted changes
+	Modified bool
+}
+
+// ListPluginDirs returns the names of all plugin directories of a shop
+func ListPluginDirs(executor rackssh.Executor, shop *rackshop.RackShop) ([]string, error) {
+	pluginDir := filepath.Join(shop.ShopwareDir, PluginDir)
+
+	out, err := executor.Run("cd " + pluginDir + ` && for d in */; do [ -d "$d" ] && echo "${d%/}"; done; true`)
+	if err != nil {
+		return nil, err
+	}
+
+	return splitLines(out), nil
+}
+
+// InspectPlugins returns the information about all plugin directories of a shop
+func InspectPlugins(executor rackssh.Executor, shop *rackshop.RackShop) ([]InstalledPlugin, error) {
+	names, err := ListPluginDirs(executor, shop)
+	if err != nil {
+		return nil, err
+	}
+
+	plugins := []InstalledPlugin{}
+
+	for _, name := range names {
+		plugins = append(plugins, InspectPlugin(executor, shop, name))
+	}
+
+	return plugins, nil
+}
+
+// InspectPlugin returns the information about a single plugin directory of a shop
+func InspectPlugin(executor rackssh.Executor, shop *rackshop.RackShop, name string) InstalledPlugin {
+	pluginPath := filepath.Join(shop.ShopwareDir, PluginDir, name)
+	plugin := InstalledPlugin{Name: name}
+
+	if data, err := executor.ReadFile(filepath.Join(pluginPath, "plugin.xml")); err == nil {
+		pluginXML := &rackplugin.Plugin{}
+		if err := xml.Unmarshal(data, pluginXML); err == nil {
+			plugin.Version = strings.TrimSpace(pluginXML.Version)
+		}
+	}
+
+	git := "git -C " + pluginPath + " "
+
+	commit, err := executor.Run(git + "rev-parse HEAD")
+	if err != nil {
+		return plugin
+	}
+
+	plugin.IsGit = true
+	plugin.Commit = strings.TrimSpace(commit)
+
+	if tag, err := executor.Run(git + "describe --tags --exact-match HEAD"); err == nil {
+		plugin.Tag = strings.TrimSpace(tag)
+	}
+
+	if origin, err := executor.Run(git + "remote get-url origin"); err == nil {
+		plugin.Origin = gitutil.StripURLAuth(strings.TrimSpace(origin))
+	}
+
+	if status, err := executor.Run(git + "status --porcelain"); err == nil {
+		plugin.Modified = len(strings.TrimSpace(status)) > 0
+	}
+
+	return plugin
+}
+
+//splitLines splits the output of a command into its non-empty lines
+func splitLines(output string) []string {
+	lines := []string{}
+
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); len(line) > 0 {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
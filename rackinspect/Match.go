@@ -0,0 +1,43 @@
+package rackinspect
+
+import (
+	"errors"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackspec"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/repository"
+)
+
+// SpecMatch is the rackspec an installed plugin corresponds to
+type SpecMatch struct {
+	Repo    string
+	Version string
+	Spec    *rackspec.RackSpec
+}
+
+// MatchRackSpec finds the rackspec of the local repositories, that corresponds to an installed plugin.
+// The version is matched by the tag of the checkout, the given additional versions and the version of the plugin.xml.
+func MatchRackSpec(plugin InstalledPlugin, additionalVersions ...string) (*SpecMatch, error) {
+	if !plugin.IsGit {
+		return nil, errors.New("no git checkout")
+	}
+
+	repoName, err := repository.FindPluginRepo(plugin.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := append([]string{plugin.Tag}, additionalVersions...)
+	candidates = append(candidates, plugin.Version)
+
+	version, err := repository.MatchPluginVersion(repoName, plugin.Name, candidates...)
+	if err != nil {
+		return nil, err
+	}
+
+	spec, _, err := repository.GetRackSpec(plugin.Name, version)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SpecMatch{repoName, version, spec}, nil
+}
@@ -0,0 +1,80 @@
+package rackinspect
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/fileutil"
+)
+
+func TestMatchRackSpec(t *testing.T) {
+	home, err := ioutil.TempDir("", "rackjobber")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(home)
+
+	if err := fileutil.SetHome(home); err != nil {
+		t.Fatal(err)
+	}
+
+	dataPath, err := fileutil.GetAppFolderPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, version := range []string{"1.0.0", "1.2.0"} {
+		specDir := filepath.Join(*dataPath, "repos", "master", "SwagPlugin", version)
+		if err := os.MkdirAll(specDir, os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+
+		spec := []byte("name: SwagPlugin\nversion: " + version + "\nsource:\n  GIT: https://git.example.com/swag.git\n")
+		if err := ioutil.WriteFile(filepath.Join(specDir, "rackspec.yaml"), spec, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		plugin      InstalledPlugin
+		additional  []string
+		wantVersion string
+		wantErr     bool
+	}{
+		{name: "tag", plugin: InstalledPlugin{Name: "SwagPlugin", IsGit: true, Tag: "v1.2.0", Version: "1.0.0"},
+			wantVersion: "1.2.0"},
+		{name: "plugin.xml", plugin: InstalledPlugin{Name: "SwagPlugin", IsGit: true, Version: "1.0"},
+			wantVersion: "1.0.0"},
+		{name: "additional version", plugin: InstalledPlugin{Name: "SwagPlugin", IsGit: true, Version: "0.9.0"},
+			additional: []string{"1.2.0"}, wantVersion: "1.2.0"},
+		{name: "unknown version", plugin: InstalledPlugin{Name: "SwagPlugin", IsGit: true, Tag: "2.0.0"},
+			wantErr: true},
+		{name: "no git checkout", plugin: InstalledPlugin{Name: "SwagPlugin", Version: "1.2.0"}, wantErr: true},
+		{name: "no rackspec", plugin: InstalledPlugin{Name: "OtherPlugin", IsGit: true, Tag: "1.2.0"}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		match, err := MatchRackSpec(test.plugin, test.additional...)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%v: expected an error, got %+v", test.name, match)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.name, err)
+			continue
+		}
+
+		if match.Repo != "master" || match.Version != test.wantVersion ||
+			match.Spec.Source.GIT != "https://git.example.com/swag.git" {
+			t.Errorf("%v: got %+v, want version %v", test.name, match, test.wantVersion)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package rackinspect
+
+import (
+	"errors"
+	"strings"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshop"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackssh"
+)
+
+// PluginState contains the state of a plugin as known by shopware
+type PluginState struct {
+	Name      string
+	Label     string
+	Version   string
+	Active    bool
+	Installed bool
+}
+
+// ReadPluginStates runs the plugin list of the shopware console and returns the state of every plugin by its name
+func ReadPluginStates(executor rackssh.Executor, shop *rackshop.RackShop) (map[string]PluginState, error) {
+	out, err := executor.Run(rackssh.ConsoleCommand(shop, "sw:plugin:list"))
+	if err != nil {
+		return nil, errors.New("sw:plugin:list failed: " + strings.TrimSpace(out))
+	}
+
+	return parsePluginList(out)
+}
+
+//parsePluginList parses the table printed by sw:plugin:list.
+//The columns are identified by their header, so additional columns of other shopware versions are ignored.
+func parsePluginList(out string) (map[string]PluginState, error) {
+	states := map[string]PluginState{}
+
+	var header []string
+
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "|") {
+			continue
+		}
+
+		cells := splitTableRow(line)
+
+		if header == nil {
+			header = cells
+			continue
+		}
+
+		state := PluginState{}
+
+		for i, cell := range cells {
+			if i >= len(header) {
+				break
+			}
+
+			switch strings.ToLower(header[i]) {
+			case "plugin", "name":
+				state.Name = cell
+			case "label":
+				state.Label = cell
+			case "version":
+				state.Version = cell
+			case "active":
+				state.Active = isYes(cell)
+			case "installed":
+				state.Installed = isYes(cell)
+			}
+		}
+
+		if len(state.Name) > 0 {
+			states[state.Name] = state
+		}
+	}
+
+	if header == nil {
+		return nil, errors.New("unable to parse the plugin list of the shopware console")
+	}
+
+	return states, nil
+}
+
+//splitTableRow returns the trimmed cells of a row like "| a | b |"
+func splitTableRow(line string) []string {
+	parts := strings.Split(strings.Trim(line, "|"), "|")
+	cells := make([]string, len(parts))
+
+	for i, part := range parts {
+		cells[i] = strings.TrimSpace(part)
+	}
+
+	return cells
+}
+
+func isYes(value string) bool {
+	value = strings.ToLower(value)
+	return value == "yes" || value == "1" || value == "true"
+}
@@ -0,0 +1,50 @@
+package rackinspect
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePluginList(t *testing.T) {
+	shopware55 := `
++--------------+--------------+---------+------------+--------+-----------+
+| Plugin       | Label        | Version | Author     | Active | Installed |
++--------------+--------------+---------+------------+--------+-----------+
+| SwagPlugin   | Swag Plugin  | 1.2.0   | shopware   | Yes    | Yes       |
+| OtherPlugin  | Other        | 0.1.0   | worldiety  | No     | Yes       |
+| LegacyPlugin | Legacy       | 2.0.0   | worldiety  | No     | No        |
++--------------+--------------+---------+------------+--------+-----------+
+`
+	reordered := `| Name | Installed | Active | Version |
+| SwagPlugin | 1 | 0 | 1.2.0 |
+`
+
+	tests := []struct {
+		name    string
+		out     string
+		want    map[string]PluginState
+		wantErr bool
+	}{
+		{name: "shopware 5.5", out: shopware55, want: map[string]PluginState{
+			"SwagPlugin":   {Name: "SwagPlugin", Label: "Swag Plugin", Version: "1.2.0", Active: true, Installed: true},
+			"OtherPlugin":  {Name: "OtherPlugin", Label: "Other", Version: "0.1.0", Installed: true},
+			"LegacyPlugin": {Name: "LegacyPlugin", Label: "Legacy", Version: "2.0.0"},
+		}},
+		{name: "reordered columns", out: reordered, want: map[string]PluginState{
+			"SwagPlugin": {Name: "SwagPlugin", Version: "1.2.0", Installed: true},
+		}},
+		{name: "no table", out: "Could not open input file: bin/console\n", wantErr: true},
+	}
+
+	for _, test := range tests {
+		states, err := parsePluginList(test.out)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%v: got error %v, want an error %v", test.name, err, test.wantErr)
+			continue
+		}
+
+		if !test.wantErr && !reflect.DeepEqual(states, test.want) {
+			t.Errorf("%v: got %+v, want %+v", test.name, states, test.want)
+		}
+	}
+}
@@ -1,32 +1,48 @@
 package main
 
 import (
-	"log"
+	"errors"
 	"os"
-	"path/filepath"
 
 	"github.com/urfave/cli"
 	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/fileutil"
 	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackcommands"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racklog"
 )
 
 func main() {
 	var app = cli.NewApp()
 
-	setup()
 	info(app)
 	commands(app)
 
+	app.Flags = rackcommands.GlobalFlags()
+	app.Before = setup
+
 	err := app.Run(os.Args)
 	if err != nil {
-		log.Fatal(err)
+		racklog.Fatalf("%v", err)
 	}
+
+	_ = racklog.Close()
 }
 
-func setup() {
-	rackresourcePath, _ := fileutil.GetAppFolderPath()
-	_ = fileutil.CreateDirIfNotExistant(*rackresourcePath)
-	_ = fileutil.CreateDirIfNotExistant(filepath.Join(*rackresourcePath, "repos"))
+//setup validates the global flags and creates the folders of rackjobber, after the flags chose them
+func setup(c *cli.Context) error {
+	if err := rackcommands.ValidateGlobalFlags(c); err != nil {
+		return err
+	}
+
+	if err := fileutil.CreateHomeFolders(); err != nil {
+		return errors.New("failed to create the folders of rackjobber: " + err.Error())
+	}
+
+	if fileutil.UsesLegacyFolder() && c.Args().First() != "migrate" {
+		racklog.Infof("The config and the repositories are kept next to the executable, " +
+			"run 'rackjobber migrate' to move them to the home of the user")
+	}
+
+	return nil
 }
 
 func info(app *cli.App) {
@@ -58,5 +74,13 @@ func commands(app *cli.App) {
 		rackcommands.ShopCommand(),
 		rackcommands.PluginCommand(),
 		rackcommands.UpCommand(),
+		rackcommands.RollbackCommand(),
+		rackcommands.BackupCommand(),
+		rackcommands.FreezeCommand(),
+		rackcommands.StatusCommand(),
+		rackcommands.HistoryCommand(),
+		rackcommands.HealthCommand(),
+		rackcommands.NotifyCommand(),
+		rackcommands.MigrateCommand(),
 	}
 }
@@ -0,0 +1,261 @@
+// Package racklock includes the lock, that prevents concurrent deployments to a shop.
+// The lock is a file on the shop, so it works for all machines deploying to the shop.
+package racklock
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racklog"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshop"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackssh"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackstate"
+)
+
+// LockFile is the lock file of a shop
+const LockFile = rackstate.StateDir + "/deploy.lock"
+
+// DefaultTTL is the time after which a lock is considered abandoned, unless its holder refreshes it
+const DefaultTTL = 2 * time.Hour
+
+//notHeld is printed by the refresh command, if the lock is held by another process
+const notHeld = "rackjobber-lock-not-held"
+
+// retryInterval is the time to wait before a held lock is checked again
+const retryInterval = 5 * time.Second
+
+// Lock describes the holder of the lock of a shop
+type Lock struct {
+	ID        string    `json:"id"`
+	Command   string    `json:"command"`
+	Owner     string    `json:"owner"`
+	Host      string    `json:"host"`
+	PID       int       `json:"pid"`
+	StartedAt time.Time `json:"startedAt"`
+	TTL       string    `json:"ttl"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Expired returns if the lock is older than its time to live
+func (l Lock) Expired() bool {
+	return time.Now().After(l.ExpiresAt)
+}
+
+// String returns a human readable description of the holder of the lock
+func (l Lock) String() string {
+	return fmt.Sprintf("%v by %v@%v (pid %v) since %v, expires %v", l.Command, l.Owner, l.Host, l.PID,
+		l.StartedAt.Local().Format(time.RFC3339), l.ExpiresAt.Local().Format(time.RFC3339))
+}
+
+// Acquire locks the shop for the given command.
+// If the shop is locked, it waits for at most wait for the lock to be released, or until the context is done.
+// Expired locks are taken over.
+func Acquire(ctx context.Context, executor rackssh.Executor, shop *rackshop.RackShop, command string,
+	wait time.Duration, ttl time.Duration) (*Lock, error) {
+	owner, host := rackstate.Deployer()
+	now := time.Now().UTC()
+	lock := &Lock{
+		ID:        rackstate.NewRunID(),
+		Command:   command,
+		Owner:     owner,
+		Host:      host,
+		PID:       os.Getpid(),
+		StartedAt: now,
+		TTL:       ttl.String(),
+		ExpiresAt: now.Add(ttl),
+	}
+
+	data, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(shop.ShopwareDir, LockFile)
+	command = "mkdir -p " + filepath.Dir(path) + " && echo " + base64.StdEncoding.EncodeToString(data) +
+		" | base64 -d | (set -C; cat > " + path + ")"
+	deadline := time.Now().Add(wait)
+
+	for {
+		out, createErr := executor.Run(command)
+		if createErr == nil {
+			return lock, nil
+		}
+
+		held, err := Read(executor, shop)
+		if err != nil {
+			return nil, err
+		}
+
+		if held == nil {
+			return nil, errors.New("failed to lock " + shop.Name + ": " + strings.TrimSpace(out))
+		}
+
+		if held.Expired() {
+			racklog.With("shop", shop.Name).Warnf("Taking over the expired lock of %v: %v", shop.Name, held.String())
+
+			if err = remove(executor, shop); err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, errors.New("the shop " + shop.Name + " is locked: " + held.String())
+		}
+
+		racklog.With("shop", shop.Name).Infof("Waiting for the lock of %v: %v", shop.Name, held.String())
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(retryInterval):
+		}
+	}
+}
+
+// Refresh extends the lock by its time to live, if it is still held by this lock.
+// The lock is checked and replaced by a single command, that renames the refreshed lock over the held one,
+// so a lock taken over by another process after it expired is not overwritten.
+func (l *Lock) Refresh(executor rackssh.Executor, shop *rackshop.RackShop) error {
+	ttl, err := time.ParseDuration(l.TTL)
+	if err != nil {
+		return err
+	}
+
+	refreshed := *l
+	refreshed.ExpiresAt = time.Now().UTC().Add(ttl)
+
+	data, err := json.MarshalIndent(refreshed, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(shop.ShopwareDir, LockFile)
+	temp := path + "." + l.ID
+	id := fmt.Sprintf("%q: %q", "id", l.ID)
+	command := "echo " + base64.StdEncoding.EncodeToString(data) + " | base64 -d > " + rackssh.QuoteArgument(temp) +
+		" && if grep -qF " + rackssh.QuoteArgument(id) + " " + rackssh.QuoteArgument(path) +
+		"; then mv -f " + rackssh.QuoteArgument(temp) + " " + rackssh.QuoteArgument(path) +
+		"; else rm -f " + rackssh.QuoteArgument(temp) + "; echo " + notHeld + "; exit 1; fi"
+
+	if out, err := executor.Run(command); err != nil {
+		if strings.Contains(out, notHeld) {
+			return errors.New("the lock of " + shop.Name + " is no longer held by this process")
+		}
+
+		return errors.New("failed to refresh the lock of " + shop.Name + ": " + err.Error() + " " +
+			strings.TrimSpace(out))
+	}
+
+	l.ExpiresAt = refreshed.ExpiresAt
+
+	return nil
+}
+
+// KeepAlive refreshes the lock every third of its time to live, so runs taking longer than the time to live
+// keep the lock. The returned function stops the refreshing and waits for a running refresh to finish.
+func (l *Lock) KeepAlive(executor rackssh.Executor, shop *rackshop.RackShop) func() {
+	const refreshesPerTTL = 3
+
+	ttl, err := time.ParseDuration(l.TTL)
+	if err != nil || ttl <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(ttl / refreshesPerTTL)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := l.Refresh(executor, shop); err != nil {
+					racklog.With("shop", shop.Name).Warnf("failed to refresh the lock of %v: %v", shop.Name, err)
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			close(done)
+			<-stopped
+		})
+	}
+}
+
+// Release removes the lock from the shop, if it is still held by this lock
+func (l *Lock) Release(executor rackssh.Executor, shop *rackshop.RackShop) error {
+	held, err := Read(executor, shop)
+	if err != nil {
+		return err
+	}
+
+	if held == nil || held.ID != l.ID {
+		return errors.New("the lock of " + shop.Name + " is no longer held by this process")
+	}
+
+	return remove(executor, shop)
+}
+
+// Read returns the lock of the shop, or nil if the shop is not locked
+func Read(executor rackssh.Executor, shop *rackshop.RackShop) (*Lock, error) {
+	path := filepath.Join(shop.ShopwareDir, LockFile)
+
+	if _, err := executor.Run("test -e " + path); err != nil {
+		return nil, nil
+	}
+
+	data, err := executor.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	lock := &Lock{}
+	if err = json.Unmarshal(data, lock); err != nil {
+		return nil, errors.New("failed to parse the lock of " + shop.Name + ": " + err.Error())
+	}
+
+	return lock, nil
+}
+
+// Unlock removes the lock of a shop, that is expired, or any lock if force is set
+func Unlock(executor rackssh.Executor, shop *rackshop.RackShop, force bool) error {
+	held, err := Read(executor, shop)
+	if err != nil && !force {
+		return err
+	}
+
+	if held != nil && !held.Expired() && !force {
+		return errors.New("the lock of " + shop.Name + " is not expired, use --force to remove it: " + held.String())
+	}
+
+	return remove(executor, shop)
+}
+
+//remove deletes the lock file of the shop
+func remove(executor rackssh.Executor, shop *rackshop.RackShop) error {
+	if out, err := executor.Run("rm -f " + filepath.Join(shop.ShopwareDir, LockFile)); err != nil {
+		return errors.New("failed to remove the lock of " + shop.Name + ": " + strings.TrimSpace(out))
+	}
+
+	return nil
+}
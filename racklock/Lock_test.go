@@ -0,0 +1,143 @@
+package racklock
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/fileutil"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshop"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackssh"
+)
+
+//localShop returns a local shop in a temporary folder and its executor, the returned function removes the folder
+func localShop(t *testing.T) (*rackshop.RackShop, rackssh.Executor, func()) {
+	dir, err := ioutil.TempDir("", "rackjobber")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fileutil.SetHome(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	shop := &rackshop.RackShop{Name: "my-shop", Local: true, ShopwareDir: dir}
+
+	return shop, rackssh.NewExecutor(shop), func() { _ = os.RemoveAll(dir) }
+}
+
+func TestAcquire(t *testing.T) {
+	shop, executor, cleanup := localShop(t)
+	defer cleanup()
+
+	held, err := Acquire(context.Background(), executor, shop, "up", 0, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		wait    time.Duration
+		wantErr string
+	}{
+		{name: "held lock", ctx: context.Background(), wantErr: "is locked"},
+		{name: "cancelled while waiting", ctx: cancelled, wait: time.Minute, wantErr: "canceled"},
+	}
+
+	for _, test := range tests {
+		_, err := Acquire(test.ctx, executor, shop, "rollback", test.wait, time.Hour)
+		if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+			t.Errorf("%v: got error %v, want %q", test.name, err, test.wantErr)
+		}
+
+		if read, _ := Read(executor, shop); read == nil || read.ID != held.ID {
+			t.Errorf("%v: the shop is locked by %+v, want %v", test.name, read, held.ID)
+		}
+	}
+
+	if err := held.Release(executor, shop); err != nil {
+		t.Fatal(err)
+	}
+
+	if read, err := Read(executor, shop); read != nil || err != nil {
+		t.Errorf("the released shop is locked by %+v, %v", read, err)
+	}
+}
+
+func TestRefreshAfterTakeOver(t *testing.T) {
+	shop, executor, cleanup := localShop(t)
+	defer cleanup()
+
+	expired, err := Acquire(context.Background(), executor, shop, "up", 0, -time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	taken, err := Acquire(context.Background(), executor, shop, "up", 0, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := expired.Refresh(executor, shop); err == nil || !strings.Contains(err.Error(), "no longer held") {
+		t.Errorf("refreshing the taken over lock: got %v", err)
+	}
+
+	if err := expired.Release(executor, shop); err == nil {
+		t.Error("expected releasing the taken over lock to fail")
+	}
+
+	before := taken.ExpiresAt
+	if err := taken.Refresh(executor, shop); err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := Read(executor, shop)
+	if err != nil || read == nil || read.ID != taken.ID || !read.ExpiresAt.Equal(taken.ExpiresAt) ||
+		read.ExpiresAt.Before(before) {
+		t.Errorf("the shop is locked by %+v, %v, want the refreshed lock %+v", read, err, taken)
+	}
+
+	if files, _ := ioutil.ReadDir(shop.ShopwareDir + "/custom/rackjobber"); len(files) != 1 {
+		t.Errorf("the refresh left %v files behind", len(files))
+	}
+}
+
+func TestUnlock(t *testing.T) {
+	shop, executor, cleanup := localShop(t)
+	defer cleanup()
+
+	tests := []struct {
+		name    string
+		ttl     time.Duration
+		force   bool
+		wantErr bool
+	}{
+		{name: "held lock", ttl: time.Hour, wantErr: true},
+		{name: "held lock forced", ttl: time.Hour, force: true},
+		{name: "expired lock", ttl: -time.Minute},
+	}
+
+	for _, test := range tests {
+		if _, err := Acquire(context.Background(), executor, shop, "up", 0, test.ttl); err != nil {
+			t.Fatal(err)
+		}
+
+		err := Unlock(executor, shop, test.force)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%v: got error %v, want an error %v", test.name, err, test.wantErr)
+		}
+
+		if read, _ := Read(executor, shop); (read != nil) != test.wantErr {
+			t.Errorf("%v: the shop is locked by %+v", test.name, read)
+		}
+
+		_ = Unlock(executor, shop, true)
+	}
+}
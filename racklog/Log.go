@@ -0,0 +1,290 @@
+// Package racklog includes the leveled logger of rackjobber, that writes to stderr and an optional log file,
+// redacts secrets from every line and adds the fields of a subsystem, like the shop, plugin or step.
+package racklog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is the verbosity of a log message
+type Level int
+
+// Levels of the log messages, from the most to the least important
+const (
+	LevelError Level = iota
+	LevelWarn
+	LevelInfo
+	LevelVerbose
+	LevelDebug
+)
+
+// String returns the name of a level, like it is written to the log
+func (l Level) String() string {
+	switch l {
+	case LevelError:
+		return "ERROR"
+	case LevelWarn:
+		return "WARN"
+	case LevelInfo:
+		return "INFO"
+	case LevelVerbose:
+		return "VERBOSE"
+	default:
+		return "DEBUG"
+	}
+}
+
+//timeFormat is the format of the time of the log lines, the same as the standard logger of go
+const timeFormat = "2006/01/02 15:04:05"
+
+//output holds the targets of the log and their levels
+type output struct {
+	mutex     sync.Mutex
+	level     Level
+	writer    io.Writer
+	file      *os.File
+	fileLevel Level
+}
+
+var std = &output{level: LevelInfo, writer: os.Stderr}
+
+// SetLevel sets the level up to which messages are written to stderr
+func SetLevel(level Level) {
+	std.mutex.Lock()
+	defer std.mutex.Unlock()
+
+	std.level = level
+}
+
+// GetLevel returns the level up to which messages are written to stderr
+func GetLevel() Level {
+	std.mutex.Lock()
+	defer std.mutex.Unlock()
+
+	return std.level
+}
+
+// OpenFile appends the log to the file at path in addition to stderr.
+// The file gets at least the info messages, even if stderr is quiet.
+func OpenFile(path string, level Level) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	if level < LevelInfo {
+		level = LevelInfo
+	}
+
+	std.mutex.Lock()
+	defer std.mutex.Unlock()
+
+	if std.file != nil {
+		_ = std.file.Close()
+	}
+
+	std.file = file
+	std.fileLevel = level
+
+	return nil
+}
+
+// Close closes the log file, if one is open
+func Close() error {
+	std.mutex.Lock()
+	defer std.mutex.Unlock()
+
+	if std.file == nil {
+		return nil
+	}
+
+	err := std.file.Close()
+	std.file = nil
+
+	return err
+}
+
+//enabled returns if a message of the level is written to any target
+func (o *output) enabled(level Level) bool {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	return level <= o.level || (o.file != nil && level <= o.fileLevel)
+}
+
+//write writes a line to the targets, which log the level
+func (o *output) write(level Level, line string) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if level <= o.level {
+		_, _ = io.WriteString(o.writer, line)
+	}
+
+	if o.file != nil && level <= o.fileLevel {
+		_, _ = io.WriteString(o.file, line)
+	}
+}
+
+// Logger writes log messages with the fields of a subsystem
+type Logger struct {
+	fields []field
+}
+
+//field is a key value pair added to the log messages
+type field struct {
+	key   string
+	value string
+}
+
+var root = &Logger{}
+
+// With returns a logger, that adds the field to every message
+func With(key string, value interface{}) *Logger {
+	return root.With(key, value)
+}
+
+// With returns a logger, that adds the field to every message in addition to the fields of l.
+// A field with the same key is replaced.
+func (l *Logger) With(key string, value interface{}) *Logger {
+	added := field{key: key, value: fmt.Sprint(value)}
+	fields := make([]field, len(l.fields), len(l.fields)+1)
+	copy(fields, l.fields)
+
+	for i := range fields {
+		if fields[i].key == key {
+			fields[i] = added
+			return &Logger{fields: fields}
+		}
+	}
+
+	return &Logger{fields: append(fields, added)}
+}
+
+// Errorf logs a message of an error, that does not abort the command
+func (l *Logger) Errorf(format string, v ...interface{}) {
+	l.log(LevelError, format, v...)
+}
+
+// Warnf logs a warning
+func (l *Logger) Warnf(format string, v ...interface{}) {
+	l.log(LevelWarn, format, v...)
+}
+
+// Infof logs the progress of a command
+func (l *Logger) Infof(format string, v ...interface{}) {
+	l.log(LevelInfo, format, v...)
+}
+
+// Verbosef logs details, that are only written with --verbose
+func (l *Logger) Verbosef(format string, v ...interface{}) {
+	l.log(LevelVerbose, format, v...)
+}
+
+// Debugf logs messages, that are only written with --debug, like the commands run on a shop
+func (l *Logger) Debugf(format string, v ...interface{}) {
+	l.log(LevelDebug, format, v...)
+}
+
+// Fatalf logs an error, runs the exit hook and exits with status 1
+func (l *Logger) Fatalf(format string, v ...interface{}) {
+	l.log(LevelError, format, v...)
+
+	exitHook.Lock()
+	hook := exitHook.hook
+	exitHook.hook = nil
+	exitHook.Unlock()
+
+	if hook != nil {
+		hook()
+	}
+
+	_ = Close()
+	os.Exit(1)
+}
+
+//exitHook runs before Fatalf exits. It is removed before it runs, so a Fatalf of the hook exits at once.
+var exitHook = struct {
+	sync.Mutex
+	hook func()
+}{}
+
+// SetExitHook sets the function, that Fatalf runs before it exits, like the cleanup of a running deployment
+func SetExitHook(hook func()) {
+	exitHook.Lock()
+	defer exitHook.Unlock()
+
+	exitHook.hook = hook
+}
+
+//log formats and redacts a message, if it is written to any target
+func (l *Logger) log(level Level, format string, v ...interface{}) {
+	if !std.enabled(level) {
+		return
+	}
+
+	std.write(level, Redact(l.format(level, fmt.Sprintf(format, v...))))
+}
+
+//format returns the line of a message with the time, the level and the fields
+func (l *Logger) format(level Level, message string) string {
+	var line strings.Builder
+
+	line.WriteString(time.Now().Format(timeFormat))
+	line.WriteString(" ")
+	line.WriteString(fmt.Sprintf("%-7v ", level))
+	line.WriteString(strings.TrimRight(message, "\n"))
+
+	for _, f := range l.fields {
+		line.WriteString(" " + f.key + "=" + quote(f.value))
+	}
+
+	line.WriteString("\n")
+
+	return line.String()
+}
+
+//quote quotes a value of a field, if it is empty or contains whitespace or quotes
+func quote(value string) string {
+	if value == "" || strings.ContainsAny(value, " \t\n\r\"=") {
+		return strconv.Quote(value)
+	}
+
+	return value
+}
+
+// Errorf logs a message of an error, that does not abort the command
+func Errorf(format string, v ...interface{}) {
+	root.log(LevelError, format, v...)
+}
+
+// Warnf logs a warning
+func Warnf(format string, v ...interface{}) {
+	root.log(LevelWarn, format, v...)
+}
+
+// Infof logs the progress of a command
+func Infof(format string, v ...interface{}) {
+	root.log(LevelInfo, format, v...)
+}
+
+// Verbosef logs details, that are only written with --verbose
+func Verbosef(format string, v ...interface{}) {
+	root.log(LevelVerbose, format, v...)
+}
+
+// Debugf logs messages, that are only written with --debug
+func Debugf(format string, v ...interface{}) {
+	root.log(LevelDebug, format, v...)
+}
+
+// Fatalf logs an error and exits with status 1
+func Fatalf(format string, v ...interface{}) {
+	root.Fatalf(format, v...)
+}
@@ -0,0 +1,44 @@
+package racklog
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"sync"
+)
+
+// Progress returns the writer for the progress of go-git, like the counted objects of a fetch.
+// The progress is only logged with --debug, otherwise it is discarded.
+func Progress() io.Writer {
+	if !std.enabled(LevelDebug) {
+		return ioutil.Discard
+	}
+
+	return &progressWriter{logger: With("subsystem", "git")}
+}
+
+//progressWriter logs every line of the progress, that is terminated by a newline or a carriage return
+type progressWriter struct {
+	mutex  sync.Mutex
+	logger *Logger
+	buffer bytes.Buffer
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	for _, b := range p {
+		if b != '\n' && b != '\r' {
+			w.buffer.WriteByte(b)
+			continue
+		}
+
+		if w.buffer.Len() > 0 {
+			w.logger.Debugf("%s", w.buffer.String())
+			w.buffer.Reset()
+		}
+	}
+
+	return len(p), nil
+}
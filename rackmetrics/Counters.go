@@ -0,0 +1,70 @@
+// Package rackmetrics includes the metrics of the runs of rackjobber, like the durations of their phases and plugins,
+// that are written for the textfile collector of the Prometheus node exporter or pushed to a Pushgateway.
+package rackmetrics
+
+import (
+	"sync"
+	"time"
+)
+
+// Counters are the totals of the commands, transfers and git operations of rackjobber since it started.
+// A Run records the difference of the counters between its start and its end.
+type Counters struct {
+	Commands      int64
+	BytesSent     int64
+	BytesReceived int64
+	GitOperations int64
+	GitDuration   time.Duration
+}
+
+var counters = struct {
+	sync.Mutex
+	Counters
+}{}
+
+// AddCommand counts a command executed on a shop with the bytes of the command and its output
+func AddCommand(sent int, received int) {
+	counters.Lock()
+	defer counters.Unlock()
+
+	counters.Commands++
+	counters.BytesSent += int64(sent)
+	counters.BytesReceived += int64(received)
+}
+
+// AddTransfer counts the bytes of a file written to or read from a shop
+func AddTransfer(sent int, received int) {
+	counters.Lock()
+	defer counters.Unlock()
+
+	counters.BytesSent += int64(sent)
+	counters.BytesReceived += int64(received)
+}
+
+// AddGit counts a git operation, like a fetch, and its duration
+func AddGit(duration time.Duration) {
+	counters.Lock()
+	defer counters.Unlock()
+
+	counters.GitOperations++
+	counters.GitDuration += duration
+}
+
+//snapshot returns the current counters
+func snapshot() Counters {
+	counters.Lock()
+	defer counters.Unlock()
+
+	return counters.Counters
+}
+
+//since returns the counters added since the earlier counters
+func (c Counters) since(earlier Counters) Counters {
+	return Counters{
+		Commands:      c.Commands - earlier.Commands,
+		BytesSent:     c.BytesSent - earlier.BytesSent,
+		BytesReceived: c.BytesReceived - earlier.BytesReceived,
+		GitOperations: c.GitOperations - earlier.GitOperations,
+		GitDuration:   c.GitDuration - earlier.GitDuration,
+	}
+}
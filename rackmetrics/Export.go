@@ -0,0 +1,338 @@
+package rackmetrics
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racklog"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackretry"
+)
+
+// DefaultTimeout is the time a push to the Pushgateway may take, if the output sets no timeout
+const DefaultTimeout = 10 * time.Second
+
+//job is the job of the metrics pushed to the Pushgateway
+const job = "rackjobber"
+
+//outcomeSucceeded is the outcome of a successful run in the history
+const outcomeSucceeded = "succeeded"
+
+// Output is where the metrics of the runs are written, no metrics are written if it is empty
+type Output struct {
+	// Textfile is the directory of the textfile collector of the node exporter
+	Textfile string `yaml:",omitempty"`
+	// Pushgateway is the URL of a Pushgateway, the metrics are pushed to the job rackjobber grouped by shop and command
+	Pushgateway string `yaml:",omitempty"`
+	// Timeout is the time a single push may take, like 10s
+	Timeout string `yaml:",omitempty"`
+	// Retry is the retry policy of failed pushes
+	Retry rackretry.Policy `yaml:",omitempty"`
+}
+
+// IsEmpty returns if the output writes no metrics
+func (o Output) IsEmpty() bool {
+	return len(o.Textfile) == 0 && len(o.Pushgateway) == 0
+}
+
+// Merge returns the output with the values set in the other output replacing its own
+func (o Output) Merge(other Output) Output {
+	if len(other.Textfile) > 0 {
+		o.Textfile = other.Textfile
+	}
+
+	if len(other.Pushgateway) > 0 {
+		o.Pushgateway = other.Pushgateway
+	}
+
+	if len(other.Timeout) > 0 {
+		o.Timeout = other.Timeout
+	}
+
+	o.Retry = o.Retry.Merge(other.Retry)
+
+	return o
+}
+
+// Validate checks the URL of the Pushgateway, the timeout and the retry policy
+func (o Output) Validate() error {
+	if len(o.Pushgateway) > 0 {
+		parsed, err := url.Parse(o.Pushgateway)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || len(parsed.Host) == 0 {
+			return errors.New("the pushgateway needs an http or https URL")
+		}
+	}
+
+	if err := rackretry.ValidateDurations(o.Timeout); err != nil {
+		return err
+	}
+
+	return o.Retry.Validate()
+}
+
+//getTimeout returns the time a single push may take
+func (o Output) getTimeout() time.Duration {
+	timeout, err := time.ParseDuration(o.Timeout)
+	if err != nil || timeout <= 0 {
+		return DefaultTimeout
+	}
+
+	return timeout
+}
+
+// Export writes the metrics of the run to the textfile directory and pushes them to the Pushgateway of the output.
+// A failed export is logged, it does not fail the run.
+func Export(run *Run, output Output) {
+	if run == nil || output.IsEmpty() {
+		return
+	}
+
+	logger := racklog.With("shop", run.Shop)
+
+	if err := output.Validate(); err != nil {
+		logger.Warnf("Skipping the metrics: %v", err)
+		return
+	}
+
+	var metrics bytes.Buffer
+	if err := Write(&metrics, run); err != nil {
+		logger.Warnf("Failed to format the metrics: %v", err)
+		return
+	}
+
+	if len(output.Textfile) > 0 {
+		path, err := WriteTextfile(output.Textfile, run, metrics.Bytes())
+		if err != nil {
+			logger.Warnf("Failed to write the metrics: %v", err)
+		} else {
+			logger.Verbosef("Wrote the metrics to %v", path)
+		}
+	}
+
+	if len(output.Pushgateway) > 0 {
+		if err := Push(context.Background(), output, run, metrics.Bytes()); err != nil {
+			logger.Warnf("Failed to push the metrics: %v", err)
+		} else {
+			logger.Verbosef("Pushed the metrics to the pushgateway")
+		}
+	}
+}
+
+// WriteTextfile writes the metrics to the file of the shop and the command in the directory.
+// The file is replaced at once, so the node exporter never reads a partial file.
+func WriteTextfile(dir string, run *Run, metrics []byte) (string, error) {
+	path := filepath.Join(dir, "rackjobber_"+fileName(run.Shop)+"_"+fileName(run.Command)+".prom")
+
+	temp, err := ioutil.TempFile(dir, ".rackjobber-")
+	if err != nil {
+		return "", err
+	}
+
+	_, err = temp.Write(metrics)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Chmod(temp.Name(), 0644)
+	}
+
+	if err == nil {
+		err = os.Rename(temp.Name(), path)
+	}
+
+	if err != nil {
+		_ = os.Remove(temp.Name())
+		return "", err
+	}
+
+	return path, nil
+}
+
+// Push replaces the metrics of the shop and the command on the Pushgateway of the output
+func Push(ctx context.Context, output Output, run *Run, metrics []byte) error {
+	target := strings.TrimRight(output.Pushgateway, "/") + "/metrics/job/" + job +
+		"/shop/" + url.PathEscape(run.Shop) + "/command/" + url.PathEscape(run.Command)
+
+	return rackretry.Do(ctx, output.Retry, "Pushing the metrics", func() error {
+		return rackretry.WithTimeout(ctx, output.getTimeout(), "the push of the metrics",
+			func(ctx context.Context) error {
+				return put(ctx, target, metrics)
+			})
+	})
+}
+
+//put sends the metrics to the Pushgateway, client errors are not retried
+func put(ctx context.Context, target string, metrics []byte) error {
+	request, err := http.NewRequest(http.MethodPut, target, bytes.NewReader(metrics))
+	if err != nil {
+		return rackretry.Stop(err)
+	}
+
+	request = request.WithContext(ctx)
+	request.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		_, _ = io.Copy(ioutil.Discard, response.Body)
+		return nil
+	}
+
+	text, _ := ioutil.ReadAll(io.LimitReader(response.Body, 512))
+	err = fmt.Errorf("the pushgateway answered %v: %v", response.Status, strings.TrimSpace(string(text)))
+
+	if response.StatusCode >= 400 && response.StatusCode < 500 {
+		return rackretry.Stop(err)
+	}
+
+	return err
+}
+
+// Write writes the metrics of the run in the text format of Prometheus
+func Write(w io.Writer, run *Run) error {
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	labels := []string{"shop", run.Shop, "command", run.Command}
+	success := 0.0
+
+	if run.Outcome == outcomeSucceeded {
+		success = 1
+	}
+
+	families := []family{
+		{"rackjobber_run_duration_seconds", "Duration of the last run on the shop",
+			[]sample{{withLabel(labels, "outcome", run.Outcome), run.Duration.Seconds()}}},
+		{"rackjobber_run_success", "1 if the last run on the shop succeeded, 0 otherwise",
+			[]sample{{labels, success}}},
+		{"rackjobber_run_timestamp_seconds", "Time the last run on the shop finished, in seconds since the epoch",
+			[]sample{{labels, float64(run.Started.Add(run.Duration).UnixNano()) / 1e9}}},
+		{"rackjobber_phase_duration_seconds", "Duration of the phases of the last run",
+			durationSamples(labels, "phase", run.Phases)},
+		{"rackjobber_action_duration_seconds", "Duration of the steps of the last run by their action",
+			durationSamples(labels, "action", run.Actions)},
+		{"rackjobber_plugin_duration_seconds", "Duration of the steps of the last run by their plugin",
+			durationSamples(labels, "plugin", run.Plugins)},
+		{"rackjobber_plugins", "Number of plugins of the last run by their result",
+			resultSamples(labels, run.Results)},
+		{"rackjobber_commands", "Number of commands the last run executed on the shop",
+			[]sample{{labels, float64(run.Counters.Commands)}}},
+		{"rackjobber_transferred_bytes", "Bytes the last run sent to and received from the shop",
+			[]sample{{withLabel(labels, "direction", "sent"), float64(run.Counters.BytesSent)},
+				{withLabel(labels, "direction", "received"), float64(run.Counters.BytesReceived)}}},
+		{"rackjobber_git_operations", "Number of git operations, like fetches, clones and pulls, of the last run",
+			[]sample{{labels, float64(run.Counters.GitOperations)}}},
+		{"rackjobber_git_duration_seconds", "Duration of the git operations of the last run",
+			[]sample{{labels, run.Counters.GitDuration.Seconds()}}},
+	}
+
+	for _, f := range families {
+		if err := f.write(w); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//family is a metric with its samples
+type family struct {
+	name    string
+	help    string
+	samples []sample
+}
+
+//sample is a value of a metric with its labels, given as pairs of name and value
+type sample struct {
+	labels []string
+	value  float64
+}
+
+func (f family) write(w io.Writer) error {
+	if len(f.samples) == 0 {
+		return nil
+	}
+
+	if _, err := fmt.Fprintf(w, "# HELP %v %v\n# TYPE %v gauge\n", f.name, f.help, f.name); err != nil {
+		return err
+	}
+
+	for _, s := range f.samples {
+		pairs := []string{}
+		for i := 0; i+1 < len(s.labels); i += 2 {
+			pairs = append(pairs, s.labels[i]+"=\""+escapeLabel(s.labels[i+1])+"\"")
+		}
+
+		value := strconv.FormatFloat(s.value, 'g', -1, 64)
+		if _, err := fmt.Fprintf(w, "%v{%v} %v\n", f.name, strings.Join(pairs, ","), value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//durationSamples returns a sample for every duration, labeled with its key and sorted by it
+func durationSamples(labels []string, label string, durations map[string]time.Duration) []sample {
+	keys := []string{}
+	for key := range durations {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	samples := []sample{}
+	for _, key := range keys {
+		samples = append(samples, sample{withLabel(labels, label, key), durations[key].Seconds()})
+	}
+
+	return samples
+}
+
+//resultSamples returns a sample for every result of the plugins, also for those no plugin has
+func resultSamples(labels []string, results map[string]int) []sample {
+	samples := []sample{}
+	for _, result := range Results {
+		samples = append(samples, sample{withLabel(labels, "result", result), float64(results[result])})
+	}
+
+	return samples
+}
+
+//withLabel returns a copy of the labels with the additional label
+func withLabel(labels []string, name string, value string) []string {
+	return append(append([]string{}, labels...), name, value)
+}
+
+//escapeLabel escapes the backslashes, quotes and newlines of a label value
+func escapeLabel(value string) string {
+	value = strings.Replace(value, `\`, `\\`, -1)
+	value = strings.Replace(value, `"`, `\"`, -1)
+
+	return strings.Replace(value, "\n", `\n`, -1)
+}
+
+var unsafeFileName = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+//fileName replaces the characters of a name, that are not safe in a file name
+func fileName(name string) string {
+	return unsafeFileName.ReplaceAllString(name, "_")
+}
@@ -0,0 +1,83 @@
+package rackmetrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWrite(t *testing.T) {
+	started := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name    string
+		run     *Run
+		want    []string
+		missing []string
+	}{
+		{
+			name: "succeeded run",
+			run: &Run{Shop: "my-shop", Command: "up", Outcome: outcomeSucceeded, Started: started,
+				Duration: 90 * time.Second,
+				Phases:   map[string]time.Duration{PhasePrepare: 2 * time.Second, PhaseExecute: 88 * time.Second},
+				Actions:  map[string]time.Duration{"deploy": 80 * time.Second},
+				Plugins:  map[string]time.Duration{"SwagPlugin": 80 * time.Second},
+				Results:  map[string]int{ResultUpdated: 1, ResultSkipped: 3},
+				Counters: Counters{Commands: 12, BytesSent: 2048, BytesReceived: 512, GitOperations: 2,
+					GitDuration: 1500 * time.Millisecond}},
+			want: []string{
+				"# HELP rackjobber_run_duration_seconds Duration of the last run on the shop\n" +
+					"# TYPE rackjobber_run_duration_seconds gauge\n" +
+					`rackjobber_run_duration_seconds{shop="my-shop",command="up",outcome="succeeded"} 90` + "\n",
+				`rackjobber_run_success{shop="my-shop",command="up"} 1` + "\n",
+				`rackjobber_run_timestamp_seconds{shop="my-shop",command="up"} 1.70000009e+09` + "\n",
+				`rackjobber_phase_duration_seconds{shop="my-shop",command="up",phase="execute"} 88` + "\n" +
+					`rackjobber_phase_duration_seconds{shop="my-shop",command="up",phase="prepare"} 2` + "\n",
+				`rackjobber_plugin_duration_seconds{shop="my-shop",command="up",plugin="SwagPlugin"} 80` + "\n",
+				`rackjobber_plugins{shop="my-shop",command="up",result="updated"} 1` + "\n",
+				`rackjobber_plugins{shop="my-shop",command="up",result="deleted"} 0` + "\n",
+				`rackjobber_transferred_bytes{shop="my-shop",command="up",direction="sent"} 2048` + "\n",
+				`rackjobber_git_duration_seconds{shop="my-shop",command="up"} 1.5` + "\n",
+			},
+		},
+		{
+			name: "failed run without steps",
+			run: &Run{Shop: "my-shop", Command: "rollback", Outcome: "failed", Started: started,
+				Phases: map[string]time.Duration{}, Actions: map[string]time.Duration{},
+				Plugins: map[string]time.Duration{}, Results: map[string]int{}},
+			want: []string{
+				`rackjobber_run_success{shop="my-shop",command="rollback"} 0` + "\n",
+				`rackjobber_plugins{shop="my-shop",command="rollback",result="failed"} 0` + "\n",
+			},
+			missing: []string{"rackjobber_phase_duration_seconds", "rackjobber_plugin_duration_seconds"},
+		},
+		{
+			name: "escaped labels",
+			run: &Run{Shop: `my "shop"` + "\n" + `\eu`, Command: "up", Started: started,
+				Results: map[string]int{}},
+			want: []string{`rackjobber_run_success{shop="my \"shop\"\n\\eu",command="up"} 0` + "\n"},
+		},
+	}
+
+	for _, test := range tests {
+		var buffer bytes.Buffer
+
+		if err := Write(&buffer, test.run); err != nil {
+			t.Errorf("%v: unexpected error: %v", test.name, err)
+			continue
+		}
+
+		for _, want := range test.want {
+			if !strings.Contains(buffer.String(), want) {
+				t.Errorf("%v: the metrics do not contain %q:\n%v", test.name, want, buffer.String())
+			}
+		}
+
+		for _, missing := range test.missing {
+			if strings.Contains(buffer.String(), missing) {
+				t.Errorf("%v: the metrics contain %q:\n%v", test.name, missing, buffer.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package rackmetrics
+
+import (
+	"sync"
+	"time"
+)
+
+// Results of the plugins, that are counted by a run
+const (
+	ResultUpdated = "updated"
+	ResultSkipped = "skipped"
+	ResultDeleted = "deleted"
+	ResultFailed  = "failed"
+)
+
+// Phases of a run, that are measured
+const (
+	PhasePrepare = "prepare"
+	PhaseBackup  = "backup"
+	PhaseExecute = "execute"
+	PhaseFinish  = "finish"
+)
+
+// Results lists the results of the plugins, every result is written, even if no plugin has it
+var Results = []string{ResultUpdated, ResultSkipped, ResultDeleted, ResultFailed}
+
+// Run collects the metrics of a single run on a shop. A nil Run records nothing.
+type Run struct {
+	mutex   sync.Mutex
+	Shop    string
+	Command string
+	Outcome string
+	Started time.Time
+	// Duration is the time from the start to the finish of the run
+	Duration time.Duration
+	// Phases are the durations of the phases of the run, like prepare or execute
+	Phases map[string]time.Duration
+	// Actions are the durations of the steps of the plan by their action
+	Actions map[string]time.Duration
+	// Plugins are the durations of the steps of the plan by their plugin
+	Plugins map[string]time.Duration
+	// Results count the plugins by their result, like updated or skipped
+	Results map[string]int
+	// Counters are the commands, transfers and git operations of the run
+	Counters Counters
+	start    Counters
+}
+
+// Start returns the metrics of a run of the command on the shop, that starts now
+func Start(shop string, command string) *Run {
+	return &Run{
+		Shop:    shop,
+		Command: command,
+		Started: time.Now(),
+		Phases:  map[string]time.Duration{},
+		Actions: map[string]time.Duration{},
+		Plugins: map[string]time.Duration{},
+		Results: map[string]int{},
+		start:   snapshot(),
+	}
+}
+
+// Phase adds the time since start to the duration of the phase
+func (r *Run) Phase(phase string, start time.Time) {
+	if r == nil {
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.Phases[phase] += time.Since(start)
+}
+
+// Step adds the duration of a step to its action and its plugin, and counts the result of the plugin, if it has one
+func (r *Run) Step(action string, plugin string, result string, duration time.Duration) {
+	if r == nil {
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.Actions[action] += duration
+
+	if len(plugin) > 0 {
+		r.Plugins[plugin] += duration
+	}
+
+	if len(result) > 0 {
+		r.Results[result]++
+	}
+}
+
+// Finish records the outcome and the duration of the run and the counters since its start
+func (r *Run) Finish(outcome string) {
+	if r == nil {
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.Outcome = outcome
+	r.Duration = time.Since(r.Started)
+	r.Counters = snapshot().since(r.start)
+}
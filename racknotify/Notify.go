@@ -0,0 +1,200 @@
+package racknotify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racklog"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackretry"
+)
+
+// Events, that are sent to the targets
+const (
+	EventStarted   = "started"
+	EventSucceeded = "succeeded"
+	EventFailed    = "failed"
+	EventCancelled = "cancelled"
+	// EventTest is sent by notify test to every target, regardless of its events
+	EventTest = "test"
+)
+
+// Payload is the JSON sent to webhooks, the messages of the chats are formatted from it
+type Payload struct {
+	Event      string     `json:"event"`
+	Shop       string     `json:"shop"`
+	RunID      string     `json:"runId,omitempty"`
+	Command    string     `json:"command,omitempty"`
+	User       string     `json:"user,omitempty"`
+	Host       string     `json:"host,omitempty"`
+	StartedAt  time.Time  `json:"startedAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	// DurationMillis and Outcome are only set, when the run finished
+	DurationMillis int64  `json:"durationMs,omitempty"`
+	Outcome        string `json:"outcome,omitempty"`
+	// Plugins are the plugins deployed or deleted by the run
+	Plugins []PluginChange `json:"plugins"`
+	Errors  []string       `json:"errors,omitempty"`
+}
+
+// PluginChange is a plugin deployed or deleted by a run, with its version before and after the run
+type PluginChange struct {
+	Plugin     string `json:"plugin"`
+	Action     string `json:"action"`
+	OldVersion string `json:"oldVersion,omitempty"`
+	NewVersion string `json:"newVersion,omitempty"`
+}
+
+//chatMessage is the body of a message to an incoming webhook of Slack or Mattermost
+type chatMessage struct {
+	Text     string `json:"text"`
+	Channel  string `json:"channel,omitempty"`
+	Username string `json:"username,omitempty"`
+}
+
+// Send sends the payload to every target, that is notified of its event.
+// A failed notification is logged, it does not fail the run.
+func Send(ctx context.Context, targets []Target, payload Payload) {
+	for _, target := range targets {
+		if !target.Notifies(payload.Event) {
+			continue
+		}
+
+		if err := target.Send(ctx, payload); err != nil {
+			racklog.With("shop", payload.Shop).Warnf("Failed to notify %v: %v", target, err)
+		}
+	}
+}
+
+// Send posts the payload to the target in its format, with its timeout and retry policy.
+// The URL is redacted from the log like the headers, because URLs of incoming webhooks contain their token.
+func (t Target) Send(ctx context.Context, payload Payload) error {
+	racklog.AddSecret(t.URL)
+
+	for _, value := range t.Headers {
+		racklog.AddSecret(value)
+	}
+
+	body, err := t.body(payload)
+	if err != nil {
+		return err
+	}
+
+	racklog.With("shop", payload.Shop).Verbosef("Notifying %v of the event %v", t, payload.Event)
+
+	return rackretry.Do(ctx, t.Retry, "Notifying "+t.String(), func() error {
+		return rackretry.WithTimeout(ctx, t.GetTimeout(), "the notification of "+t.String(),
+			func(ctx context.Context) error {
+				return t.post(ctx, body)
+			})
+	})
+}
+
+//body returns the body of the request in the format of the target
+func (t Target) body(payload Payload) ([]byte, error) {
+	if t.GetFormat() == FormatWebhook {
+		return json.Marshal(payload)
+	}
+
+	return json.Marshal(chatMessage{Text: Message(payload), Channel: t.Channel, Username: t.Username})
+}
+
+//post posts the body to the target, client errors except timeouts and rate limits are not retried
+func (t Target) post(ctx context.Context, body []byte) error {
+	request, err := http.NewRequest(http.MethodPost, t.URL, bytes.NewReader(body))
+	if err != nil {
+		return rackretry.Stop(err)
+	}
+
+	request = request.WithContext(ctx)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "rackjobber")
+
+	for key, value := range t.Headers {
+		request.Header.Set(key, value)
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		_, _ = io.Copy(ioutil.Discard, response.Body)
+		return nil
+	}
+
+	text, _ := ioutil.ReadAll(io.LimitReader(response.Body, 512))
+	err = fmt.Errorf("%v answered %v: %v", t, response.Status, strings.TrimSpace(string(text)))
+
+	if response.StatusCode >= 400 && response.StatusCode < 500 &&
+		response.StatusCode != http.StatusRequestTimeout && response.StatusCode != http.StatusTooManyRequests {
+		return rackretry.Stop(err)
+	}
+
+	return err
+}
+
+// Message returns the payload as a message for a chat, formatted with the markdown of Slack and Mattermost
+func Message(payload Payload) string {
+	var message strings.Builder
+
+	switch payload.Event {
+	case EventStarted:
+		message.WriteString(":rocket: Rackjobber started " + payload.Command + " on *" + payload.Shop + "*")
+	case EventSucceeded:
+		message.WriteString(":white_check_mark: Rackjobber finished " + payload.Command + " on *" + payload.Shop + "*")
+	case EventFailed:
+		message.WriteString(":x: Rackjobber failed " + payload.Command + " on *" + payload.Shop + "*")
+	case EventCancelled:
+		message.WriteString(":warning: Rackjobber cancelled " + payload.Command + " on *" + payload.Shop + "*")
+	default:
+		message.WriteString(":bell: Test notification of Rackjobber for *" + payload.Shop + "*")
+	}
+
+	if payload.FinishedAt != nil {
+		message.WriteString(fmt.Sprintf(" in %v", time.Duration(payload.DurationMillis)*time.Millisecond))
+	}
+
+	if len(payload.RunID) > 0 {
+		message.WriteString(" (run `" + payload.RunID + "`")
+
+		if len(payload.User) > 0 {
+			message.WriteString(" by " + payload.User)
+		}
+
+		message.WriteString(")")
+	}
+
+	for _, change := range payload.Plugins {
+		message.WriteString("\n• " + change.String())
+	}
+
+	for _, err := range payload.Errors {
+		message.WriteString("\n> " + err)
+	}
+
+	return message.String()
+}
+
+// String returns the change like PluginA 1.0.0 → 1.1.0
+func (c PluginChange) String() string {
+	switch {
+	case len(c.NewVersion) == 0 && len(c.OldVersion) == 0:
+		return c.Plugin + " deleted"
+	case len(c.NewVersion) == 0:
+		return c.Plugin + " " + c.OldVersion + " → deleted"
+	case len(c.OldVersion) == 0:
+		return c.Plugin + " " + c.NewVersion + " (new)"
+	default:
+		return c.Plugin + " " + c.OldVersion + " → " + c.NewVersion
+	}
+}
@@ -0,0 +1,158 @@
+package racknotify
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racklog"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackretry"
+)
+
+//receiver records the requests of the notifications and answers them with its statuses in turn
+type receiver struct {
+	sync.Mutex
+	statuses []int
+	bodies   [][]byte
+	headers  []http.Header
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, request *http.Request) {
+	body, _ := ioutil.ReadAll(request.Body)
+
+	r.Lock()
+	defer r.Unlock()
+
+	r.bodies = append(r.bodies, body)
+	r.headers = append(r.headers, request.Header)
+
+	if len(r.bodies) <= len(r.statuses) {
+		w.WriteHeader(r.statuses[len(r.bodies)-1])
+	}
+}
+
+func TestSend(t *testing.T) {
+	finished := time.Date(2026, 5, 4, 12, 0, 30, 0, time.UTC)
+	payload := Payload{
+		Event:          EventSucceeded,
+		Shop:           "my-shop",
+		RunID:          "run-1",
+		Command:        "up",
+		User:           "deploy",
+		StartedAt:      finished.Add(-30 * time.Second),
+		FinishedAt:     &finished,
+		DurationMillis: 30000,
+		Outcome:        "succeeded",
+		Plugins:        []PluginChange{{Plugin: "SwagPlugin", Action: "deploy", OldVersion: "1.0.0", NewVersion: "1.1.0"}},
+	}
+
+	tests := []struct {
+		name     string
+		format   string
+		statuses []int
+		requests int
+		wantErr  bool
+	}{
+		{name: "webhook", format: FormatWebhook, requests: 1},
+		{name: "slack", format: FormatSlack, requests: 1},
+		{name: "mattermost", format: FormatMattermost, requests: 1},
+		{name: "server error retried", statuses: []int{500, 502}, requests: 3},
+		{name: "rate limit retried", statuses: []int{429}, requests: 2},
+		{name: "client error not retried", statuses: []int{404}, requests: 1, wantErr: true},
+		{name: "retries used up", statuses: []int{500, 500, 500}, requests: 3, wantErr: true},
+	}
+
+	for _, test := range tests {
+		receiver := &receiver{statuses: test.statuses}
+		server := httptest.NewServer(receiver)
+
+		target := Target{
+			URL:     server.URL + "/hooks/token",
+			Format:  test.format,
+			Headers: map[string]string{"Authorization": "Bearer header-token"},
+			Channel: "deployments",
+			Retry:   rackretry.Policy{Attempts: 3, Delay: "1ms"},
+		}
+
+		err := target.Send(context.Background(), payload)
+		server.Close()
+
+		if (err != nil) != test.wantErr {
+			t.Errorf("%v: got error %v, want an error %v", test.name, err, test.wantErr)
+		}
+
+		if len(receiver.bodies) != test.requests {
+			t.Errorf("%v: got %v requests, want %v", test.name, len(receiver.bodies), test.requests)
+			continue
+		}
+
+		if receiver.headers[0].Get("Authorization") != "Bearer header-token" ||
+			receiver.headers[0].Get("Content-Type") != "application/json" {
+			t.Errorf("%v: got headers %v", test.name, receiver.headers[0])
+		}
+
+		if target.GetFormat() == FormatWebhook {
+			var received Payload
+			if err := json.Unmarshal(receiver.bodies[0], &received); err != nil ||
+				received.Shop != payload.Shop || received.Event != payload.Event ||
+				len(received.Plugins) != 1 || received.Plugins[0] != payload.Plugins[0] {
+				t.Errorf("%v: got payload %s", test.name, receiver.bodies[0])
+			}
+		} else {
+			var received chatMessage
+			if err := json.Unmarshal(receiver.bodies[0], &received); err != nil ||
+				received.Text != Message(payload) || received.Channel != "deployments" {
+				t.Errorf("%v: got message %s", test.name, receiver.bodies[0])
+			}
+		}
+
+		if racklog.Redact(target.URL) != "***" {
+			t.Errorf("%v: the URL of the target is not redacted from the log", test.name)
+		}
+	}
+}
+
+func TestMessage(t *testing.T) {
+	finished := time.Now()
+
+	tests := []struct {
+		name    string
+		payload Payload
+		want    []string
+	}{
+		{
+			name:    "started",
+			payload: Payload{Event: EventStarted, Shop: "my-shop", Command: "up", RunID: "run-1", User: "deploy"},
+			want:    []string{":rocket: Rackjobber started up on *my-shop* (run `run-1` by deploy)"},
+		},
+		{
+			name: "failed",
+			payload: Payload{Event: EventFailed, Shop: "my-shop", Command: "up", FinishedAt: &finished,
+				DurationMillis: 1500, Errors: []string{"deploy of SwagPlugin failed"},
+				Plugins: []PluginChange{{Plugin: "SwagPlugin", NewVersion: "1.0.0"}}},
+			want: []string{":x: Rackjobber failed up on *my-shop* in 1.5s", "\n• SwagPlugin 1.0.0 (new)",
+				"\n> deploy of SwagPlugin failed"},
+		},
+		{
+			name:    "test",
+			payload: Payload{Event: EventTest, Shop: "my-shop"},
+			want:    []string{":bell: Test notification of Rackjobber for *my-shop*"},
+		},
+	}
+
+	for _, test := range tests {
+		message := Message(test.payload)
+
+		for _, want := range test.want {
+			if !strings.Contains(message, want) {
+				t.Errorf("%v: message %q does not contain %q", test.name, message, want)
+			}
+		}
+	}
+}
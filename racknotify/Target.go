@@ -0,0 +1,131 @@
+// Package racknotify includes the notifications of external systems, like webhooks and chats,
+// about the runs of rackjobber on a shop.
+package racknotify
+
+import (
+	"errors"
+	"net/url"
+	"time"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackretry"
+)
+
+// Formats of the requests sent to a target
+const (
+	// FormatWebhook posts the Payload as JSON
+	FormatWebhook = "webhook"
+	// FormatSlack posts a message to an incoming webhook of Slack
+	FormatSlack = "slack"
+	// FormatMattermost posts a message to an incoming webhook of Mattermost
+	FormatMattermost = "mattermost"
+)
+
+// DefaultTimeout is the time a notification may take, if the target sets no timeout
+const DefaultTimeout = 10 * time.Second
+
+// Target is an URL, that is notified of the runs of rackjobber
+type Target struct {
+	// Name is shown in the log instead of the URL, which may contain a token
+	Name string `yaml:",omitempty"`
+	URL  string
+	// Format is webhook, slack or mattermost, webhook is the default
+	Format string `yaml:",omitempty"`
+	// Events are the events, that are sent to the target, all events are sent if it is empty
+	Events []string `yaml:",omitempty"`
+	// Headers are added to every request, like an authorization header
+	Headers map[string]string `yaml:",omitempty"`
+	// Channel and Username replace the defaults of the incoming webhook of Slack or Mattermost
+	Channel  string `yaml:",omitempty"`
+	Username string `yaml:",omitempty"`
+	// Timeout is the time a single request may take, like 10s
+	Timeout string `yaml:",omitempty"`
+	// Retry is the retry policy of failed requests
+	Retry rackretry.Policy `yaml:",omitempty"`
+}
+
+// GetFormat returns the format of the target, webhook if none is set
+func (t Target) GetFormat() string {
+	if len(t.Format) == 0 {
+		return FormatWebhook
+	}
+
+	return t.Format
+}
+
+// GetTimeout returns the time a single request may take
+func (t Target) GetTimeout() time.Duration {
+	timeout, err := time.ParseDuration(t.Timeout)
+	if err != nil || timeout <= 0 {
+		return DefaultTimeout
+	}
+
+	return timeout
+}
+
+// Notifies returns if the event is sent to the target
+func (t Target) Notifies(event string) bool {
+	if len(t.Events) == 0 || event == EventTest {
+		return true
+	}
+
+	for _, e := range t.Events {
+		if e == event {
+			return true
+		}
+	}
+
+	return false
+}
+
+// String returns the name of the target, or the host of its URL
+func (t Target) String() string {
+	if len(t.Name) > 0 {
+		return t.Name
+	}
+
+	if parsed, err := url.Parse(t.URL); err == nil && len(parsed.Host) > 0 {
+		return parsed.Host
+	}
+
+	return "notification target"
+}
+
+// Validate checks the URL, the format, the events, the timeout and the retry policy of the target
+func (t Target) Validate() error {
+	parsed, err := url.Parse(t.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || len(parsed.Host) == 0 {
+		return errors.New("the notification target " + t.String() + " needs an http or https URL")
+	}
+
+	switch t.GetFormat() {
+	case FormatWebhook, FormatSlack, FormatMattermost:
+	default:
+		return errors.New("unknown notification format " + t.Format + ", must be webhook, slack or mattermost")
+	}
+
+	for _, event := range t.Events {
+		switch event {
+		case EventStarted, EventSucceeded, EventFailed, EventCancelled:
+		default:
+			return errors.New("unknown notification event " + event +
+				", must be started, succeeded, failed or cancelled")
+		}
+	}
+
+	if err := rackretry.ValidateDurations(t.Timeout); err != nil {
+		return err
+	}
+
+	return t.Retry.Validate()
+}
+
+// ValidateTargets checks all targets
+func ValidateTargets(targets []Target) error {
+	for _, target := range targets {
+		if err := target.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+// Package rackplan includes the structs of a deployment plan, that describes the changes rackjobber makes to a shop
+package rackplan
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackhook"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshop"
+)
+
+// Actions of a step in a plan
+const (
+	ActionDelete          = "delete"
+	ActionDeploy          = "deploy"
+	ActionSkip            = "skip"
+	ActionInitializeTheme = "initializeTheme"
+	ActionSetTheme        = "setTheme"
+	ActionAssignTheme     = "assignTheme"
+	ActionConfigure       = "configure"
+	ActionHook            = "hook"
+	ActionClearCache      = "clearCache"
+	ActionHealthCheck     = "healthCheck"
+	// ActionEnableMaintenance and ActionDisableMaintenance switch the maintenance mode of the shop on and off
+	ActionEnableMaintenance  = "enableMaintenance"
+	ActionDisableMaintenance = "disableMaintenance"
+)
+
+// Plan is the list of steps, that are executed by a single run of rackjobber
+type Plan struct {
+	Shop  string `json:"shop"`
+	RunID string `json:"runId"`
+	Steps []Step `json:"steps"`
+}
+
+// Step is a single action of a plan
+type Step struct {
+	Action  string `json:"action"`
+	Plugin  string `json:"plugin,omitempty"`
+	Version string `json:"version,omitempty"`
+	Commit  string `json:"commit,omitempty"`
+	Flag    string `json:"flag,omitempty"`
+	Source  string `json:"source,omitempty"`
+	Repo    string `json:"repo,omitempty"`
+	Theme   string `json:"theme,omitempty"`
+	// SubShop is the ID of the shopware subshop, a theme is assigned to or a configuration value is set for
+	SubShop string `json:"subShop,omitempty"`
+	// Key and Value are the configuration value set by the step, secrets are kept as their reference
+	Key   string `json:"key,omitempty"`
+	Value string `json:"value,omitempty"`
+	// Hook is the hook run by the step
+	Hook *rackhook.Hook `json:"hook,omitempty"`
+	// HealthCheck is the health check run by the step
+	HealthCheck *rackshop.HealthCheck `json:"healthCheck,omitempty"`
+	// Clone is set, if the plugin is not checked out on the shop yet
+	Clone  bool   `json:"clone,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// HasChanges returns if the plan contains steps, that change plugins of the shop
+func (p Plan) HasChanges() bool {
+	for _, step := range p.Steps {
+		if step.Action == ActionDelete || step.Action == ActionDeploy {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Print writes the steps of the plan to w
+func (p Plan) Print(w io.Writer) {
+	fmt.Fprintf(w, "Plan for %v (run %v):\n", p.Shop, p.RunID)
+
+	for _, step := range p.Steps {
+		fmt.Fprintln(w, " - "+step.String())
+	}
+}
+
+// String returns a human readable description of the step
+func (s Step) String() string {
+	parts := []string{s.Action}
+
+	if len(s.Plugin) > 0 {
+		parts = append(parts, s.Plugin)
+	}
+
+	if len(s.Version) > 0 {
+		parts = append(parts, s.Version)
+	}
+
+	if len(s.Commit) > 0 {
+		parts = append(parts, "("+shortCommit(s.Commit)+")")
+	}
+
+	if len(s.Flag) > 0 {
+		parts = append(parts, "["+s.Flag+"]")
+	}
+
+	if s.Hook != nil {
+		parts = append(parts, s.Hook.String())
+	}
+
+	if s.HealthCheck != nil {
+		parts = append(parts, s.HealthCheck.URL)
+	}
+
+	if len(s.Key) > 0 {
+		parts = append(parts, s.Key+"="+s.Value)
+	}
+
+	if len(s.Theme) > 0 {
+		parts = append(parts, "theme "+s.Theme)
+	}
+
+	if len(s.SubShop) > 0 {
+		parts = append(parts, "to shop "+s.SubShop)
+	}
+
+	if len(s.Reason) > 0 {
+		parts = append(parts, "- "+s.Reason)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func shortCommit(commit string) string {
+	const shortLength = 8
+
+	if len(commit) > shortLength {
+		return commit[:shortLength]
+	}
+
+	return commit
+}
@@ -7,13 +7,13 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/fileutil"
 	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/gitutil"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racklog"
 	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackspec"
 
 	"gopkg.in/yaml.v2"
@@ -33,13 +33,13 @@ type Plugin struct {
 func Integrate() error {
 	currentDir, err := os.Getwd()
 	if err != nil {
-		log.Fatalf("Getwd - error: %v\n", err)
+		racklog.Fatalf("Getwd - error: %v\n", err)
 		return err
 	}
 
 	files, err := ioutil.ReadDir(currentDir)
 	if err != nil {
-		log.Fatalf("ReadDir - error: %v\n", err)
+		racklog.Fatalf("ReadDir - error: %v\n", err)
 		return err
 	}
 
@@ -47,24 +47,24 @@ func Integrate() error {
 		if strings.Contains(file.Name(), "plugin.xml") {
 			repo, err := gitutil.GetRepoFromLocalDir(currentDir)
 			if err != nil {
-				log.Fatalf("OpenCurrentDirGit - error: %v\n", err)
+				racklog.Fatalf("OpenCurrentDirGit - error: %v\n", err)
 				return err
 			}
 
 			if repo == nil {
-				log.Fatalln("Repository is nil, can not determine source of plugin")
+				racklog.Fatalf("Repository is nil, can not determine source of plugin")
 				return errors.New("Unable to open Repository")
 			}
 
 			url, err := gitutil.GetURLForRepo(*repo)
 			if err != nil {
-				log.Fatalf("GetURLForRepo - error: %v\n", err)
+				racklog.Fatalf("GetURLForRepo - error: %v\n", err)
 				return err
 			}
 
 			xmlData, err := ioutil.ReadFile(file.Name())
 			if err != nil {
-				log.Fatalf("ReadFile - error: %v\n", err)
+				racklog.Fatalf("ReadFile - error: %v\n", err)
 				return err
 			}
 
@@ -75,7 +75,7 @@ func Integrate() error {
 		}
 	}
 
-	log.Fatalf("Could not find plugin.xml in directory: '%v' to integrate rackspec\n", currentDir)
+	racklog.Fatalf("Could not find plugin.xml in directory: '%v' to integrate rackspec\n", currentDir)
 
 	return errors.New("No plugin.xml found")
 }
@@ -85,7 +85,7 @@ func writeYaml(currentDir, url string, xmlData []byte) error {
 
 	err := xml.Unmarshal(xmlData, &plugin)
 	if err != nil {
-		log.Fatalf("xml.Unmarshal - error: %v\n", err)
+		racklog.Fatalf("xml.Unmarshal - error: %v\n", err)
 	}
 
 	comp := rackspec.Compatibility{
@@ -115,7 +115,7 @@ func writeYaml(currentDir, url string, xmlData []byte) error {
 
 	specData, err := yaml.Marshal(&spec)
 	if err != nil {
-		log.Fatalf("Marshal - error: %v\n", err)
+		racklog.Fatalf("Marshal - error: %v\n", err)
 		return err
 	}
 
@@ -124,12 +124,12 @@ func writeYaml(currentDir, url string, xmlData []byte) error {
 
 	exists, err := fileutil.ObjectExists(rackSpecPath)
 	if err != nil {
-		log.Fatalf("ObjectExists - error: %v\n", err)
+		racklog.Fatalf("ObjectExists - error: %v\n", err)
 		return err
 	}
 
 	if exists {
-		log.Fatalf("%v already exists! Aborting\n", rackSpecPath)
+		racklog.Fatalf("%v already exists! Aborting\n", rackSpecPath)
 		return errors.New("RackSpecFile Already Exists")
 	}
 
@@ -160,13 +160,13 @@ func setupTheme(currentDir string) string {
 func Deintegrate() error {
 	dir, err := os.Getwd()
 	if err != nil {
-		log.Fatalf("Getwd - error: %v\n", err)
+		racklog.Fatalf("Getwd - error: %v\n", err)
 		return err
 	}
 
 	files, err := ioutil.ReadDir(dir)
 	if err != nil {
-		log.Fatalf("ReadDir - error: %v\n", err)
+		racklog.Fatalf("ReadDir - error: %v\n", err)
 		return err
 	}
 
@@ -176,17 +176,17 @@ func Deintegrate() error {
 
 			err = os.Remove(filePath)
 			if err != nil {
-				log.Fatalf("Remove - error: %v\n", err)
+				racklog.Fatalf("Remove - error: %v\n", err)
 				return err
 			}
 
-			log.Printf("Successfully removed %v\n", file.Name())
+			racklog.Infof("Removed %v\n", file.Name())
 
 			return nil
 		}
 	}
 
-	log.Fatalln("No rackspec in this directory - Aborting")
+	racklog.Fatalf("No rackspec in this directory - Aborting")
 
 	return errors.New("No rackspec found")
 }
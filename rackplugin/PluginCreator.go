@@ -1,11 +1,11 @@
 package rackplugin
 
 import (
-	"log"
 	"os"
 	"path/filepath"
 
 	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/fileutil"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racklog"
 )
 
 // createPluginSkeleton creates a plugin skeleton containing the base .php, plugin.xml and rackspec
@@ -22,7 +22,7 @@ func createPluginFolder(pluginName string) {
 
 	err := fileutil.CreateDirIfNotExistant(pluginDir)
 	if err != nil {
-		log.Fatalf("CreateDirIfNotExistant - error: %v\n", err)
+		racklog.Fatalf("CreateDirIfNotExistant - error: %v\n", err)
 	}
 }
 
@@ -40,7 +40,7 @@ func createBasePHP(pluginName string) {
 
 	err := fileutil.CreateOrWriteFile(filepath.Join(pluginDir, pluginName+".php"), data)
 	if err != nil {
-		log.Fatalf("CreateOrWriteFile - error: %v\n", err)
+		racklog.Fatalf("CreateOrWriteFile - error: %v\n", err)
 	}
 }
 
@@ -103,6 +103,6 @@ func createPluginXML(pluginName string, opts map[string]string) {
 
 	err := fileutil.CreateOrWriteFile(filepath.Join(pluginDir, "plugin.xml"), data)
 	if err != nil {
-		log.Fatalf("CreateOrWriteFile - error: %v\n", err)
+		racklog.Fatalf("CreateOrWriteFile - error: %v\n", err)
 	}
 }
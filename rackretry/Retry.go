@@ -0,0 +1,164 @@
+// Package rackretry includes the retry policies and time limits of operations on shops and git repositories,
+// that may fail because of the network.
+package rackretry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racklog"
+)
+
+// Defaults of a policy, that leaves values empty
+const (
+	DefaultAttempts = 3
+	DefaultDelay    = 2 * time.Second
+	DefaultMaxDelay = 30 * time.Second
+)
+
+// Policy decides how often a failing operation is tried and how long to wait in between.
+// The delay doubles after every attempt, up to MaxDelay.
+type Policy struct {
+	// Attempts is the number of times an operation is tried, 1 disables retries
+	Attempts int `yaml:",omitempty"`
+	// Delay is the time to wait before the first retry, like 2s
+	Delay string `yaml:",omitempty"`
+	// MaxDelay is the longest time to wait between two attempts, like 30s
+	MaxDelay string `yaml:",omitempty"`
+}
+
+// GetAttempts returns the number of times an operation is tried
+func (p Policy) GetAttempts() int {
+	if p.Attempts <= 0 {
+		return DefaultAttempts
+	}
+
+	return p.Attempts
+}
+
+// GetDelay returns the time to wait before the first retry
+func (p Policy) GetDelay() time.Duration {
+	return parseDuration(p.Delay, DefaultDelay)
+}
+
+// GetMaxDelay returns the longest time to wait between two attempts
+func (p Policy) GetMaxDelay() time.Duration {
+	return parseDuration(p.MaxDelay, DefaultMaxDelay)
+}
+
+// Merge returns the policy with the values set in the other policy replacing its own
+func (p Policy) Merge(other Policy) Policy {
+	if other.Attempts > 0 {
+		p.Attempts = other.Attempts
+	}
+
+	if len(other.Delay) > 0 {
+		p.Delay = other.Delay
+	}
+
+	if len(other.MaxDelay) > 0 {
+		p.MaxDelay = other.MaxDelay
+	}
+
+	return p
+}
+
+// Validate checks the durations of the policy
+func (p Policy) Validate() error {
+	if p.Attempts < 0 {
+		return errors.New("the number of attempts must not be negative")
+	}
+
+	return ValidateDurations(p.Delay, p.MaxDelay)
+}
+
+// permanentError is an error, that is not retried
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+// Stop marks an error, so Do returns it without retrying the operation
+func Stop(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return permanentError{err}
+}
+
+// Do runs the operation until it succeeds, it returns an error marked with Stop,
+// the attempts of the policy are used up or the context is done.
+// Every failed attempt, that is retried, is printed with the description of the operation.
+func Do(ctx context.Context, policy Policy, description string, operation func() error) error {
+	delay := policy.GetDelay()
+
+	for attempt := 1; ; attempt++ {
+		err := operation()
+		if err == nil {
+			return nil
+		}
+
+		if permanent, ok := err.(permanentError); ok {
+			return permanent.err
+		}
+
+		if attempt >= policy.GetAttempts() || ctx.Err() != nil {
+			return err
+		}
+
+		racklog.Warnf("%v failed (attempt %v of %v), retrying in %v: %v\n", description, attempt,
+			policy.GetAttempts(), delay, err)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+
+		if delay *= 2; delay > policy.GetMaxDelay() {
+			delay = policy.GetMaxDelay()
+		}
+	}
+}
+
+// WithTimeout runs the operation with a context, that is done after the timeout.
+// If the operation fails because of the timeout, the error says which operation took too long.
+func WithTimeout(ctx context.Context, timeout time.Duration, description string,
+	operation func(ctx context.Context) error) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := operation(timeoutCtx)
+	if err != nil && ctx.Err() == nil && timeoutCtx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("%v did not finish within %v", description, timeout)
+	}
+
+	return err
+}
+
+// ValidateDurations checks, that the given durations are empty or can be parsed
+func ValidateDurations(durations ...string) error {
+	for _, duration := range durations {
+		if _, err := time.ParseDuration(duration); len(duration) > 0 && err != nil {
+			return errors.New("invalid duration " + duration)
+		}
+	}
+
+	return nil
+}
+
+//parseDuration returns the parsed duration, or the default if it is empty or invalid
+func parseDuration(duration string, defaultDuration time.Duration) time.Duration {
+	parsed, err := time.ParseDuration(duration)
+	if err != nil || parsed <= 0 {
+		return defaultDuration
+	}
+
+	return parsed
+}
@@ -0,0 +1,112 @@
+package rackretry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDo(t *testing.T) {
+	failure := errors.New("connection refused")
+	permanent := errors.New("handshake failed")
+
+	tests := []struct {
+		name      string
+		attempts  int
+		failUntil int
+		stop      bool
+		wantCalls int
+		wantErr   error
+	}{
+		{name: "success", attempts: 3, failUntil: 0, wantCalls: 1},
+		{name: "success after retries", attempts: 3, failUntil: 2, wantCalls: 3},
+		{name: "attempts used up", attempts: 3, failUntil: 5, wantCalls: 3, wantErr: failure},
+		{name: "retries disabled", attempts: 1, failUntil: 5, wantCalls: 1, wantErr: failure},
+		{name: "stopped", attempts: 3, failUntil: 5, stop: true, wantCalls: 1, wantErr: permanent},
+	}
+
+	for _, test := range tests {
+		calls := 0
+		policy := Policy{Attempts: test.attempts, Delay: "1ms"}
+
+		err := Do(context.Background(), policy, test.name, func() error {
+			calls++
+
+			switch {
+			case test.stop:
+				return Stop(permanent)
+			case calls <= test.failUntil:
+				return failure
+			}
+
+			return nil
+		})
+
+		if err != test.wantErr || calls != test.wantCalls {
+			t.Errorf("%v: got %v after %v calls, want %v after %v", test.name, err, calls, test.wantErr, test.wantCalls)
+		}
+	}
+}
+
+func TestDoBackoff(t *testing.T) {
+	policy := Policy{Attempts: 4, Delay: "20ms", MaxDelay: "30ms"}
+	calls := []time.Time{}
+
+	_ = Do(context.Background(), policy, "backoff", func() error {
+		calls = append(calls, time.Now())
+		return errors.New("failed")
+	})
+
+	if len(calls) != 4 {
+		t.Fatalf("got %v calls, want 4", len(calls))
+	}
+
+	// the delay doubles from 20ms and is capped at 30ms
+	minimums := []time.Duration{20 * time.Millisecond, 30 * time.Millisecond, 30 * time.Millisecond}
+
+	for i, minimum := range minimums {
+		if waited := calls[i+1].Sub(calls[i]); waited < minimum {
+			t.Errorf("waited %v before attempt %v, want at least %v", waited, i+2, minimum)
+		}
+	}
+}
+
+func TestDoCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+
+	err := Do(ctx, Policy{Attempts: 5, Delay: "1m"}, "cancelled", func() error {
+		calls++
+		cancel()
+
+		return errors.New("failed")
+	})
+
+	if err == nil || calls != 1 {
+		t.Errorf("got %v after %v calls, want the error of the only call", err, calls)
+	}
+}
+
+func TestStop(t *testing.T) {
+	if Stop(nil) != nil {
+		t.Error("Stop(nil) should be nil")
+	}
+}
+
+func TestPolicyDefaultsAndMerge(t *testing.T) {
+	policy := Policy{}
+	if policy.GetAttempts() != DefaultAttempts || policy.GetDelay() != DefaultDelay ||
+		policy.GetMaxDelay() != DefaultMaxDelay {
+		t.Errorf("empty policy got %v, %v, %v", policy.GetAttempts(), policy.GetDelay(), policy.GetMaxDelay())
+	}
+
+	merged := Policy{Attempts: 5, Delay: "1s", MaxDelay: "10s"}.Merge(Policy{Delay: "3s"})
+	if merged != (Policy{Attempts: 5, Delay: "3s", MaxDelay: "10s"}) {
+		t.Errorf("merged policy = %+v", merged)
+	}
+
+	if err := (Policy{Delay: "soon"}).Validate(); err == nil {
+		t.Error("expected an invalid delay to fail the validation")
+	}
+}
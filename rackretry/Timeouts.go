@@ -0,0 +1,69 @@
+package rackretry
+
+import "time"
+
+// Defaults of timeouts, that are left empty
+const (
+	DefaultDialTimeout     = 30 * time.Second
+	DefaultCommandTimeout  = 30 * time.Minute
+	DefaultGitTimeout      = 10 * time.Minute
+	DefaultLsRemoteTimeout = time.Minute
+)
+
+// Timeouts limit how long rackjobber waits for a shop or a git repository, durations like 30s or 10m
+type Timeouts struct {
+	// Dial is the time to establish the ssh connection to a shop
+	Dial string `yaml:",omitempty"`
+	// Command is the time a single command on a shop may take
+	Command string `yaml:",omitempty"`
+	// Git is the time a git clone, fetch or pull may take, on the shop and on this machine
+	Git string `yaml:",omitempty"`
+	// LsRemote is the time git ls-remote may take to find the commit of a version
+	LsRemote string `yaml:",omitempty"`
+}
+
+// GetDial returns the time to establish the ssh connection to a shop
+func (t Timeouts) GetDial() time.Duration {
+	return parseDuration(t.Dial, DefaultDialTimeout)
+}
+
+// GetCommand returns the time a single command on a shop may take
+func (t Timeouts) GetCommand() time.Duration {
+	return parseDuration(t.Command, DefaultCommandTimeout)
+}
+
+// GetGit returns the time a git clone, fetch or pull may take
+func (t Timeouts) GetGit() time.Duration {
+	return parseDuration(t.Git, DefaultGitTimeout)
+}
+
+// GetLsRemote returns the time git ls-remote may take
+func (t Timeouts) GetLsRemote() time.Duration {
+	return parseDuration(t.LsRemote, DefaultLsRemoteTimeout)
+}
+
+// Merge returns the timeouts with the values set in the other timeouts replacing its own
+func (t Timeouts) Merge(other Timeouts) Timeouts {
+	if len(other.Dial) > 0 {
+		t.Dial = other.Dial
+	}
+
+	if len(other.Command) > 0 {
+		t.Command = other.Command
+	}
+
+	if len(other.Git) > 0 {
+		t.Git = other.Git
+	}
+
+	if len(other.LsRemote) > 0 {
+		t.LsRemote = other.LsRemote
+	}
+
+	return t
+}
+
+// Validate checks the durations of the timeouts
+func (t Timeouts) Validate() error {
+	return ValidateDurations(t.Dial, t.Command, t.Git, t.LsRemote)
+}
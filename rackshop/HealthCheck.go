@@ -0,0 +1,67 @@
+package rackshop
+
+import (
+	"errors"
+	"time"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackhook"
+)
+
+// HealthCheck is a request to the shop, that has to succeed after up
+type HealthCheck struct {
+	URL string
+	// Status are the expected status codes, 200 if empty
+	Status []int `yaml:",omitempty"`
+	// Contains are texts, that the body of the response has to contain
+	Contains []string `yaml:",omitempty"`
+	// MaxLatency is the longest time the response may take, like 2s
+	MaxLatency string `yaml:",omitempty"`
+	// Retries is the number of times a failing check is repeated, after waiting RetryDelay (5s by default)
+	Retries    int    `yaml:",omitempty"`
+	RetryDelay string `yaml:",omitempty"`
+	// OnFailure is one of the policies of hooks, abort if it is empty
+	OnFailure string `yaml:",omitempty"`
+}
+
+// GetStatus returns the expected status codes of the check
+func (h HealthCheck) GetStatus() []int {
+	if len(h.Status) == 0 {
+		return []int{200}
+	}
+
+	return h.Status
+}
+
+// GetRetryDelay returns the time to wait before a failing check is repeated
+func (h HealthCheck) GetRetryDelay() time.Duration {
+	delay, err := time.ParseDuration(h.RetryDelay)
+	if err != nil {
+		return 5 * time.Second
+	}
+
+	return delay
+}
+
+// Policy returns the failure policy of the check
+func (h HealthCheck) Policy() string {
+	if len(h.OnFailure) == 0 {
+		return rackhook.OnFailureAbort
+	}
+
+	return h.OnFailure
+}
+
+// Validate checks the url, the durations and the failure policy of the check
+func (h HealthCheck) Validate() error {
+	if len(h.URL) == 0 {
+		return errors.New("a health check has no url")
+	}
+
+	for _, duration := range []string{h.MaxLatency, h.RetryDelay} {
+		if _, err := time.ParseDuration(duration); len(duration) > 0 && err != nil {
+			return errors.New("invalid duration " + duration + " of the health check of " + h.URL)
+		}
+	}
+
+	return rackhook.ValidatePolicy(h.OnFailure)
+}
@@ -0,0 +1,63 @@
+package rackshop
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Modes to switch a shop into maintenance mode
+const (
+	// MaintenanceSetting uses the maintenance setting of shopware, this is the default
+	MaintenanceSetting = "setting"
+	// MaintenanceFile creates a file in the shopware directory, that the webserver checks for
+	MaintenanceFile = "file"
+	// MaintenanceCommand runs custom commands to switch the maintenance mode on and off
+	MaintenanceCommand = "command"
+)
+
+// MaintenanceModes lists the known modes to switch a shop into maintenance mode
+var MaintenanceModes = []string{MaintenanceSetting, MaintenanceFile, MaintenanceCommand}
+
+// Maintenance configures how a shop is switched into maintenance mode
+type Maintenance struct {
+	// Mode is one of MaintenanceModes, the setting of shopware if it is empty
+	Mode string `yaml:",omitempty"`
+	// File is the path of the maintenance file relative to the shopware directory.
+	// The file contains the whitelisted IPs, one per line.
+	File string `yaml:",omitempty"`
+	// On and Off are the shell commands, that switch the maintenance mode on and off.
+	// The whitelisted IPs are passed comma separated in RACKJOBBER_WHITELIST.
+	On  string `yaml:",omitempty"`
+	Off string `yaml:",omitempty"`
+	// Whitelist are the IPs, that can still use the shop during the maintenance
+	Whitelist []string `yaml:",omitempty"`
+}
+
+// GetMode returns the mode of the maintenance, the setting of shopware if none is configured
+func (m Maintenance) GetMode() string {
+	if len(m.Mode) == 0 {
+		return MaintenanceSetting
+	}
+
+	return m.Mode
+}
+
+// Validate checks that the mode is known and has its file or commands configured
+func (m Maintenance) Validate() error {
+	switch m.GetMode() {
+	case MaintenanceSetting:
+	case MaintenanceFile:
+		if len(m.File) == 0 {
+			return errors.New("the maintenance mode file needs a maintenance file")
+		}
+	case MaintenanceCommand:
+		if len(m.On) == 0 || len(m.Off) == 0 {
+			return errors.New("the maintenance mode command needs a command to switch it on and off")
+		}
+	default:
+		return fmt.Errorf("unknown maintenance mode %v, must be one of %v", m.Mode, strings.Join(MaintenanceModes, ", "))
+	}
+
+	return nil
+}
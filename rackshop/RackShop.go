@@ -3,15 +3,20 @@ package rackshop
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"golang.org/x/crypto/ssh"
 	"gopkg.in/yaml.v2"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackhook"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racklog"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racknotify"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackretry"
 )
 
 // RackShop struct that holds the information for a Shop that will be stored in the ShopStore
@@ -22,6 +27,154 @@ type RackShop struct {
 	Password    string
 	ShopwareDir string
 	Container   string
+	Tags        []string `yaml:",omitempty"`
+	Environment string   `yaml:",omitempty"`
+	// Local marks a shop, that runs in docker on the executing machine and is managed without ssh
+	Local bool `yaml:",omitempty"`
+	// ThemeStrategy is the way themes are assigned to the subshops, one of ThemeStrategies
+	ThemeStrategy string `yaml:",omitempty"`
+	// Hooks run before and after every up of the shop
+	Hooks rackhook.Hooks `yaml:",omitempty"`
+	// Maintenance is the way the shop is switched into maintenance mode during up
+	Maintenance Maintenance `yaml:",omitempty"`
+	// HealthChecks are checked after every up of the shop
+	HealthChecks []HealthCheck `yaml:",omitempty"`
+	// Timeouts replace the timeouts of the config for the shop
+	Timeouts rackretry.Timeouts `yaml:",omitempty"`
+	// Retry replaces the retry policy of the config for the shop
+	Retry rackretry.Policy `yaml:",omitempty"`
+	// Notifications are notified of every up of the shop, in addition to those of the config and the groups
+	Notifications []racknotify.Target `yaml:",omitempty"`
+	// BackupDir is the absolute directory on the shop, that its backups are stored in.
+	// It must lie outside of the shopware directory, so the backups can not be downloaded from the shop.
+	BackupDir string `yaml:",omitempty"`
+}
+
+// GetBackupDir returns the directory of the backups on the shop,
+// rackjobber-backups/<name> next to the shopware directory if none is set
+func (r RackShop) GetBackupDir() string {
+	if len(r.BackupDir) > 0 {
+		return r.BackupDir
+	}
+
+	return filepath.Join(filepath.Dir(filepath.Clean(r.ShopwareDir)), "rackjobber-backups", r.Name)
+}
+
+//validateBackupDir checks, that the backup directory is absolute and lies outside of the shopware directory
+func (r RackShop) validateBackupDir() error {
+	if len(r.BackupDir) == 0 {
+		return nil
+	}
+
+	if !filepath.IsAbs(r.BackupDir) {
+		return errors.New("the backup directory " + r.BackupDir + " must be an absolute path")
+	}
+
+	rel, err := filepath.Rel(filepath.Clean(r.ShopwareDir), filepath.Clean(r.BackupDir))
+	if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return errors.New("the backup directory " + r.BackupDir + " must lie outside of the shopware directory")
+	}
+
+	return nil
+}
+
+// Environments lists the environments a shop may be assigned to
+var Environments = []string{"dev", "staging", "production"}
+
+// ValidateEnvironment checks if the given environment is empty or one of the known Environments
+func ValidateEnvironment(environment string) error {
+	if environment == "" {
+		return nil
+	}
+
+	for _, known := range Environments {
+		if environment == known {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown environment %v, must be one of %v", environment, strings.Join(Environments, ", "))
+}
+
+// Strategies to assign themes to the subshops of a shop
+const (
+	// ThemeStrategyAuto uses the custom command if it is installed, else the theme service of shopware
+	// if its console can synchronize themes, else the database
+	ThemeStrategyAuto = "auto"
+	// ThemeStrategyCommand uses the custom wdy:theme:set console command
+	ThemeStrategyCommand = "command"
+	// ThemeStrategyConsole synchronizes the themes with the console and assigns them with the theme service of shopware
+	ThemeStrategyConsole = "console"
+	// ThemeStrategyDatabase updates the theme of the subshop in the database
+	ThemeStrategyDatabase = "database"
+)
+
+// ThemeStrategies lists the strategies, that can be used to assign themes
+var ThemeStrategies = []string{ThemeStrategyAuto, ThemeStrategyCommand, ThemeStrategyConsole, ThemeStrategyDatabase}
+
+// ValidateThemeStrategy checks if the given strategy is empty or one of the known ThemeStrategies
+func ValidateThemeStrategy(strategy string) error {
+	if strategy == "" {
+		return nil
+	}
+
+	for _, known := range ThemeStrategies {
+		if strategy == known {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown theme strategy %v, must be one of %v", strategy, strings.Join(ThemeStrategies, ", "))
+}
+
+// Validate checks the environment, the theme strategy and the other settings of the shop
+func (r RackShop) Validate() error {
+	if err := ValidateEnvironment(r.Environment); err != nil {
+		return err
+	}
+
+	if err := ValidateThemeStrategy(r.ThemeStrategy); err != nil {
+		return err
+	}
+
+	if err := r.Maintenance.Validate(); err != nil {
+		return err
+	}
+
+	for _, check := range r.HealthChecks {
+		if err := check.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if err := r.Timeouts.Validate(); err != nil {
+		return err
+	}
+
+	if err := r.Retry.Validate(); err != nil {
+		return err
+	}
+
+	if err := racknotify.ValidateTargets(r.Notifications); err != nil {
+		return err
+	}
+
+	if err := r.validateBackupDir(); err != nil {
+		return err
+	}
+
+	return rackhook.ValidateHooks(r.Hooks.Before, r.Hooks.After)
+}
+
+// HasTag checks if the shop carries the given tag
+func (r RackShop) HasTag(tag string) bool {
+	for _, shopTag := range r.Tags {
+		if shopTag == tag {
+			return true
+		}
+	}
+
+	return false
 }
 
 // UnmarshalRackShop will unmarshal a yaml file at a specified path.
@@ -67,13 +220,13 @@ func (r RackShop) GetRemoteConfig() *ssh.ClientConfig {
 func getPrivateKeyFile() ssh.AuthMethod {
 	key, err := ioutil.ReadFile(filepath.Join(os.Getenv("HOME"), ".ssh", "id_rsa"))
 	if err != nil {
-		log.Fatalf("unable to read private key: %v", err)
+		racklog.Fatalf("unable to read private key: %v", err)
 		fmt.Println("Please setup the initial ssh connection manually")
 	}
 
 	signer, err := ssh.ParsePrivateKey(key)
 	if err != nil {
-		log.Fatalf("unable to parse private key: %v", err)
+		racklog.Fatalf("unable to parse private key: %v", err)
 		fmt.Println("Please setup the initial ssh connection manually")
 	}
 
@@ -84,14 +237,14 @@ func getPrivateKeyFile() ssh.AuthMethod {
 func getHostKey(host string) ssh.PublicKey {
 	file, err := os.Open(filepath.Join(os.Getenv("HOME"), ".ssh", "known_hosts"))
 	if err != nil {
-		log.Fatalf("known_hosts not found: %v\n", err)
+		racklog.Fatalf("known_hosts not found: %v\n", err)
 		fmt.Println("Please setup the initial ssh connection manually")
 	}
 
 	defer func() {
 		err := file.Close()
 		if err != nil {
-			log.Fatalf("file.Close - error: %v\n", err)
+			racklog.Fatalf("file.Close - error: %v\n", err)
 		}
 	}()
 
@@ -111,7 +264,7 @@ func getHostKey(host string) ssh.PublicKey {
 
 			hostKey, _, _, _, err = ssh.ParseAuthorizedKey(scanner.Bytes())
 			if err != nil {
-				log.Fatalf("error parsing %q: %v", fields[2], err)
+				racklog.Fatalf("error parsing %q: %v", fields[2], err)
 			}
 
 			break
@@ -119,7 +272,7 @@ func getHostKey(host string) ssh.PublicKey {
 	}
 
 	if hostKey == nil {
-		log.Fatalf("no hostkey found for %s", host)
+		racklog.Fatalf("no hostkey found for %s", host)
 		fmt.Println("Please setup the initial ssh connection manually")
 	}
 
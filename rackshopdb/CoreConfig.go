@@ -0,0 +1,70 @@
+package rackshopdb
+
+import (
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshop"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackssh"
+)
+
+// readCoreConfigScript prints the value of a basic setting of shopware for a subshop,
+// or its default if it is not set for the subshop
+const readCoreConfigScript = `
+$statement = $pdo->prepare('SELECT e.value, v.value FROM s_core_config_elements e '
+    . 'JOIN s_core_config_forms f ON f.id = e.form_id '
+    . 'LEFT JOIN s_core_config_values v ON v.element_id = e.id AND v.shop_id = ? '
+    . 'WHERE f.plugin_id IS NULL AND e.name = ?');
+$statement->execute([$request['subShop'], $request['name']]);
+$row = $statement->fetch(PDO::FETCH_NUM);
+if ($row === false) {
+    echo 'unknown setting ' . $request['name'];
+    exit(1);
+}
+$value = $row[1] !== null ? $row[1] : $row[0];
+echo json_encode($value === null ? null : unserialize($value));
+`
+
+// writeCoreConfigScript sets the value of a basic setting of shopware for a subshop
+const writeCoreConfigScript = `
+$statement = $pdo->prepare('SELECT e.id FROM s_core_config_elements e '
+    . 'JOIN s_core_config_forms f ON f.id = e.form_id WHERE f.plugin_id IS NULL AND e.name = ?');
+$statement->execute([$request['name']]);
+$elementID = $statement->fetchColumn();
+if ($elementID === false) {
+    echo 'unknown setting ' . $request['name'];
+    exit(1);
+}
+$pdo->prepare('DELETE FROM s_core_config_values WHERE element_id = ? AND shop_id = ?')
+    ->execute([$elementID, $request['subShop']]);
+$pdo->prepare('INSERT INTO s_core_config_values (element_id, shop_id, value) VALUES (?, ?, ?)')
+    ->execute([$elementID, $request['subShop'], serialize($request['value'])]);
+echo json_encode(true);
+`
+
+// coreConfigRequest is passed to the readCoreConfigScript and the writeCoreConfigScript
+type coreConfigRequest struct {
+	Name    string      `json:"name"`
+	SubShop string      `json:"subShop"`
+	Value   interface{} `json:"value"`
+}
+
+// ReadCoreConfig returns the value of a basic setting of shopware for a subshop
+func ReadCoreConfig(executor rackssh.Executor, shop *rackshop.RackShop, subShopID string,
+	name string) (interface{}, error) {
+	var value interface{}
+
+	if err := runScript(executor, shop, readCoreConfigScript, coreConfigRequest{Name: name, SubShop: subShopID},
+		&value); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// WriteCoreConfig sets the value of a basic setting of shopware for a subshop.
+// The cache of the shop has to be cleared, before shopware uses the value.
+func WriteCoreConfig(executor rackssh.Executor, shop *rackshop.RackShop, subShopID string, name string,
+	value interface{}) error {
+	var written bool
+
+	return runScript(executor, shop, writeCoreConfigScript,
+		coreConfigRequest{Name: name, SubShop: subShopID, Value: value}, &written)
+}
@@ -0,0 +1,34 @@
+package rackshopdb
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshop"
+)
+
+// clientScript runs a mysql client with the credentials of shopware's config.php or .env and passes its exit code on.
+// The password is passed in the environment, so it does not show up in the process list.
+const clientScript = credentialsScript + `putenv('MYSQL_PWD=' . $db['password']);
+$port = empty($db['port']) ? '3306' : strval($db['port']);
+passthru('%s -h ' . escapeshellarg($db['host']) . ' -P ' . escapeshellarg($port)
+    . ' -u ' . escapeshellarg($db['username']) . ' ' . escapeshellarg($db['dbname']), $code);
+exit($code);`
+
+// DumpCommand returns the command, that prints a dump of the shop's database.
+// mysqldump has to be available in the container of the shop.
+func DumpCommand(shop *rackshop.RackShop) string {
+	return clientCommand(shop, "mysqldump --single-transaction --routines --no-tablespaces")
+}
+
+// RestoreCommand returns the command, that executes the sql read from its input in the shop's database
+func RestoreCommand(shop *rackshop.RackShop) string {
+	return clientCommand(shop, "mysql")
+}
+
+//clientCommand returns the command running the client inside of the shop's container.
+//The script is passed as an argument, so the input of the command is passed on to the client.
+func clientCommand(shop *rackshop.RackShop, client string) string {
+	script := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf(clientScript, client)))
+	return "docker exec -i " + shop.Container + " php -r \"eval(base64_decode('" + script + "'));\""
+}
@@ -0,0 +1,55 @@
+package rackshopdb
+
+import (
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshop"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackssh"
+)
+
+// pluginConfigScript prints the configuration values of a plugin for a subshop.
+// The values are stored serialized by shopware, they are printed in the format sw:plugin:config:set accepts.
+const pluginConfigScript = `
+$statement = $pdo->prepare('SELECT e.name, e.value, v.value FROM s_core_config_elements e '
+    . 'JOIN s_core_config_forms f ON f.id = e.form_id '
+    . 'JOIN s_core_plugins p ON p.id = f.plugin_id '
+    . 'LEFT JOIN s_core_config_values v ON v.element_id = e.id AND v.shop_id = ? '
+    . 'WHERE p.name = ?');
+$statement->execute([$request['subShop'], $request['plugin']]);
+$format = function ($value) use (&$format) {
+    if ($value === null) {
+        return 'null';
+    }
+    if (is_bool($value)) {
+        return $value ? 'true' : 'false';
+    }
+    if (is_array($value)) {
+        return '[' . implode(',', array_map($format, $value)) . ']';
+    }
+    return strval($value);
+};
+$values = new stdClass();
+foreach ($statement->fetchAll(PDO::FETCH_NUM) as $row) {
+    $value = $row[2] !== null ? $row[2] : $row[1];
+    $values->{$row[0]} = $value === null ? 'null' : $format(unserialize($value));
+}
+echo json_encode($values);
+`
+
+// pluginConfigRequest is passed to the pluginConfigScript
+type pluginConfigRequest struct {
+	Plugin  string `json:"plugin"`
+	SubShop string `json:"subShop"`
+}
+
+// ReadPluginConfig returns the configuration values of a plugin, that apply to the given subshop.
+// Values, that are not set for the subshop, are returned with the default of the plugin.
+// Booleans, null and lists are returned as true, false, null and [a,b], like they are passed to the shopware console.
+func ReadPluginConfig(executor rackssh.Executor, shop *rackshop.RackShop, plugin string,
+	subShopID string) (map[string]string, error) {
+	values := map[string]string{}
+
+	if err := runScript(executor, shop, pluginConfigScript, pluginConfigRequest{plugin, subShopID}, &values); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
@@ -0,0 +1,126 @@
+// Package rackshopdb includes functions to query the database of a shopware installation.
+// The queries are executed by php inside of the shop's container, using the credentials of shopware's config.php
+// or .env, so neither a database client nor the credentials are needed on the executing machine.
+package rackshopdb
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshop"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackssh"
+)
+
+// credentialsScript reads the database credentials of shopware into $db.
+// They are read from the config.php, or from the DATABASE_URL of the environment or the .env file of installations,
+// whose config.php does not contain them.
+const credentialsScript = `
+$db = [];
+if (is_file('/var/www/html/config.php')) {
+    $config = require '/var/www/html/config.php';
+    if (is_array($config) && !empty($config['db'])) {
+        $db = $config['db'];
+    }
+}
+if (empty($db['host']) || empty($db['dbname'])) {
+    $url = getenv('DATABASE_URL');
+    if (!$url && is_file('/var/www/html/.env')) {
+        foreach (file('/var/www/html/.env', FILE_IGNORE_NEW_LINES) as $line) {
+            if (preg_match('/^\s*(?:export\s+)?DATABASE_URL\s*=\s*([\'"]?)(.*)\1\s*$/', $line, $match)) {
+                $url = $match[2];
+            }
+        }
+    }
+    $parts = $url ? parse_url($url) : false;
+    if (!$parts || empty($parts['host'])) {
+        fwrite(STDERR, "no database credentials found in config.php or .env\n");
+        exit(1);
+    }
+    $db = [
+        'host' => $parts['host'],
+        'port' => isset($parts['port']) ? $parts['port'] : '',
+        'username' => isset($parts['user']) ? rawurldecode($parts['user']) : '',
+        'password' => isset($parts['pass']) ? rawurldecode($parts['pass']) : '',
+        'dbname' => isset($parts['path']) ? ltrim($parts['path'], '/') : '',
+    ];
+}
+`
+
+// connectScript opens the database connection of shopware and decodes the request of a script.
+// The request is passed base64 encoded, so it does not need to be escaped for the shell.
+const connectScript = `<?php` + credentialsScript + `$dsn = 'mysql:host=' . $db['host'] . ';dbname=' . $db['dbname'] . ';charset=utf8';
+if (!empty($db['port'])) {
+    $dsn .= ';port=' . $db['port'];
+}
+$pdo = new PDO($dsn, $db['username'], $db['password'], [PDO::ATTR_ERRMODE => PDO::ERRMODE_EXCEPTION]);
+$request = json_decode(base64_decode('%s'), true);
+`
+
+// queryScript executes a prepared statement with its arguments and prints the resulting rows
+const queryScript = `
+$statement = $pdo->prepare($request['query']);
+$statement->execute($request['args']);
+$rows = [];
+if ($statement->columnCount() > 0) {
+    foreach ($statement->fetchAll(PDO::FETCH_NUM) as $row) {
+        $rows[] = array_map('strval', $row);
+    }
+}
+echo json_encode($rows);
+`
+
+// request is passed to the queryScript
+type request struct {
+	Query string   `json:"query"`
+	Args  []string `json:"args"`
+}
+
+// Query executes a statement with positional arguments in the database of a shop and returns the resulting rows.
+// All values are returned as strings, NULL values as empty strings.
+func Query(executor rackssh.Executor, shop *rackshop.RackShop, query string, args ...string) ([][]string, error) {
+	if args == nil {
+		args = []string{}
+	}
+
+	rows := [][]string{}
+
+	if err := runScript(executor, shop, queryScript, request{query, args}, &rows); err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+//runScript runs a php script with the database connection of shopware in the shop's container.
+//The request is available to the script as $request, the json printed by the script is unmarshaled into result.
+func runScript(executor rackssh.Executor, shop *rackshop.RackShop, body string, request interface{},
+	result interface{}) error {
+	requestData, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	script := fmt.Sprintf(connectScript, base64.StdEncoding.EncodeToString(requestData)) + body
+	encodedScript := base64.StdEncoding.EncodeToString([]byte(script))
+	command := "echo " + encodedScript + " | base64 -d | docker exec -i " + shop.Container + " php"
+
+	out, err := executor.Run(command)
+	if err != nil {
+		return errors.New("database query failed: " + strings.TrimSpace(out))
+	}
+
+	if err = json.Unmarshal([]byte(strings.TrimSpace(out)), result); err != nil {
+		return errors.New("unexpected output of database query: " + strings.TrimSpace(out))
+	}
+
+	return nil
+}
+
+// Exec executes a statement without result rows in the database of a shop
+func Exec(executor rackssh.Executor, shop *rackshop.RackShop, query string, args ...string) error {
+	_, err := Query(executor, shop, query, args...)
+	return err
+}
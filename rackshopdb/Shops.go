@@ -0,0 +1,82 @@
+package rackshopdb
+
+import (
+	"errors"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshop"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackssh"
+)
+
+// SubShop is a shop of a shopware installation with its assigned theme.
+// Language shops inherit the theme of their main shop and are not listed.
+type SubShop struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Default bool   `json:"default"`
+	Theme   string `json:"theme"`
+}
+
+// ReadSubShops returns all main shops of a shopware installation with the theme assigned to them
+func ReadSubShops(executor rackssh.Executor, shop *rackshop.RackShop) ([]SubShop, error) {
+	rows, err := Query(executor, shop, "SELECT s.id, s.name, s.`default`, COALESCE(t.template, '') "+
+		"FROM s_core_shops s LEFT JOIN s_core_templates t ON t.id = s.template_id "+
+		"WHERE s.main_id IS NULL ORDER BY s.id")
+	if err != nil {
+		return nil, err
+	}
+
+	subShops := []SubShop{}
+
+	for _, row := range rows {
+		const columns = 4
+		if len(row) < columns {
+			continue
+		}
+
+		subShops = append(subShops, SubShop{
+			ID:      row[0],
+			Name:    row[1],
+			Default: row[2] == "1",
+			Theme:   row[3],
+		})
+	}
+
+	return subShops, nil
+}
+
+// FindSubShop returns the subshop with the given ID or name
+func FindSubShop(subShops []SubShop, reference string) (*SubShop, error) {
+	for i, subShop := range subShops {
+		if subShop.ID == reference || subShop.Name == reference {
+			return &subShops[i], nil
+		}
+	}
+
+	return nil, errors.New("the shop " + reference + " does not exist in shopware")
+}
+
+// DefaultSubShop returns the default subshop
+func DefaultSubShop(subShops []SubShop) (*SubShop, error) {
+	for i, subShop := range subShops {
+		if subShop.Default {
+			return &subShops[i], nil
+		}
+	}
+
+	return nil, errors.New("the shop has no default subshop")
+}
+
+// AssignTemplate assigns the theme with the given template name to a main shop and its language shops
+func AssignTemplate(executor rackssh.Executor, shop *rackshop.RackShop, subShopID string, template string) error {
+	rows, err := Query(executor, shop, "SELECT id FROM s_core_templates WHERE template = ?", template)
+	if err != nil {
+		return err
+	}
+
+	if len(rows) == 0 || len(rows[0]) == 0 {
+		return errors.New("the theme " + template + " is not known to shopware")
+	}
+
+	return Exec(executor, shop, "UPDATE s_core_shops SET template_id = ? WHERE id = ? OR main_id = ?",
+		rows[0][0], subShopID, subShopID)
+}
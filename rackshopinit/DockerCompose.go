@@ -0,0 +1,91 @@
+package rackshopinit
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultShopwareImage is the docker image used for new shopware installations
+const DefaultShopwareImage = "shyim/shopware:5.7"
+
+// DefaultDatabaseImage is the docker image used for the database of new shopware installations
+const DefaultDatabaseImage = "mysql:5.7"
+
+// composeFile defines the structure of the generated docker-compose.yml
+type composeFile struct {
+	Version  string                    `yaml:"version"`
+	Services map[string]composeService `yaml:"services"`
+	Volumes  map[string]struct{}       `yaml:"volumes"`
+}
+
+// composeService defines a single service inside of the generated docker-compose.yml
+type composeService struct {
+	Image         string            `yaml:"image"`
+	ContainerName string            `yaml:"container_name"`
+	Restart       string            `yaml:"restart"`
+	DependsOn     []string          `yaml:"depends_on,omitempty"`
+	Ports         []string          `yaml:"ports,omitempty"`
+	Environment   map[string]string `yaml:"environment"`
+	Volumes       []string          `yaml:"volumes"`
+}
+
+// createDockerCompose returns the docker-compose definition for a shopware installation with its database.
+// The plugin directory of the container is mounted from custom/plugins next to the docker-compose.yml,
+// so rackjobber is able to manage the plugins with git on the host.
+func createDockerCompose(opts Options, databasePassword string) ([]byte, error) {
+	databaseContainer := opts.Shop.Container + "_database"
+
+	compose := composeFile{
+		Version: "3",
+		Services: map[string]composeService{
+			"database": {
+				Image:         opts.DatabaseImage,
+				ContainerName: databaseContainer,
+				Restart:       "unless-stopped",
+				Environment: map[string]string{
+					"MYSQL_ROOT_PASSWORD": databasePassword,
+					"MYSQL_DATABASE":      "shopware",
+					"MYSQL_USER":          "shopware",
+					"MYSQL_PASSWORD":      databasePassword,
+				},
+				Volumes: []string{"database:/var/lib/mysql"},
+			},
+			"shopware": {
+				Image:         opts.Image,
+				ContainerName: opts.Shop.Container,
+				Restart:       "unless-stopped",
+				DependsOn:     []string{"database"},
+				Ports:         []string{opts.HTTPPort + ":80"},
+				Environment: map[string]string{
+					"DATABASE_HOST":     "database",
+					"DATABASE_PORT":     "3306",
+					"DATABASE_NAME":     "shopware",
+					"DATABASE_USER":     "shopware",
+					"DATABASE_PASSWORD": databasePassword,
+					"SHOP_URL":          opts.ShopURL,
+				},
+				Volumes: []string{"./custom/plugins:/var/www/html/custom/plugins"},
+			},
+		},
+		Volumes: map[string]struct{}{
+			"database": {},
+		},
+	}
+
+	return yaml.Marshal(&compose)
+}
+
+// generatePassword returns a random password for the database
+func generatePassword() (string, error) {
+	const passwordBytes = 16
+
+	data := make([]byte, passwordBytes)
+
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(data), nil
+}
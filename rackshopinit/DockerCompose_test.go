@@ -0,0 +1,77 @@
+package rackshopinit
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshop"
+)
+
+func TestWithDefaults(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		want Options
+	}{
+		{name: "remote shop", opts: Options{Shop: rackshop.RackShop{Name: "dev", Address: "10.0.0.5"}},
+			want: Options{Shop: rackshop.RackShop{Name: "dev", Address: "10.0.0.5", Container: "dev_shopware"},
+				Image: DefaultShopwareImage, DatabaseImage: DefaultDatabaseImage, HTTPPort: "80",
+				ShopURL: "http://10.0.0.5"}},
+		{name: "local shop with port", opts: Options{Shop: rackshop.RackShop{Name: "dev", Local: true}, HTTPPort: "8080"},
+			want: Options{Shop: rackshop.RackShop{Name: "dev", Local: true, Container: "dev_shopware"},
+				Image: DefaultShopwareImage, DatabaseImage: DefaultDatabaseImage, HTTPPort: "8080",
+				ShopURL: "http://localhost:8080"}},
+		{name: "given options", opts: Options{Shop: rackshop.RackShop{Name: "dev", Address: "10.0.0.5", Container: "app"},
+			Image: "shopware:5.6", DatabaseImage: "mariadb:10", HTTPPort: "8080", ShopURL: "https://shop.example.com"},
+			want: Options{Shop: rackshop.RackShop{Name: "dev", Address: "10.0.0.5", Container: "app"},
+				Image: "shopware:5.6", DatabaseImage: "mariadb:10", HTTPPort: "8080",
+				ShopURL: "https://shop.example.com"}},
+	}
+
+	for _, test := range tests {
+		if got := withDefaults(test.opts); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: got %+v, want %+v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestCreateDockerCompose(t *testing.T) {
+	opts := withDefaults(Options{Shop: rackshop.RackShop{Name: "dev", Local: true}, HTTPPort: "8080"})
+
+	data, err := createDockerCompose(opts, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	compose := composeFile{}
+	if err := yaml.Unmarshal(data, &compose); err != nil {
+		t.Fatal(err)
+	}
+
+	shopware := compose.Services["shopware"]
+	database := compose.Services["database"]
+
+	for _, check := range []struct {
+		name string
+		got  interface{}
+		want interface{}
+	}{
+		{name: "shopware image", got: shopware.Image, want: DefaultShopwareImage},
+		{name: "shopware container", got: shopware.ContainerName, want: "dev_shopware"},
+		{name: "ports", got: shopware.Ports, want: []string{"8080:80"}},
+		{name: "shop url", got: shopware.Environment["SHOP_URL"], want: "http://localhost:8080"},
+		{name: "shopware password", got: shopware.Environment["DATABASE_PASSWORD"], want: "secret"},
+		{name: "plugin mount", got: shopware.Volumes, want: []string{"./custom/plugins:/var/www/html/custom/plugins"}},
+		{name: "dependencies", got: shopware.DependsOn, want: []string{"database"}},
+		{name: "database image", got: database.Image, want: DefaultDatabaseImage},
+		{name: "database container", got: database.ContainerName, want: "dev_shopware_database"},
+		{name: "database password", got: database.Environment["MYSQL_PASSWORD"], want: "secret"},
+		{name: "volumes", got: len(compose.Volumes), want: 1},
+	} {
+		if !reflect.DeepEqual(check.got, check.want) {
+			t.Errorf("%v: got %v, want %v", check.name, check.got, check.want)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+// Package rackshopinit includes functions to provision new shopware installations for rackjobber
+// and to integrate rackjobber into existing ones
+package rackshopinit
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackfile"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racklog"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshop"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshopstore"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackssh"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackstate"
+)
+
+const (
+	// consoleCheckAttempts is the number of times the shopware console is checked, before the init fails
+	consoleCheckAttempts = 30
+	// consoleCheckInterval is the time between two checks of the shopware console
+	consoleCheckInterval = 10 * time.Second
+)
+
+// Options contains the configuration of a new shopware installation
+type Options struct {
+	// Shop is the shop that will be added to the shop store.
+	// Its ShopwareDir is the directory the docker-compose.yml is created in.
+	Shop          rackshop.RackShop
+	Image         string
+	DatabaseImage string
+	HTTPPort      string
+	ShopURL       string
+}
+
+// InitShop creates a shopware installation with its database in docker, that is ready to be used by rackjobber.
+// The installation contains a custom/rackfile.yaml and an empty deployment state.
+// After the containers are started, the shopware console is checked and the shop is added to the shop store,
+// once the console answers. A failed init does not leave a shop in the shop store.
+func InitShop(opts Options) error {
+	opts = withDefaults(opts)
+	shop := &opts.Shop
+
+	if err := shop.Validate(); err != nil {
+		return err
+	}
+
+	if shops, err := rackshopstore.SelectShopsFromStore(shop.Name); err == nil && len(shops) > 0 {
+		return errors.New("a shop or group with name " + shop.Name + " already exists")
+	}
+
+	if !rackssh.CheckShopConnection(shop) {
+		return errors.New("unable to connect to remote shop")
+	}
+
+	executor := rackssh.NewExecutor(shop)
+
+	if err := createShopFiles(executor, opts); err != nil {
+		return err
+	}
+
+	racklog.With("shop", shop.Name).Infof("Starting shopware containers on %v.", shop.Name)
+
+	out, err := executor.Run("cd " + shop.ShopwareDir + " && docker-compose up -d")
+	if err != nil {
+		racklog.Errorf("docker-compose up - output: %v\n", out)
+		return err
+	}
+
+	if err = waitForConsole(executor, shop); err != nil {
+		return errors.New(err.Error() + ", the shop was not added to the shop store")
+	}
+
+	if err = rackshopstore.AddShop(*shop); err != nil {
+		return err
+	}
+
+	racklog.With("shop", shop.Name).Infof("Added shop %v to the shop store.", shop.Name)
+
+	return nil
+}
+
+//withDefaults fills all empty options with their default values
+func withDefaults(opts Options) Options {
+	if len(opts.Image) == 0 {
+		opts.Image = DefaultShopwareImage
+	}
+
+	if len(opts.DatabaseImage) == 0 {
+		opts.DatabaseImage = DefaultDatabaseImage
+	}
+
+	if len(opts.HTTPPort) == 0 {
+		opts.HTTPPort = "80"
+	}
+
+	if len(opts.ShopURL) == 0 {
+		opts.ShopURL = "http://" + opts.Shop.Address
+		if opts.Shop.Local {
+			opts.ShopURL = "http://localhost"
+		}
+
+		if opts.HTTPPort != "80" {
+			opts.ShopURL += ":" + opts.HTTPPort
+		}
+	}
+
+	if len(opts.Shop.Container) == 0 {
+		opts.Shop.Container = opts.Shop.Name + "_shopware"
+	}
+
+	return opts
+}
+
+//createShopFiles creates the docker-compose.yml, the plugin directory and the rackjobber files of a new shop
+func createShopFiles(executor rackssh.Executor, opts Options) error {
+	shopwareDir := opts.Shop.ShopwareDir
+	customDir := filepath.Join(shopwareDir, "custom")
+
+	if out, err := executor.Run("mkdir -p " + filepath.Join(customDir, "plugins")); err != nil {
+		racklog.Errorf("mkdir - output: %v\n", out)
+		return err
+	}
+
+	databasePassword, err := generatePassword()
+	if err != nil {
+		return err
+	}
+
+	compose, err := createDockerCompose(opts, databasePassword)
+	if err != nil {
+		return err
+	}
+
+	if err = executor.WriteFile(filepath.Join(shopwareDir, "docker-compose.yml"), compose); err != nil {
+		return err
+	}
+
+	rackFileData, err := rackfile.RackFile{Plugins: []string{}, Themes: []rackfile.ThemeEntry{}}.MarshalRackFile()
+	if err != nil {
+		return err
+	}
+
+	if err = executor.WriteFile(filepath.Join(shopwareDir, rackFilePath), *rackFileData); err != nil {
+		return err
+	}
+
+	return rackstate.WriteShopState(executor, &opts.Shop, rackstate.NewState())
+}
+
+//waitForConsole checks the shopware console of the shop until it answers, as the installation takes a while
+func waitForConsole(executor rackssh.Executor, shop *rackshop.RackShop) error {
+	racklog.With("shop", shop.Name).Infof("Waiting for the shopware console to become available.")
+
+	var out string
+
+	var err error
+
+	for attempt := 1; attempt <= consoleCheckAttempts; attempt++ {
+		out, err = executor.Run(rackssh.ConsoleCommand(shop, "sw:plugin:list"))
+		if err == nil {
+			racklog.With("shop", shop.Name).Infof("Shopware console is reachable, the shop is ready to be used with rackjobber.")
+			return nil
+		}
+
+		time.Sleep(consoleCheckInterval)
+	}
+
+	racklog.Errorf("shopware console - output: %v\n", out)
+
+	return fmt.Errorf("shopware console of %v not reachable: %v", shop.Name, err)
+}
@@ -0,0 +1,234 @@
+package rackshopinit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/gitutil"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackfile"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackinspect"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racklog"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshopstore"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackssh"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackstate"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/repository"
+)
+
+const rackFilePath = "custom/rackfile.yaml"
+
+// Flags of a rackfile entry, that reflect the state of an integrated plugin
+const (
+	flagNoInstall  = "noinstall"
+	flagNoActivate = "noactivate"
+)
+
+// integratedPlugin is a plugin of an existing installation, that could be matched to a rackspec
+type integratedPlugin struct {
+	entry string
+	state rackstate.PluginState
+	// outdated is set, if the checkout is not on the commit of the rackspec's version tag
+	outdated bool
+}
+
+// IntegrateShop integrates rackjobber into an existing shopware installation of a shop in the shop store.
+// The plugins in custom/plugins are matched to the rackspecs of the local repositories by their version
+// and a custom/rackfile.yaml and a deployment state are created, that describe the current state,
+// so the first up does not change the matched plugins.
+// Plugins without a matching rackspec are written as unmanaged entries, so up does not delete them.
+func IntegrateShop(shopName string, force bool) error {
+	shop, err := rackshopstore.GetShopFromStore(shopName)
+	if err != nil {
+		return err
+	}
+
+	executor := rackssh.NewExecutor(shop)
+
+	if _, err := executor.ReadFile(filepath.Join(shop.ShopwareDir, rackFilePath)); err == nil && !force {
+		return errors.New("the shop already contains a rackfile, use --force to override it")
+	}
+
+	plugins, err := rackinspect.InspectPlugins(executor, shop)
+	if err != nil {
+		return err
+	}
+
+	pluginStates, err := rackinspect.ReadPluginStates(executor, shop)
+	if err != nil {
+		return err
+	}
+
+	rackFile := rackfile.RackFile{Plugins: []string{}, Themes: []rackfile.ThemeEntry{}}
+	state := rackstate.NewState()
+	state.RunID = rackstate.NewRunID()
+	unmatched := map[string]error{}
+	outdated := []string{}
+
+	for _, plugin := range plugins {
+		pluginState, known := pluginStates[plugin.Name]
+
+		integrated, err := integratePlugin(plugin, pluginState, known, state.RunID)
+		if err != nil {
+			unmatched[plugin.Name] = err
+			rackFile.Plugins = append(rackFile.Plugins,
+				rackfile.Entry{Name: plugin.Name, Flag: rackfile.FlagUnmanaged}.String())
+
+			continue
+		}
+
+		rackFile.Plugins = append(rackFile.Plugins, integrated.entry)
+		state.SetPlugin(integrated.state)
+
+		if integrated.outdated {
+			outdated = append(outdated, plugin.Name)
+		}
+	}
+
+	rackFileData, err := rackFile.MarshalRackFile()
+	if err != nil {
+		return err
+	}
+
+	if err = rackssh.WriteFileToShop(shop, rackFilePath, *rackFileData); err != nil {
+		return err
+	}
+
+	state.UpdatedAt = time.Now().UTC()
+
+	if err = rackstate.WriteShopState(executor, shop, state); err != nil {
+		return err
+	}
+
+	printIntegrationReport(rackFile.Plugins, outdated, unmatched)
+
+	return nil
+}
+
+//integratePlugin matches an installed plugin to a rackspec and returns its rackfile entry and state.
+//Plugins, that are not installed or not active in shopware, get the noinstall or noactivate flag.
+func integratePlugin(plugin rackinspect.InstalledPlugin, pluginState rackinspect.PluginState, known bool,
+	runID string) (*integratedPlugin, error) {
+	match, err := rackinspect.MatchRackSpec(plugin)
+	if err != nil {
+		return nil, err
+	}
+
+	tagHash, err := gitutil.GetHashOfLastCommit(context.Background(), match.Spec.Source.GIT, match.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	specCommit, err := repository.GetRepoHeadCommit(match.Repo)
+	if err != nil {
+		return nil, errors.New("failed to read the commit of repository " + match.Repo + ": " + err.Error())
+	}
+
+	return newIntegratedPlugin(plugin, pluginState, known, *match, *tagHash, specCommit, runID), nil
+}
+
+//newIntegratedPlugin returns the rackfile entry and the state of a plugin matched to the version of a rackspec,
+//whose tag points at tagHash. The state records the checked out commit, so up only deploys the plugin,
+//if the checkout is not on the commit of the tag.
+func newIntegratedPlugin(plugin rackinspect.InstalledPlugin, pluginState rackinspect.PluginState, known bool,
+	match rackinspect.SpecMatch, tagHash string, specCommit string, runID string) *integratedPlugin {
+	deployedBy, deployedFrom := rackstate.Deployer()
+	entry := rackfile.Entry{Name: plugin.Name, Version: match.Version}
+
+	switch {
+	case !known || !pluginState.Installed:
+		entry.Flag = flagNoInstall
+	case !pluginState.Active:
+		entry.Flag = flagNoActivate
+	}
+
+	return &integratedPlugin{
+		entry: entry.String(),
+		state: rackstate.PluginState{
+			Name:         plugin.Name,
+			Version:      plugin.Version,
+			Tag:          match.Version,
+			Commit:       plugin.Commit,
+			Flag:         entry.Flag,
+			Source:       plugin.Origin,
+			SpecRepo:     match.Repo,
+			SpecCommit:   specCommit,
+			Installed:    pluginState.Installed,
+			Activated:    pluginState.Active,
+			Result:       rackstate.ResultIntegrated,
+			DeployedAt:   time.Now().UTC(),
+			DeployedBy:   deployedBy,
+			DeployedFrom: deployedFrom,
+			RunID:        runID,
+		},
+		outdated: tagHash != plugin.Commit || plugin.Modified,
+	}
+}
+
+func printIntegrationReport(entries []string, outdated []string, unmatched map[string]error) {
+	fmt.Println("Created " + rackFilePath + " with the plugins:")
+
+	for _, entry := range entries {
+		fmt.Println(" - " + entry)
+	}
+
+	if len(outdated) > 0 {
+		fmt.Println("These plugins are not on the commit of their version tag and will be updated on the next up:")
+
+		for _, name := range outdated {
+			fmt.Println(" - " + name)
+		}
+	}
+
+	if len(unmatched) > 0 {
+		fmt.Println("These plugins could not be matched to a rackspec and are kept as unmanaged plugins, " +
+			"that up neither updates nor deletes, until a rackspec is added and their entry gets a version:")
+
+		for name, err := range unmatched {
+			fmt.Printf(" - %v: %v\n", name, err)
+		}
+	}
+}
+
+// DeintegrateShop removes the rackjobber files from a shop, the plugins are left in place.
+// If stripGit is set, the git metadata of all plugins is removed as well.
+func DeintegrateShop(shopName string, stripGit bool) error {
+	shop, err := rackshopstore.GetShopFromStore(shopName)
+	if err != nil {
+		return err
+	}
+
+	executor := rackssh.NewExecutor(shop)
+
+	command := "rm -rf " + filepath.Join(shop.ShopwareDir, rackFilePath) + " " +
+		filepath.Join(shop.ShopwareDir, rackstate.StateDir) + " " + filepath.Join(shop.ShopwareDir, rackstate.LegacyHashFile)
+	if out, err := executor.Run(command); err != nil {
+		racklog.With("shop", shop.Name).Errorf("%v", out)
+		return err
+	}
+
+	racklog.With("shop", shop.Name).Infof("Removed the rackjobber files from %v.", shop.Name)
+
+	if !stripGit {
+		return nil
+	}
+
+	plugins, err := rackinspect.ListPluginDirs(executor, shop)
+	if err != nil {
+		return err
+	}
+
+	for _, plugin := range plugins {
+		gitDir := filepath.Join(shop.ShopwareDir, rackinspect.PluginDir, plugin, ".git")
+
+		if out, err := executor.Run("rm -rf " + gitDir); err != nil {
+			racklog.With("shop", shop.Name).With("plugin", plugin).Errorf("%v", out)
+			return err
+		}
+
+		racklog.With("shop", shop.Name).With("plugin", plugin).Infof("Removed git metadata of %v.", plugin)
+	}
+
+	return nil
+}
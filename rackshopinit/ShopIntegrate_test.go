@@ -0,0 +1,77 @@
+package rackshopinit
+
+import (
+	"testing"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackfile"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackinspect"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackstate"
+)
+
+func TestNewIntegratedPlugin(t *testing.T) {
+	match := rackinspect.SpecMatch{Repo: "master", Version: "1.2.0"}
+	installed := rackinspect.PluginState{Name: "SwagPlugin", Installed: true, Active: true}
+
+	tests := []struct {
+		name         string
+		plugin       rackinspect.InstalledPlugin
+		state        rackinspect.PluginState
+		known        bool
+		wantEntry    string
+		wantOutdated bool
+	}{
+		{name: "on the tag", plugin: rackinspect.InstalledPlugin{Name: "SwagPlugin", Commit: "abc"},
+			state: installed, known: true, wantEntry: "SwagPlugin:1.2.0"},
+		{name: "other commit", plugin: rackinspect.InstalledPlugin{Name: "SwagPlugin", Commit: "def"},
+			state: installed, known: true, wantEntry: "SwagPlugin:1.2.0", wantOutdated: true},
+		{name: "modified checkout", plugin: rackinspect.InstalledPlugin{Name: "SwagPlugin", Commit: "abc", Modified: true},
+			state: installed, known: true, wantEntry: "SwagPlugin:1.2.0", wantOutdated: true},
+		{name: "inactive", plugin: rackinspect.InstalledPlugin{Name: "SwagPlugin", Commit: "abc"},
+			state: rackinspect.PluginState{Installed: true}, known: true, wantEntry: "SwagPlugin:1.2.0:noactivate"},
+		{name: "not installed", plugin: rackinspect.InstalledPlugin{Name: "SwagPlugin", Commit: "abc"},
+			state: rackinspect.PluginState{}, known: true, wantEntry: "SwagPlugin:1.2.0:noinstall"},
+		{name: "unknown to shopware", plugin: rackinspect.InstalledPlugin{Name: "SwagPlugin", Commit: "abc"},
+			wantEntry: "SwagPlugin:1.2.0:noinstall"},
+	}
+
+	for _, test := range tests {
+		integrated := newIntegratedPlugin(test.plugin, test.state, test.known, match, "abc", "spec", "run")
+
+		if integrated.entry != test.wantEntry || integrated.outdated != test.wantOutdated {
+			t.Errorf("%v: got entry %q, outdated %v, want %q, %v", test.name, integrated.entry, integrated.outdated,
+				test.wantEntry, test.wantOutdated)
+		}
+
+		entry := rackfile.ParseEntry(integrated.entry)
+		if integrated.state.Tag != entry.Version || integrated.state.Flag != entry.Flag ||
+			integrated.state.SpecRepo != "master" || integrated.state.SpecCommit != "spec" ||
+			integrated.state.Result != rackstate.ResultIntegrated {
+			t.Errorf("%v: the state %+v does not match the entry %q", test.name, integrated.state, integrated.entry)
+		}
+	}
+}
+
+// TestIntegratedPluginIsUpToDate checks, that the first up after the integration does not deploy a plugin,
+// that is checked out on the commit of its version tag, as up compares that commit with the state
+func TestIntegratedPluginIsUpToDate(t *testing.T) {
+	match := rackinspect.SpecMatch{Repo: "master", Version: "1.2.0"}
+	pluginState := rackinspect.PluginState{Name: "SwagPlugin", Installed: true, Active: true}
+
+	for _, commit := range []string{"abc", "def"} {
+		plugin := rackinspect.InstalledPlugin{Name: "SwagPlugin", IsGit: true, Commit: commit, Tag: "v1.2.0"}
+		integrated := newIntegratedPlugin(plugin, pluginState, true, match, "abc", "spec", "run")
+
+		state := rackstate.NewState()
+		state.SetPlugin(integrated.state)
+
+		deployedCommit, err := state.GetCommit("SwagPlugin")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if upToDate := deployedCommit == "abc"; upToDate == integrated.outdated {
+			t.Errorf("checkout on %v: up-to-date for up %v, reported as outdated %v", commit, upToDate,
+				integrated.outdated)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package rackshopstore
+
+import (
+	"errors"
+	"strings"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshop"
+)
+
+// Keys that can be used inside of a selector expression
+const (
+	SelectorKeyName        = "name"
+	SelectorKeyEnvironment = "env"
+	SelectorKeyTag         = "tag"
+	SelectorKeyGroup       = "group"
+)
+
+// Selector is a parsed selector expression like "env=staging,tag=b2b".
+// A shop matches the selector, if it matches all of its requirements.
+type Selector struct {
+	Requirements []Requirement
+}
+
+// Requirement is a single "key=value" or "key!=value" part of a selector expression
+type Requirement struct {
+	Key    string
+	Value  string
+	Negate bool
+}
+
+// ParseSelector parses a selector expression.
+// Requirements are separated by commas, every requirement has the form key=value or key!=value.
+func ParseSelector(expression string) (*Selector, error) {
+	selector := &Selector{}
+
+	for _, part := range strings.Split(expression, ",") {
+		part = strings.TrimSpace(part)
+		if len(part) == 0 {
+			continue
+		}
+
+		requirement, err := parseRequirement(part)
+		if err != nil {
+			return nil, err
+		}
+
+		selector.Requirements = append(selector.Requirements, *requirement)
+	}
+
+	if len(selector.Requirements) == 0 {
+		return nil, errors.New("empty selector")
+	}
+
+	return selector, nil
+}
+
+//parseRequirement parses a single requirement of a selector expression
+func parseRequirement(part string) (*Requirement, error) {
+	requirement := &Requirement{}
+	separator := "="
+
+	if strings.Contains(part, "!=") {
+		requirement.Negate = true
+		separator = "!="
+	}
+
+	split := strings.SplitN(part, separator, 2)
+	if len(split) != 2 {
+		return nil, errors.New("invalid selector requirement: " + part)
+	}
+
+	requirement.Key = strings.TrimSpace(split[0])
+	requirement.Value = strings.TrimSpace(split[1])
+
+	if requirement.Key == "environment" {
+		requirement.Key = SelectorKeyEnvironment
+	}
+
+	switch requirement.Key {
+	case SelectorKeyName, SelectorKeyEnvironment, SelectorKeyTag, SelectorKeyGroup:
+	default:
+		return nil, errors.New("unknown selector key: " + requirement.Key)
+	}
+
+	if len(requirement.Value) == 0 {
+		return nil, errors.New("missing value for selector key: " + requirement.Key)
+	}
+
+	return requirement, nil
+}
+
+// IsSelectorExpression checks if the given expression is a selector rather than a plain shop or group name
+func IsSelectorExpression(expression string) bool {
+	return strings.Contains(expression, "=")
+}
+
+// Matches checks if a shop fulfills all requirements of the selector.
+// The store is needed to resolve group requirements.
+func (s Selector) Matches(shop rackshop.RackShop, store ShopStore) bool {
+	for _, requirement := range s.Requirements {
+		if requirement.matches(shop, store) == requirement.Negate {
+			return false
+		}
+	}
+
+	return true
+}
+
+//hasKey checks if the selector contains a requirement for the given key
+func (s Selector) hasKey(key string) bool {
+	for _, requirement := range s.Requirements {
+		if requirement.Key == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+//matches checks if a shop has the value of the requirement, ignoring its negation
+func (r Requirement) matches(shop rackshop.RackShop, store ShopStore) bool {
+	switch r.Key {
+	case SelectorKeyName:
+		return shop.Name == r.Value
+	case SelectorKeyEnvironment:
+		return shop.Environment == r.Value
+	case SelectorKeyTag:
+		return shop.HasTag(r.Value)
+	case SelectorKeyGroup:
+		group, err := store.GetGroupForName(r.Value)
+		if err != nil {
+			return false
+		}
+
+		return store.isShopInGroup(shop, *group)
+	}
+
+	return false
+}
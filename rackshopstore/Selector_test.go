@@ -0,0 +1,91 @@
+package rackshopstore
+
+import (
+	"reflect"
+	"testing"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshop"
+)
+
+func TestParseSelector(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       []Requirement
+		wantErr    bool
+	}{
+		{expression: "env=staging", want: []Requirement{{Key: "env", Value: "staging"}}},
+		{expression: " environment = staging , tag!=b2b,", want: []Requirement{
+			{Key: "env", Value: "staging"}, {Key: "tag", Value: "b2b", Negate: true}}},
+		{expression: "name=my-shop,group=eu", want: []Requirement{{Key: "name", Value: "my-shop"},
+			{Key: "group", Value: "eu"}}},
+		{expression: "", wantErr: true},
+		{expression: " , ", wantErr: true},
+		{expression: "staging", wantErr: true},
+		{expression: "region=eu", wantErr: true},
+		{expression: "tag=", wantErr: true},
+	}
+
+	for _, test := range tests {
+		selector, err := ParseSelector(test.expression)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("ParseSelector(%q): expected an error", test.expression)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("ParseSelector(%q): unexpected error: %v", test.expression, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(selector.Requirements, test.want) {
+			t.Errorf("ParseSelector(%q) = %+v, want %+v", test.expression, selector.Requirements, test.want)
+		}
+	}
+}
+
+func TestSelectorMatches(t *testing.T) {
+	shop := rackshop.RackShop{Name: "my-shop", Environment: "staging", Tags: []string{"b2b", "eu"}}
+
+	store := ShopStore{
+		Shops: []rackshop.RackShop{shop},
+		Groups: []ShopGroup{
+			{Name: "listed", Shops: []string{"my-shop"}},
+			{Name: "selected", Selector: "env=staging,tag=eu"},
+			{Name: "production", Selector: "env=production"},
+			{Name: "nested", Selector: "group=listed"},
+		},
+	}
+
+	tests := []struct {
+		expression string
+		matches    bool
+	}{
+		{expression: "name=my-shop", matches: true},
+		{expression: "name=other-shop", matches: false},
+		{expression: "env=staging,tag=b2b", matches: true},
+		{expression: "env=staging,tag=b2c", matches: false},
+		{expression: "tag!=b2c", matches: true},
+		{expression: "env!=staging", matches: false},
+		{expression: "group=listed", matches: true},
+		{expression: "group=selected", matches: true},
+		{expression: "group=production", matches: false},
+		{expression: "group!=production", matches: true},
+		{expression: "group=nested", matches: false},
+		{expression: "group=missing", matches: false},
+	}
+
+	for _, test := range tests {
+		selector, err := ParseSelector(test.expression)
+		if err != nil {
+			t.Errorf("ParseSelector(%q): unexpected error: %v", test.expression, err)
+			continue
+		}
+
+		if matches := selector.Matches(shop, store); matches != test.matches {
+			t.Errorf("%q matches the shop: %v, want %v", test.expression, matches, test.matches)
+		}
+	}
+}
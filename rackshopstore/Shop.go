@@ -3,23 +3,32 @@ package rackshopstore
 
 import (
 	"errors"
-	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/fileutil"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racklog"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racknotify"
 	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshop"
 	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackssh"
 )
 
+const (
+	// shopStoreLockTimeout is the maximum time to wait for another process to finish writing the shop store
+	shopStoreLockTimeout = 30 * time.Second
+	// shopStoreLockStaleAfter is the age after which a lock of the shop store is considered abandoned
+	shopStoreLockStaleAfter = 5 * time.Minute
+)
+
 func getShopStorePath() (*string, error) {
-	resPath, err := fileutil.GetAppFolderPath()
+	configPath, err := fileutil.GetConfigFolderPath()
 	if err != nil {
 		return nil, err
 	}
 
-	storePath := filepath.Join(*resPath, "shopstore.yaml")
+	storePath := filepath.Join(*configPath, "shopstore.yaml")
 
 	return &storePath, nil
 }
@@ -72,34 +81,82 @@ func readOrCreateShopStore() (*ShopStore, error) {
 	return shopStore, nil
 }
 
-func appendShopToStore(shop rackshop.RackShop) error {
-	shopStore, err := readOrCreateShopStore()
+//modifyShopStore applies a modification to the shop store while holding the lock of the shopstore.yaml.
+//The modified store is written atomically, so concurrent rackjobber processes can not corrupt it.
+//If create is set, a missing shop store is created, otherwise an error is returned.
+func modifyShopStore(create bool, modify func(shopStore *ShopStore) error) error {
+	storePath, err := getShopStorePath()
 	if err != nil {
 		return err
 	}
 
-	shopStore.Shops = append(shopStore.Shops, shop)
+	lock, err := fileutil.LockFile(*storePath, shopStoreLockTimeout, shopStoreLockStaleAfter)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := lock.Unlock(); err != nil {
+			racklog.Warnf("lock.Unlock - error: %v\n", err)
+		}
+	}()
+
+	var shopStore *ShopStore
+	if create {
+		shopStore, err = readOrCreateShopStore()
+	} else {
+		shopStore, err = getShopStore()
+	}
 
-	data, err := shopStore.MarshalShopStore()
 	if err != nil {
 		return err
 	}
 
-	storePath, err := getShopStorePath()
+	if err = modify(shopStore); err != nil {
+		return err
+	}
+
+	if len(shopStore.Shops) == 0 && len(shopStore.Groups) == 0 {
+		return os.Remove(*storePath)
+	}
+
+	data, err := shopStore.MarshalShopStore()
 	if err != nil {
 		return err
 	}
 
-	err = fileutil.CreateOrWriteFile(*storePath, *data)
+	return fileutil.WriteFileAtomic(*storePath, *data)
+}
+
+func appendShopToStore(shop rackshop.RackShop) error {
+	return modifyShopStore(true, func(shopStore *ShopStore) error {
+		if shopStore.hasShop(shop.Name) {
+			return errors.New("a shop with name " + shop.Name + " already exists")
+		}
+
+		shopStore.Shops = append(shopStore.Shops, shop)
+
+		return nil
+	})
+}
+
+//proveShopNameIsUnused returns an error if a shop with the given name already exists,
+//so the user does not have to wait for the connection check in this case
+func proveShopNameIsUnused(name string) error {
+	shopStore, err := readOrCreateShopStore()
 	if err != nil {
 		return err
 	}
 
+	if shopStore.hasShop(name) {
+		return errors.New("a shop with name " + name + " already exists")
+	}
+
 	return nil
 }
 
 func proveRemoteShopConnection(shop rackshop.RackShop) bool {
-	return rackssh.CheckConnection(shop.Address, shop.GetRemoteConfig())
+	return rackssh.CheckShopConnection(&shop)
 }
 
 // AddShopWithFile will add a shop based on a file where the informations are available
@@ -109,10 +166,18 @@ func AddShopWithFile(path string) error {
 		return err
 	}
 
+	if err = rackShop.Validate(); err != nil {
+		return err
+	}
+
+	if err = proveShopNameIsUnused(rackShop.Name); err != nil {
+		return err
+	}
+
 	connected := proveRemoteShopConnection(*rackShop)
 
 	if !connected {
-		fmt.Println("Could not connect to shop, shop will not be added to store")
+		racklog.Errorf("Could not connect to shop, shop will not be added to store")
 		return errors.New("unable to connect to remote shop")
 	}
 
@@ -125,20 +190,19 @@ func AddShopWithFile(path string) error {
 }
 
 // AddShop will add a shop to rackjobber based on the passed flags
-func AddShop(name string, address string, user string, password string, sdir string, container string) error {
-	rackShop := rackshop.RackShop{
-		Name:        name,
-		Address:     address,
-		User:        user,
-		Password:    password,
-		ShopwareDir: sdir,
-		Container:   container,
+func AddShop(rackShop rackshop.RackShop) error {
+	if err := rackShop.Validate(); err != nil {
+		return err
+	}
+
+	if err := proveShopNameIsUnused(rackShop.Name); err != nil {
+		return err
 	}
 
 	connected := proveRemoteShopConnection(rackShop)
 
 	if !connected {
-		fmt.Println("Could not connect to shop, shop will not be added to store")
+		racklog.Errorf("Could not connect to shop, shop will not be added to store")
 		return errors.New("unable to connect to remote shop")
 	}
 
@@ -160,54 +224,245 @@ func GetShopFromStore(name string) (*rackshop.RackShop, error) {
 	return shopStore.GetShopForName(name)
 }
 
-// RemoveShopFromStore will remove a shop with a given name from the shop store
+// RemoveShopFromStore will remove a shop with a given name from the shop store and from the groups listing it.
+// Groups, that have neither shops nor a selector left, are removed as well, so they can not select nothing.
 func RemoveShopFromStore(name string) error {
-	shopStore, err := getShopStore()
+	return modifyShopStore(false, func(shopStore *ShopStore) error {
+		indexToRemove := -1
+
+		for index, shop := range shopStore.Shops {
+			if strings.Compare(shop.Name, name) == 0 {
+				indexToRemove = index
+			}
+		}
+
+		if indexToRemove == -1 {
+			racklog.Errorf("Could not find shop with name: %v\n", name)
+			return errors.New("shop not found")
+		}
+
+		shops := shopStore.Shops
+
+		shops[indexToRemove] = shops[len(shops)-1]
+		shopStore.Shops = shops[:len(shops)-1]
+
+		groups := []ShopGroup{}
+
+		for _, group := range shopStore.Groups {
+			group.Shops = removeName(group.Shops, name)
+
+			if len(group.Shops) == 0 && group.Selector == "" {
+				racklog.Infof("Removed the group %v, as %v was its last shop.", group.Name, name)
+				continue
+			}
+
+			groups = append(groups, group)
+		}
+
+		shopStore.Groups = groups
+
+		return nil
+	})
+}
+
+// EditShopInStore will apply the changes of edit to the shop with a given name.
+// The name of a shop can not be changed by edit, use RenameShopInStore instead.
+func EditShopInStore(name string, edit func(shop *rackshop.RackShop)) error {
+	return modifyShopStore(false, func(shopStore *ShopStore) error {
+		for i := range shopStore.Shops {
+			if shopStore.Shops[i].Name != name {
+				continue
+			}
+
+			edit(&shopStore.Shops[i])
+			shopStore.Shops[i].Name = name
+
+			return shopStore.Shops[i].Validate()
+		}
+
+		racklog.Errorf("Could not find shop with name: %v\n", name)
+
+		return errors.New("shop not found")
+	})
+}
+
+// RenameShopInStore will rename a shop and update the groups it is listed in
+func RenameShopInStore(name string, newName string) error {
+	if len(newName) == 0 {
+		return errors.New("the new name of the shop must not be empty")
+	}
+
+	return modifyShopStore(false, func(shopStore *ShopStore) error {
+		if shopStore.hasShop(newName) {
+			return errors.New("a shop with name " + newName + " already exists")
+		}
+
+		if !shopStore.hasShop(name) {
+			racklog.Errorf("Could not find shop with name: %v\n", name)
+			return errors.New("shop not found")
+		}
+
+		for i := range shopStore.Shops {
+			if shopStore.Shops[i].Name == name {
+				shopStore.Shops[i].Name = newName
+			}
+		}
+
+		for i, group := range shopStore.Groups {
+			for j, shopName := range group.Shops {
+				if shopName == name {
+					shopStore.Groups[i].Shops[j] = newName
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// ProveShopConnection checks if the shop with a given name is reachable via ssh
+func ProveShopConnection(name string) error {
+	shop, err := GetShopFromStore(name)
 	if err != nil {
 		return err
 	}
 
-	indexToRemove := -1
+	if !proveRemoteShopConnection(*shop) {
+		return errors.New("unable to connect to remote shop")
+	}
+
+	return nil
+}
+
+// ListShopsFromStore returns all shops that are currently configured in the shop store
+func ListShopsFromStore() (*[]rackshop.RackShop, error) {
+	shopStore, err := getShopStore()
+	if err != nil {
+		return nil, err
+	}
+
+	return &shopStore.Shops, nil
+}
+
+// SelectShopsFromStore returns all shops that are addressed by the given expression.
+// The expression may be the name of a shop, the name of a group or a selector like "env=staging,tag=b2b".
+func SelectShopsFromStore(expression string) ([]rackshop.RackShop, error) {
+	shopStore, err := getShopStore()
+	if err != nil {
+		return nil, err
+	}
 
-	for index, shop := range shopStore.Shops {
-		if strings.Compare(shop.Name, name) == 0 {
-			indexToRemove = index
+	if IsSelectorExpression(expression) {
+		selector, err := ParseSelector(expression)
+		if err != nil {
+			return nil, err
 		}
+
+		return shopStore.SelectShops(*selector), nil
 	}
 
-	if indexToRemove == -1 {
-		fmt.Printf("Could not find shop with name: %v\n", name)
-		return errors.New("shop not found")
+	if shopStore.hasShop(expression) {
+		shop, err := shopStore.GetShopForName(expression)
+		if err != nil {
+			return nil, err
+		}
+
+		return []rackshop.RackShop{*shop}, nil
 	}
 
-	shops := shopStore.Shops
+	if _, err := shopStore.GetGroupForName(expression); err == nil {
+		return shopStore.GetShopsForGroup(expression)
+	}
 
-	shops[indexToRemove] = shops[len(shops)-1]
-	shopStore.Shops = shops[:len(shops)-1]
+	return nil, errors.New("no shop or group found for: " + expression)
+}
 
-	storePath, err := getShopStorePath()
-	if err != nil {
-		return nil
+// AddGroupToStore will add a group of shops to the shop store, or replace the group with the same name
+func AddGroupToStore(group ShopGroup) error {
+	if len(group.Shops) == 0 && group.Selector == "" {
+		return errors.New("a group needs shops or a selector")
 	}
 
-	if len(shopStore.Shops) == 0 {
-		return os.Remove(*storePath)
+	if err := racknotify.ValidateTargets(group.Notifications); err != nil {
+		return err
 	}
 
-	data, err := shopStore.MarshalShopStore()
-	if err != nil {
+	if group.Selector != "" {
+		selector, err := ParseSelector(group.Selector)
+		if err != nil {
+			return err
+		}
+
+		if selector.hasKey(SelectorKeyGroup) {
+			return errors.New("the selector of a group must not reference other groups")
+		}
+	}
+
+	return modifyShopStore(true, func(shopStore *ShopStore) error {
+		for _, name := range group.Shops {
+			if !shopStore.hasShop(name) {
+				return errors.New("shop " + name + " not found")
+			}
+		}
+
+		for i, existing := range shopStore.Groups {
+			if existing.Name == group.Name {
+				shopStore.Groups[i] = group
+				return nil
+			}
+		}
+
+		shopStore.Groups = append(shopStore.Groups, group)
+
 		return nil
+	})
+}
+
+// RemoveGroupFromStore will remove the group with a given name from the shop store
+func RemoveGroupFromStore(name string) error {
+	return modifyShopStore(false, func(shopStore *ShopStore) error {
+		for i, group := range shopStore.Groups {
+			if group.Name == name {
+				shopStore.Groups = append(shopStore.Groups[:i], shopStore.Groups[i+1:]...)
+				return nil
+			}
+		}
+
+		racklog.Errorf("Could not find group with name: %v\n", name)
+
+		return errors.New("group not found")
+	})
+}
+
+// ListGroupsOfShop returns all groups, that list the shop or match it with their selector
+func ListGroupsOfShop(shop rackshop.RackShop) ([]ShopGroup, error) {
+	shopStore, err := getShopStore()
+	if err != nil {
+		return nil, err
 	}
 
-	return fileutil.CreateOrWriteFile(*storePath, *data)
+	return shopStore.GetGroupsOfShop(shop), nil
 }
 
-// ListShopsFromStore returns all shops that are currently configured in the shop store
-func ListShopsFromStore() (*[]rackshop.RackShop, error) {
+// ListGroupsFromStore returns all groups that are currently configured in the shop store
+func ListGroupsFromStore() ([]ShopGroup, error) {
 	shopStore, err := getShopStore()
 	if err != nil {
 		return nil, err
 	}
 
-	return &shopStore.Shops, nil
+	return shopStore.Groups, nil
+}
+
+//removeName returns the given names without the name to remove
+func removeName(names []string, name string) []string {
+	remaining := []string{}
+
+	for _, existing := range names {
+		if existing != name {
+			remaining = append(remaining, existing)
+		}
+	}
+
+	return remaining
 }
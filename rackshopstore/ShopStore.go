@@ -1,17 +1,30 @@
 package rackshopstore
 
 import (
+	"errors"
 	"io/ioutil"
-	"log"
 	"strings"
 
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racklog"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racknotify"
 	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshop"
 	"gopkg.in/yaml.v2"
 )
 
 // ShopStore struct that defines the structure for the shopStore.yaml
 type ShopStore struct {
-	Shops []rackshop.RackShop
+	Shops  []rackshop.RackShop
+	Groups []ShopGroup `yaml:",omitempty"`
+}
+
+// ShopGroup struct that defines a named group of shops inside of the shopStore.yaml.
+// A group either lists its shops by name, or selects them with a selector expression, or both.
+type ShopGroup struct {
+	Name     string
+	Shops    []string `yaml:",omitempty"`
+	Selector string   `yaml:",omitempty"`
+	// Notifications are notified of every up of the shops of the group
+	Notifications []racknotify.Target `yaml:",omitempty"`
 }
 
 // UnmarshalShopStore will unmarshal a yaml file at a specified path.
@@ -50,7 +63,99 @@ func (s ShopStore) GetShopForName(name string) (*rackshop.RackShop, error) {
 		}
 	}
 
-	log.Fatalf("Shop with name %v not found.", name)
+	racklog.Fatalf("Shop with name %v not found.", name)
 
 	return nil, nil
 }
+
+//hasShop checks if a shop with a specific name exists in the store
+func (s ShopStore) hasShop(name string) bool {
+	for _, shop := range s.Shops {
+		if shop.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetGroupForName will return the group with a specific name
+func (s ShopStore) GetGroupForName(name string) (*ShopGroup, error) {
+	for _, group := range s.Groups {
+		if group.Name == name {
+			return &group, nil
+		}
+	}
+
+	return nil, errors.New("group " + name + " not found")
+}
+
+// GetShopsForGroup will return all shops that are members of the group with a specific name
+func (s ShopStore) GetShopsForGroup(name string) ([]rackshop.RackShop, error) {
+	group, err := s.GetGroupForName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	shops := []rackshop.RackShop{}
+
+	for _, shop := range s.Shops {
+		if s.isShopInGroup(shop, *group) {
+			shops = append(shops, shop)
+		}
+	}
+
+	return shops, nil
+}
+
+// GetGroupsOfShop will return all groups, that list the shop or match it with their selector
+func (s ShopStore) GetGroupsOfShop(shop rackshop.RackShop) []ShopGroup {
+	groups := []ShopGroup{}
+
+	for _, group := range s.Groups {
+		if s.isShopInGroup(shop, group) {
+			groups = append(groups, group)
+		}
+	}
+
+	return groups
+}
+
+// SelectShops will return all shops that match the given selector
+func (s ShopStore) SelectShops(selector Selector) []rackshop.RackShop {
+	shops := []rackshop.RackShop{}
+
+	for _, shop := range s.Shops {
+		if selector.Matches(shop, s) {
+			shops = append(shops, shop)
+		}
+	}
+
+	return shops
+}
+
+//isShopInGroup checks if a shop is listed in the group or matches the group's selector
+func (s ShopStore) isShopInGroup(shop rackshop.RackShop, group ShopGroup) bool {
+	for _, name := range group.Shops {
+		if name == shop.Name {
+			return true
+		}
+	}
+
+	if group.Selector == "" {
+		return false
+	}
+
+	selector, err := ParseSelector(group.Selector)
+	if err != nil {
+		racklog.Warnf("Invalid selector of group %v: %v\n", group.Name, err)
+		return false
+	}
+
+	if selector.hasKey(SelectorKeyGroup) {
+		racklog.Warnf("Selector of group %v must not reference other groups\n", group.Name)
+		return false
+	}
+
+	return selector.Matches(shop, s)
+}
@@ -0,0 +1,200 @@
+package rackshopstore
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/fileutil"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshop"
+)
+
+//useTemporaryHome lets the shop store live in a temporary folder, the returned function removes the folder
+func useTemporaryHome(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "rackjobber")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fileutil.SetHome(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	return func() { _ = os.RemoveAll(dir) }
+}
+
+func TestAppendShopToStore(t *testing.T) {
+	defer useTemporaryHome(t)()
+
+	var wg sync.WaitGroup
+
+	errs := make([]error, 10)
+
+	for i := range errs {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = appendShopToStore(rackshop.RackShop{Name: "shop-" + strconv.Itoa(i)})
+		}(i)
+	}
+
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("adding shop-%v: %v", i, err)
+		}
+	}
+
+	if err := appendShopToStore(rackshop.RackShop{Name: "shop-3"}); err == nil {
+		t.Error("expected adding a shop with a used name to fail")
+	}
+
+	if err := proveShopNameIsUnused("shop-3"); err == nil {
+		t.Error("expected the name of an added shop to be used")
+	}
+
+	store, err := getShopStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(store.Shops) != len(errs) {
+		t.Errorf("the store contains %v shops, want %v", len(store.Shops), len(errs))
+	}
+}
+
+func TestRenameShopInStore(t *testing.T) {
+	defer useTemporaryHome(t)()
+
+	for _, name := range []string{"my-shop", "other-shop"} {
+		if err := appendShopToStore(rackshop.RackShop{Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := modifyShopStore(false, func(shopStore *ShopStore) error {
+		shopStore.Groups = []ShopGroup{{Name: "eu", Shops: []string{"other-shop", "my-shop"}}}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		newName string
+		wantErr bool
+	}{
+		{name: "my-shop", newName: "other-shop", wantErr: true},
+		{name: "missing-shop", newName: "new-shop", wantErr: true},
+		{name: "my-shop", newName: "", wantErr: true},
+		{name: "my-shop", newName: "my-shop-staging"},
+	}
+
+	for _, test := range tests {
+		err := RenameShopInStore(test.name, test.newName)
+		if (err != nil) != test.wantErr {
+			t.Errorf("renaming %v to %q: got error %v, want an error %v", test.name, test.newName, err, test.wantErr)
+		}
+	}
+
+	store, err := getShopStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{}
+	for _, shop := range store.Shops {
+		names = append(names, shop.Name)
+	}
+
+	if want := []string{"my-shop-staging", "other-shop"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got shops %v, want %v", names, want)
+	}
+
+	if want := []string{"other-shop", "my-shop-staging"}; !reflect.DeepEqual(store.Groups[0].Shops, want) {
+		t.Errorf("got group %v, want %v", store.Groups[0].Shops, want)
+	}
+}
+
+func TestEditShopInStore(t *testing.T) {
+	defer useTemporaryHome(t)()
+
+	if err := appendShopToStore(rackshop.RackShop{Name: "my-shop", Environment: "staging"}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := EditShopInStore("my-shop", func(shop *rackshop.RackShop) {
+		shop.Name = "renamed"
+		shop.Environment = ""
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := EditShopInStore("my-shop", func(shop *rackshop.RackShop) { shop.Environment = "qa" }); err == nil {
+		t.Error("expected an invalid environment to be rejected")
+	}
+
+	shop, err := GetShopFromStore("my-shop")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if shop.Environment != "" {
+		t.Errorf("got environment %q, want it to be cleared", shop.Environment)
+	}
+}
+
+func TestRemoveShopFromStore(t *testing.T) {
+	defer useTemporaryHome(t)()
+
+	for _, name := range []string{"my-shop", "other-shop"} {
+		if err := appendShopToStore(rackshop.RackShop{Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := modifyShopStore(false, func(shopStore *ShopStore) error {
+		shopStore.Groups = []ShopGroup{
+			{Name: "single", Shops: []string{"my-shop"}},
+			{Name: "both", Shops: []string{"my-shop", "other-shop"}},
+			{Name: "selected", Shops: []string{"my-shop"}, Selector: "env=staging"},
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RemoveShopFromStore("my-shop"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RemoveShopFromStore("my-shop"); err == nil {
+		t.Error("expected removing a missing shop to fail")
+	}
+
+	store, err := getShopStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	groups := []string{}
+	for _, group := range store.Groups {
+		groups = append(groups, group.Name+":"+strings.Join(group.Shops, ",")+":"+group.Selector)
+	}
+
+	want := []string{"both:other-shop:", "selected::env=staging"}
+
+	if len(store.Shops) != 1 || !reflect.DeepEqual(groups, want) {
+		t.Errorf("got %v shops and groups %v, want 1 shop and %v", len(store.Shops), groups, want)
+	}
+}
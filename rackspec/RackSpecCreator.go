@@ -5,12 +5,13 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/fileutil"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackhook"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racklog"
 
 	"gopkg.in/yaml.v2"
 )
@@ -24,6 +25,10 @@ type RackSpec struct {
 	Compatibility Compatibility `yaml:"compatibility"`
 	Source        Source        `yaml:"source"`
 	Theme         string        `yaml:"theme"`
+	// Requires are the names of the plugins, that have to be deployed together with the plugin by up --only
+	Requires []string `yaml:"requires,omitempty"`
+	// PostInstall are hooks, that run after the plugin is installed, if the rackfile of the shop opts in
+	PostInstall []rackhook.Hook `yaml:"postInstall,omitempty"`
 }
 
 //Source Interface with clone URL
@@ -82,7 +87,7 @@ func CreateDefaultRackSpec(name string) error {
 
 	data, err := yaml.Marshal(&defaultSpec)
 	if err != nil {
-		log.Fatalf("yaml.Marshal - error: %v\n", err)
+		racklog.Fatalf("yaml.Marshal - error: %v\n", err)
 		return err
 	}
 
@@ -91,14 +96,14 @@ func CreateDefaultRackSpec(name string) error {
 	exists, ok := fileutil.ObjectExists(directory)
 
 	if ok != nil {
-		log.Fatalf("dirExists(directory) - error: %v\n", ok)
+		racklog.Fatalf("dirExists(directory) - error: %v\n", ok)
 		return ok
 	}
 
 	if !exists {
 		err := os.MkdirAll(directory, os.ModePerm)
 		if err != nil {
-			log.Fatalf("MkdirAll(directory) - error: %v\n", err)
+			racklog.Fatalf("MkdirAll(directory) - error: %v\n", err)
 		}
 	}
 
@@ -106,18 +111,18 @@ func CreateDefaultRackSpec(name string) error {
 
 	exists, ok = fileutil.ObjectExists(path)
 	if ok != nil {
-		log.Fatalf("dirExists(path) - error: %v\n", ok)
+		racklog.Fatalf("dirExists(path) - error: %v\n", ok)
 		return ok
 	}
 
 	if exists {
-		log.Fatalf("%v already exists! Aborting\n", path)
+		racklog.Fatalf("%v already exists! Aborting\n", path)
 		return errors.New("Template file exists")
 	}
 
 	err = ioutil.WriteFile(path, data, os.ModePerm)
 	if err != nil {
-		log.Fatalf("WriteFile - error: %v\n", err)
+		racklog.Fatalf("WriteFile - error: %v\n", err)
 	}
 
 	return err
@@ -0,0 +1,411 @@
+package rackssh
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	sshrw "github.com/mosolovsa/go_cat_sshfilerw"
+	"golang.org/x/crypto/ssh"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackconfig"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racklog"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackmetrics"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackretry"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshop"
+)
+
+// Executor runs commands and transfers files on the machine a shop is running on
+type Executor interface {
+	// Run executes a shell command and returns its combined output
+	Run(command string) (string, error)
+	// RunContext executes a shell command like Run, but kills it when the context is done.
+	// Run and RunContext kill commands after the command timeout, unless the context has a deadline.
+	RunContext(ctx context.Context, command string) (string, error)
+	// ReadFile returns the content of the file at an absolute path
+	ReadFile(path string) ([]byte, error)
+	// WriteFile creates or overrides the file at an absolute path
+	WriteFile(path string, data []byte) error
+	// CopyFileTo writes the content of the file at an absolute path to w without reading it into memory
+	CopyFileTo(path string, w io.Writer) error
+	// CopyFileFrom creates or overrides the file at an absolute path with the content of r
+	CopyFileFrom(path string, r io.Reader) error
+}
+
+// NewExecutor returns the Executor for a shop with the timeouts and retry policy of the config and the shop.
+// Local shops are managed on the executing machine, all others via ssh.
+// Failing ssh connections are retried, failing commands are not.
+func NewExecutor(shop *rackshop.RackShop) Executor {
+	timeouts := rackconfig.GetTimeouts(shop)
+
+	if shop.Local {
+		return localExecutor{timeouts}
+	}
+
+	return remoteExecutor{shop, timeouts, rackconfig.GetRetryPolicy(shop)}
+}
+
+// WithContext returns an Executor, whose Run executes the commands with the context like RunContext,
+// so helpers, that only call Run, are killed when the context is done
+func WithContext(ctx context.Context, executor Executor) Executor {
+	return contextExecutor{executor, ctx}
+}
+
+//contextExecutor runs the commands of the embedded Executor with its context
+type contextExecutor struct {
+	Executor
+	ctx context.Context
+}
+
+func (e contextExecutor) Run(command string) (string, error) {
+	return e.Executor.RunContext(e.ctx, command)
+}
+
+//withCommandTimeout runs the operation with the command timeout, unless the context has a deadline already
+func withCommandTimeout(ctx context.Context, timeouts rackretry.Timeouts,
+	operation func(ctx context.Context) error) error {
+	if _, ok := ctx.Deadline(); ok {
+		return operation(ctx)
+	}
+
+	return rackretry.WithTimeout(ctx, timeouts.GetCommand(), "the command", operation)
+}
+
+type remoteExecutor struct {
+	shop     *rackshop.RackShop
+	timeouts rackretry.Timeouts
+	retry    rackretry.Policy
+}
+
+func (e remoteExecutor) Run(command string) (string, error) {
+	return e.RunContext(context.Background(), command)
+}
+
+func (e remoteExecutor) RunContext(ctx context.Context, command string) (string, error) {
+	racklog.With("shop", e.shop.Name).Debugf("Running on %v: %v", e.shop.Address, command)
+
+	var out string
+
+	err := withCommandTimeout(ctx, e.timeouts, func(ctx context.Context) error {
+		var err error
+		out, err = e.run(ctx, command)
+
+		return err
+	})
+
+	rackmetrics.AddCommand(len(command), len(out))
+
+	return out, err
+}
+
+//run executes the command in a new ssh session and kills it when the context is done
+func (e remoteExecutor) run(ctx context.Context, command string) (string, error) {
+	var conn *ssh.Client
+
+	err := e.connect(ctx, func() error {
+		var err error
+		conn, err = ssh.Dial("tcp", e.shop.Address+":22", remoteConfig(e.shop, e.timeouts))
+
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	defer conn.Close()
+
+	session, err := conn.NewSession()
+	if err != nil {
+		return "", err
+	}
+
+	defer session.Close()
+
+	var out bytes.Buffer
+
+	session.Stdout = &out
+	session.Stderr = &out
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = session.Signal(ssh.SIGKILL)
+			_ = conn.Close()
+		case <-done:
+		}
+	}()
+
+	err = session.Run(command)
+	if ctx.Err() != nil {
+		return out.String(), ctx.Err()
+	}
+
+	return out.String(), err
+}
+
+//ReadFile connects to the shop and reads the file, both are retried with the retry policy.
+//Reads, that fail because the file can not be read on the shop, are not retried.
+func (e remoteExecutor) ReadFile(path string) ([]byte, error) {
+	var data []byte
+
+	err := rackretry.Do(context.Background(), e.retry, "Reading "+path+" from "+e.shop.Name, func() error {
+		c, err := connectToShop(e.shop, e.timeouts)
+		if err != nil {
+			return stopOnHandshake(err)
+		}
+
+		defer c.Close()
+
+		data, err = readRemoteFile(c, path)
+		if _, ok := err.(*ssh.ExitError); ok {
+			return rackretry.Stop(err)
+		}
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rackmetrics.AddTransfer(0, len(data))
+
+	return data, nil
+}
+
+func (e remoteExecutor) WriteFile(path string, data []byte) error {
+	c, err := connectToShop(e.shop, e.timeouts)
+	if err != nil {
+		return err
+	}
+
+	defer c.Close()
+
+	rackmetrics.AddTransfer(len(data), 0)
+
+	return c.WriteFile(bytes.NewReader(data), path)
+}
+
+func (e remoteExecutor) CopyFileTo(path string, w io.Writer) error {
+	var c *sshrw.SSHClient
+
+	err := e.connect(context.Background(), func() error {
+		var err error
+		c, err = connectToShop(e.shop, e.timeouts)
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	defer c.Close()
+
+	counter := &countingWriter{w: w}
+	err = c.ReadFile(counter, path)
+	rackmetrics.AddTransfer(0, counter.n)
+
+	return err
+}
+
+func (e remoteExecutor) CopyFileFrom(path string, r io.Reader) error {
+	c, err := connectToShop(e.shop, e.timeouts)
+	if err != nil {
+		return err
+	}
+
+	defer c.Close()
+
+	counter := &countingReader{r: r}
+	err = c.WriteFile(counter, path)
+	rackmetrics.AddTransfer(counter.n, 0)
+
+	return err
+}
+
+//connect runs the dial of a connection to the shop with the retry policy.
+//Failed handshakes, like wrong keys, are not retried.
+func (e remoteExecutor) connect(ctx context.Context, dial func() error) error {
+	return rackretry.Do(ctx, e.retry, "Connecting to "+e.shop.Name, func() error {
+		return stopOnHandshake(dial())
+	})
+}
+
+//stopOnHandshake marks errors of failed handshakes, like wrong keys, so they are not retried
+func stopOnHandshake(err error) error {
+	if err != nil && strings.Contains(err.Error(), "handshake failed") {
+		return rackretry.Stop(err)
+	}
+
+	return err
+}
+
+type localExecutor struct {
+	timeouts rackretry.Timeouts
+}
+
+func (e localExecutor) Run(command string) (string, error) {
+	return e.RunContext(context.Background(), command)
+}
+
+func (e localExecutor) RunContext(ctx context.Context, command string) (string, error) {
+	racklog.Debugf("Running locally: %v", command)
+
+	var out string
+
+	err := withCommandTimeout(ctx, e.timeouts, func(ctx context.Context) error {
+		var err error
+		out, err = runLocal(ctx, command)
+
+		return err
+	})
+
+	rackmetrics.AddCommand(len(command), len(out))
+
+	return out, err
+}
+
+//runLocal writes the output to a file instead of a pipe,
+//so a killed command does not wait for the processes it started to close the pipe
+func runLocal(ctx context.Context, command string) (string, error) {
+	file, err := ioutil.TempFile("", "rackjobber-")
+	if err != nil {
+		return "", err
+	}
+
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command) //nolint, commands are built by rackjobber
+	cmd.Stdout = file
+	cmd.Stderr = file
+	err = cmd.Run()
+
+	out, readErr := ioutil.ReadFile(file.Name())
+	if readErr != nil {
+		return "", readErr
+	}
+
+	if ctx.Err() != nil {
+		return string(out), ctx.Err()
+	}
+
+	return string(out), err
+}
+
+func (localExecutor) ReadFile(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path) //nolint, the path belongs to a local shop
+	rackmetrics.AddTransfer(0, len(data))
+
+	return data, err
+}
+
+func (localExecutor) WriteFile(path string, data []byte) error {
+	rackmetrics.AddTransfer(len(data), 0)
+	return ioutil.WriteFile(path, data, os.ModePerm)
+}
+
+func (localExecutor) CopyFileTo(path string, w io.Writer) error {
+	file, err := os.Open(path) //nolint, the path belongs to a local shop
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	n, err := io.Copy(w, file)
+	rackmetrics.AddTransfer(0, int(n))
+
+	return err
+}
+
+func (localExecutor) CopyFileFrom(path string, r io.Reader) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	n, err := io.Copy(file, r)
+	rackmetrics.AddTransfer(int(n), 0)
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+//countingWriter counts the bytes written to w for the transfer metrics
+type countingWriter struct {
+	w io.Writer
+	n int
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += n
+
+	return n, err
+}
+
+//countingReader counts the bytes read from r for the transfer metrics
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+
+	return n, err
+}
+
+// RunCommandInShop runs a command on the machine of the shop and returns its output
+func RunCommandInShop(command string, shop *rackshop.RackShop) (string, error) {
+	return NewExecutor(shop).Run(command)
+}
+
+// WriteFileToShop writes a file relative to the shopware directory of a shop
+func WriteFileToShop(shop *rackshop.RackShop, file string, data []byte) error {
+	return NewExecutor(shop).WriteFile(filepath.Join(shop.ShopwareDir, file), data)
+}
+
+// CheckShopConnection checks if the machine of a shop is reachable
+func CheckShopConnection(shop *rackshop.RackShop) bool {
+	if shop.Local {
+		return true
+	}
+
+	return CheckConnection(shop.Address, remoteConfig(shop, rackconfig.GetTimeouts(shop)))
+}
+
+// ConsoleCommand returns the command to run the shopware console with the given arguments inside of the shop's container
+func ConsoleCommand(shop *rackshop.RackShop, args string) string {
+	return "docker exec -i " + shop.Container + " php /var/www/html/bin/console " + args
+}
+
+// QuoteArgument quotes an argument of a command, so it is passed unchanged by the shell
+func QuoteArgument(arg string) string {
+	return "'" + strings.ReplaceAll(arg, "'", `'"'"'`) + "'"
+}
+
+//splitLines splits the output of a command into its non-empty lines
+func splitLines(output string) []string {
+	lines := []string{}
+
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); len(line) > 0 {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
@@ -0,0 +1,46 @@
+package rackssh
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/fileutil"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshop"
+)
+
+func TestWithContext(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rackjobber")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	if err := fileutil.SetHome(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	executor := NewExecutor(&rackshop.RackShop{Name: "my-shop", Local: true, ShopwareDir: dir})
+
+	out, err := WithContext(context.Background(), executor).Run("echo running")
+	if err != nil || strings.TrimSpace(out) != "running" {
+		t.Errorf("got %q, %v, want the output of the command", out, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+
+	if _, err := WithContext(ctx, executor).Run("sleep 10"); err != context.DeadlineExceeded {
+		t.Errorf("got error %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	if took := time.Since(start); took > 5*time.Second {
+		t.Errorf("the command was killed after %v", took)
+	}
+}
@@ -4,8 +4,6 @@ package rackssh
 import (
 	"bufio"
 	"bytes"
-	"fmt"
-	"log"
 	"path/filepath"
 	"strings"
 
@@ -13,6 +11,8 @@ import (
 	"golang.org/x/crypto/ssh"
 
 	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/gitutil"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racklog"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackretry"
 	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshop"
 )
 
@@ -26,13 +26,13 @@ func ConnectToRemote(host string, config *ssh.ClientConfig) (*ssh.Client, error)
 func CheckConnection(host string, config *ssh.ClientConfig) bool {
 	client, err := ConnectToRemote(host, config)
 	if err != nil {
-		fmt.Println(err)
+		racklog.With("host", host).Errorf("%v", err)
 		return false
 	}
 
 	defer func() {
 		if err = client.Close(); err != nil {
-			log.Printf("client.Close - error: %v\n", err)
+			racklog.Warnf("client.Close - error: %v\n", err)
 		}
 	}()
 
@@ -42,7 +42,7 @@ func CheckConnection(host string, config *ssh.ClientConfig) bool {
 //GetRemoteFileFromShop loads a remote file from a shop and returns its local path
 func GetRemoteFileFromShop(shop *rackshop.RackShop, file string) ([]byte, error) {
 	fileDir := filepath.Join(shop.ShopwareDir, file)
-	return readRemoteFileForShop(shop, fileDir)
+	return NewExecutor(shop).ReadFile(fileDir)
 }
 
 //GetRemoteDirsFromShop returns a list of folders inside of the given dir and shop
@@ -51,28 +51,21 @@ func GetRemoteDirsFromShop(shop *rackshop.RackShop, dir string) []string {
 	return readRemoteDirsForShop(shop, dirsDir)
 }
 
-//readRemoteFileForShop returns the requested file of a given shop
-func readRemoteFileForShop(shop *rackshop.RackShop, path string) ([]byte, error) {
+//readRemoteFile returns the requested file from a connected shop
+func readRemoteFile(c *sshrw.SSHClient, path string) ([]byte, error) {
 	var buff bytes.Buffer
 
-	c, err := connectToShop(shop)
-	if err != nil {
-		fmt.Println("Failed to connect to shop")
-		return nil, err
-	}
-	defer c.Close()
-
 	w := bufio.NewWriter(&buff)
 
-	err = c.ReadFile(w, path)
+	err := c.ReadFile(w, path)
 	if err != nil {
-		fmt.Println("Error on file read: ", err.Error())
+		racklog.Errorf("Error on file read: %v", err)
 		return nil, err
 	}
 
 	err = w.Flush()
 	if err != nil {
-		fmt.Printf("bufioWriter.Flush - error: %v\n", err)
+		racklog.Errorf("bufioWriter.Flush - error: %v\n", err)
 	}
 
 	return buff.Bytes(), nil
@@ -82,81 +75,51 @@ func readRemoteFileForShop(shop *rackshop.RackShop, path string) ([]byte, error)
 func readRemoteDirsForShop(shop *rackshop.RackShop, path string) []string {
 	command := "cd " + path + " && ls -d */"
 
-	conn, _ := ssh.Dial("tcp", shop.Address+":22", shop.GetRemoteConfig())
-
-	defer func() {
-		if err := conn.Close(); err != nil {
-			log.Printf("conn.Close - error: %v\n", err)
-		}
-	}()
-
-	session, _ := conn.NewSession()
-
-	defer func() {
-		if err := session.Close(); err != nil {
-			log.Printf("session.Close - error: %v\n", err)
-		}
-	}()
-
-	var b bytes.Buffer
-	session.Stdout = &b
-
-	err := session.Run(command)
+	out, err := NewExecutor(shop).Run(command)
 	if err != nil {
-		log.Fatalf("session.Run - error: %v\n", err)
+		racklog.Fatalf("session.Run - error: %v\n", err)
 	}
 
-	return strings.Split(strings.ReplaceAll(b.String(), "/", ""), "\n")
+	return splitLines(strings.ReplaceAll(out, "/", ""))
 }
 
 //connectToShop returns a SSHClient for the given shop
-func connectToShop(shop *rackshop.RackShop) (*sshrw.SSHClient, error) {
-	return sshrw.NewSSHclt(shop.Address+":22", shop.GetRemoteConfig())
+func connectToShop(shop *rackshop.RackShop, timeouts rackretry.Timeouts) (*sshrw.SSHClient, error) {
+	return sshrw.NewSSHclt(shop.Address+":22", remoteConfig(shop, timeouts))
 }
 
-//RunRemoteCommandInShop runs a command on the remote machine
-func RunRemoteCommandInShop(command string, shop *rackshop.RackShop) {
-	conn, err := ssh.Dial("tcp", shop.Address+":22", shop.GetRemoteConfig())
-	if err != nil {
-		log.Fatalf("Could not connect to remote machine: %v\n", err)
-	}
-
-	defer func() {
-		if err := conn.Close(); err != nil {
-			log.Printf("conn.Close - error: %v\n", err)
-		}
-	}()
+//remoteConfig returns the ssh config of the shop, that gives up connecting after the dial timeout
+func remoteConfig(shop *rackshop.RackShop, timeouts rackretry.Timeouts) *ssh.ClientConfig {
+	racklog.AddSecret(shop.Password)
 
-	session, err := conn.NewSession()
-	if err != nil {
-		log.Fatalf("Error creating new SSH session: %v\n", err)
-	}
+	config := shop.GetRemoteConfig()
+	config.Timeout = timeouts.GetDial()
 
-	defer func() {
-		if err := session.Close(); err != nil && !strings.Contains(err.Error(), "EOF") {
-			log.Printf("session.Close - error: %v\n", err)
-		}
-	}()
+	return config
+}
 
-	err = session.Run(command)
+//RunRemoteCommandInShop runs a command on the remote machine
+func RunRemoteCommandInShop(command string, shop *rackshop.RackShop) {
+	_, err := NewExecutor(shop).Run(command)
 	if err != nil {
-		log.Fatalf("session.Run - error: %v\n", err)
+		racklog.Fatalf("session.Run - error: %v\n", err)
 	}
 }
 
 //CloneGitToRemoteShop clones a GIT repo to the remote server of a shop
 func CloneGitToRemoteShop(shop *rackshop.RackShop, pluginName string, url string, version string) {
-	fmt.Println("Cloning " + pluginName + " to " + shop.Name + ".")
+	racklog.With("shop", shop.Name).With("plugin", pluginName).Infof("Cloning %v to %v.", pluginName, shop.Name)
+	RunRemoteCommandInShop(CloneCommand(shop, pluginName, url, version), shop)
+}
 
+//CloneCommand returns the command, that clones a GIT repo into the plugins of a shop
+func CloneCommand(shop *rackshop.RackShop, pluginName string, url string, version string) string {
 	filledURL := gitutil.GetURLWithAuth(url)
 	remoteClonePath := filepath.Join(shop.ShopwareDir, "custom", "plugins", pluginName)
 
-	command := ""
 	if version != "" {
-		command = "git clone --single-branch --branch " + version + " " + filledURL + " " + remoteClonePath
-	} else {
-		command = "git clone " + filledURL + " " + remoteClonePath
+		return "git clone --single-branch --branch " + version + " " + filledURL + " " + remoteClonePath
 	}
 
-	RunRemoteCommandInShop(command, shop)
+	return "git clone " + filledURL + " " + remoteClonePath
 }
@@ -0,0 +1,63 @@
+package rackstate
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshop"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackssh"
+)
+
+const (
+	// StateDir is the directory of a shop, that contains the files written by rackjobber
+	StateDir = "custom/rackjobber"
+	// StateFile is the path of the state file relative to the shopware directory
+	StateFile = StateDir + "/state.yaml"
+	// LegacyHashFile is the path of the rackpluginhashes.yaml, that was used before the state file
+	LegacyHashFile = "custom/rackpluginhashes.yaml"
+)
+
+// ErrNoState is returned by ReadShopState, if the shop has neither a state file nor a rackpluginhashes.yaml
+var ErrNoState = errors.New("the shop has no deployment state")
+
+// ReadShopState reads the state of a shop.
+// If the shop has no state file yet, its rackpluginhashes.yaml is migrated.
+// ErrNoState is returned, if the shop has neither of them, failures to read them are returned as they are.
+func ReadShopState(executor rackssh.Executor, shop *rackshop.RackShop) (*State, error) {
+	statePath := filepath.Join(shop.ShopwareDir, StateFile)
+	legacyPath := filepath.Join(shop.ShopwareDir, LegacyHashFile)
+
+	out, err := executor.Run("for file in " + rackssh.QuoteArgument(statePath) + " " + rackssh.QuoteArgument(legacyPath) +
+		"; do if [ -e \"$file\" ]; then echo \"$file\"; exit 0; fi; done")
+	if err != nil {
+		return nil, errors.New("failed to look for the state of " + shop.Name + ": " + err.Error() + " " +
+			strings.TrimSpace(out))
+	}
+
+	path := strings.TrimSpace(out)
+	if len(path) == 0 {
+		return nil, ErrNoState
+	}
+
+	data, err := executor.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return UnmarshalState(data)
+}
+
+// WriteShopState writes the state file of a shop
+func WriteShopState(executor rackssh.Executor, shop *rackshop.RackShop, state *State) error {
+	data, err := state.MarshalState()
+	if err != nil {
+		return err
+	}
+
+	if _, err := executor.Run("mkdir -p " + filepath.Join(shop.ShopwareDir, StateDir)); err != nil {
+		return err
+	}
+
+	return executor.WriteFile(filepath.Join(shop.ShopwareDir, StateFile), *data)
+}
@@ -0,0 +1,73 @@
+package rackstate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/fileutil"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshop"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackssh"
+)
+
+func TestReadShopState(t *testing.T) {
+	root, err := ioutil.TempDir("", "rackjobber")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(root)
+
+	if err := fileutil.SetHome(filepath.Join(root, "home")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		files   map[string]string
+		commit  string
+		wantErr error
+	}{
+		{name: "no state", files: map[string]string{}, wantErr: ErrNoState},
+		{name: "state file", files: map[string]string{
+			StateFile:      "version: 1\nplugins:\n- name: SwagPlugin\n  commit: abc\n",
+			LegacyHashFile: "hashes:\n- name: SwagPlugin\n  hash: old\n",
+		}, commit: "abc"},
+		{name: "rackpluginhashes", files: map[string]string{
+			LegacyHashFile: "hashes:\n- name: SwagPlugin\n  hash: old\n",
+		}, commit: "old"},
+	}
+
+	for _, test := range tests {
+		shop := &rackshop.RackShop{Name: test.name, Local: true, ShopwareDir: filepath.Join(root, test.name)}
+
+		for path, content := range test.files {
+			if err := os.MkdirAll(filepath.Dir(filepath.Join(shop.ShopwareDir, path)), os.ModePerm); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := ioutil.WriteFile(filepath.Join(shop.ShopwareDir, path), []byte(content), 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		state, err := ReadShopState(rackssh.NewExecutor(shop), shop)
+		if test.wantErr != nil {
+			if err != test.wantErr {
+				t.Errorf("%v: got %v, want %v", test.name, err, test.wantErr)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.name, err)
+			continue
+		}
+
+		if commit, _ := state.GetCommit("SwagPlugin"); commit != test.commit {
+			t.Errorf("%v: got commit %q, want %q", test.name, commit, test.commit)
+		}
+	}
+}
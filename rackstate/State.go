@@ -0,0 +1,185 @@
+// Package rackstate includes the structs and functions to read and write the deployment state of a shop.
+// The state replaces the custom/rackpluginhashes.yaml, which is still read and migrated automatically.
+package rackstate
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"os"
+	"os/user"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackpluginhashes"
+)
+
+// CurrentVersion is the version of the state file format written by rackjobber
+const CurrentVersion = 1
+
+// Results of the deployment of a plugin
+const (
+	ResultDeployed   = "deployed"
+	ResultMigrated   = "migrated"
+	ResultIntegrated = "integrated"
+)
+
+// State defines the yaml structure of the state file of a shop
+type State struct {
+	Version int `yaml:"version"`
+	// RunID is the ID of the last run, that changed the state
+	RunID     string        `yaml:"runId,omitempty"`
+	UpdatedAt time.Time     `yaml:"updatedAt,omitempty"`
+	Plugins   []PluginState `yaml:"plugins"`
+}
+
+// PluginState defines the yaml structure of a single deployed plugin inside of the state file
+type PluginState struct {
+	Name       string `yaml:"name"`
+	Version    string `yaml:"version,omitempty"`
+	Tag        string `yaml:"tag,omitempty"`
+	Commit     string `yaml:"commit"`
+	Source     string `yaml:"source,omitempty"`
+	SpecRepo   string `yaml:"specRepo,omitempty"`
+	SpecCommit string `yaml:"specCommit,omitempty"`
+	// Flag is the flag of the rackfile entry, that was applied during the deployment
+	Flag         string    `yaml:"flag,omitempty"`
+	Installed    bool      `yaml:"installed"`
+	Activated    bool      `yaml:"activated"`
+	Result       string    `yaml:"result,omitempty"`
+	DeployedAt   time.Time `yaml:"deployedAt,omitempty"`
+	DeployedBy   string    `yaml:"deployedBy,omitempty"`
+	DeployedFrom string    `yaml:"deployedFrom,omitempty"`
+	RunID        string    `yaml:"runId,omitempty"`
+}
+
+// versionProbe is used to detect the format of a state file
+type versionProbe struct {
+	Version int `yaml:"version"`
+}
+
+// NewState returns an empty state in the current format
+func NewState() *State {
+	return &State{Version: CurrentVersion, Plugins: []PluginState{}}
+}
+
+// UnmarshalState unmarshals a state file.
+// Files in the format of the rackpluginhashes.yaml are migrated to the current format.
+func UnmarshalState(data []byte) (*State, error) {
+	probe := &versionProbe{}
+	if err := yaml.Unmarshal(data, probe); err != nil {
+		return nil, err
+	}
+
+	if probe.Version == 0 {
+		hashes := &rackpluginhashes.PluginHashes{}
+		if err := yaml.Unmarshal(data, hashes); err != nil {
+			return nil, err
+		}
+
+		return MigratePluginHashes(hashes), nil
+	}
+
+	if probe.Version > CurrentVersion {
+		return nil, errors.New("the state file was written by a newer version of rackjobber")
+	}
+
+	state := NewState()
+	if err := yaml.Unmarshal(data, state); err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+// MarshalState marshals the state into yaml data
+func (s *State) MarshalState() (*[]byte, error) {
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return &data, nil
+}
+
+// MigratePluginHashes converts the content of a rackpluginhashes.yaml into a state.
+// The hashes only record the commits, the versions and sources are filled in by the next up.
+func MigratePluginHashes(hashes *rackpluginhashes.PluginHashes) *State {
+	state := NewState()
+
+	for _, hash := range hashes.Hashes {
+		state.Plugins = append(state.Plugins, PluginState{
+			Name:   hash.Name,
+			Commit: hash.Hash,
+			Result: ResultMigrated,
+		})
+	}
+
+	return state
+}
+
+// GetPlugin returns the state of a plugin
+func (s *State) GetPlugin(name string) (*PluginState, bool) {
+	for i := range s.Plugins {
+		if s.Plugins[i].Name == name {
+			return &s.Plugins[i], true
+		}
+	}
+
+	return nil, false
+}
+
+// GetCommit returns the commit a plugin was deployed with
+func (s *State) GetCommit(name string) (string, error) {
+	plugin, ok := s.GetPlugin(name)
+	if !ok {
+		return "", errors.New("No hash found for plugin: " + name)
+	}
+
+	return plugin.Commit, nil
+}
+
+// SetPlugin sets the state of a plugin. If the plugin does not exist yet, it is added
+func (s *State) SetPlugin(plugin PluginState) {
+	for i := range s.Plugins {
+		if s.Plugins[i].Name == plugin.Name {
+			s.Plugins[i] = plugin
+			return
+		}
+	}
+
+	s.Plugins = append(s.Plugins, plugin)
+}
+
+// RemovePlugin removes the state of a plugin
+func (s *State) RemovePlugin(name string) {
+	for i := range s.Plugins {
+		if s.Plugins[i].Name == name {
+			s.Plugins = append(s.Plugins[:i], s.Plugins[i+1:]...)
+			return
+		}
+	}
+}
+
+// NewRunID returns a new unique ID for a run of rackjobber, starting with the current time
+func NewRunID() string {
+	const randomBytes = 3
+
+	random := make([]byte, randomBytes)
+	_, _ = rand.Read(random)
+
+	return time.Now().UTC().Format("20060102-150405") + "-" + hex.EncodeToString(random)
+}
+
+// Deployer returns the name of the current user and the host rackjobber is running on
+func Deployer() (string, string) {
+	name := os.Getenv("USER")
+	if current, err := user.Current(); err == nil {
+		name = current.Username
+	}
+
+	host, _ := os.Hostname()
+
+	return name, host
+}
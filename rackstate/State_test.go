@@ -0,0 +1,111 @@
+package rackstate
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnmarshalState(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []PluginState
+		wantErr bool
+	}{
+		{
+			name: "current format",
+			data: "version: 1\nrunId: run-1\nplugins:\n- name: SwagPlugin\n  tag: 1.2.0\n  commit: abc\n" +
+				"  source: https://git.example.com/swag.git\n  flag: noactivate\n  installed: true\n",
+			want: []PluginState{{Name: "SwagPlugin", Tag: "1.2.0", Commit: "abc",
+				Source: "https://git.example.com/swag.git", Flag: "noactivate", Installed: true}},
+		},
+		{
+			name: "rackpluginhashes format",
+			data: "hashes:\n- name: SwagPlugin\n  hash: abc\n- name: OtherPlugin\n  hash: def\n",
+			want: []PluginState{
+				{Name: "SwagPlugin", Commit: "abc", Result: ResultMigrated},
+				{Name: "OtherPlugin", Commit: "def", Result: ResultMigrated},
+			},
+		},
+		{
+			name: "empty rackpluginhashes",
+			data: "hashes: []\n",
+			want: []PluginState{},
+		},
+		{
+			name:    "newer version",
+			data:    "version: 2\nplugins: []\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid yaml",
+			data:    "version: [",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		state, err := UnmarshalState([]byte(test.data))
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%v: expected an error", test.name)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.name, err)
+			continue
+		}
+
+		if state.Version != CurrentVersion {
+			t.Errorf("%v: version = %v, want %v", test.name, state.Version, CurrentVersion)
+		}
+
+		if !reflect.DeepEqual(state.Plugins, test.want) {
+			t.Errorf("%v: plugins = %+v, want %+v", test.name, state.Plugins, test.want)
+		}
+	}
+}
+
+func TestMarshalStateRoundTrip(t *testing.T) {
+	state := NewState()
+	state.RunID = "run-1"
+	state.SetPlugin(PluginState{Name: "SwagPlugin", Tag: "1.2.0", Commit: "abc", Installed: true, Activated: true})
+
+	data, err := state.MarshalState()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := UnmarshalState(*data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(read, state) {
+		t.Errorf("read %+v, want %+v", read, state)
+	}
+}
+
+func TestSetAndRemovePlugin(t *testing.T) {
+	state := NewState()
+	state.SetPlugin(PluginState{Name: "SwagPlugin", Commit: "abc"})
+	state.SetPlugin(PluginState{Name: "OtherPlugin", Commit: "def"})
+	state.SetPlugin(PluginState{Name: "SwagPlugin", Commit: "ghi"})
+
+	if commit, err := state.GetCommit("SwagPlugin"); err != nil || commit != "ghi" {
+		t.Errorf("GetCommit(SwagPlugin) = %q, %v, want ghi", commit, err)
+	}
+
+	state.RemovePlugin("SwagPlugin")
+
+	if _, err := state.GetCommit("SwagPlugin"); err == nil {
+		t.Error("expected no commit for a removed plugin")
+	}
+
+	if len(state.Plugins) != 1 || state.Plugins[0].Name != "OtherPlugin" {
+		t.Errorf("plugins = %+v, want only OtherPlugin", state.Plugins)
+	}
+}
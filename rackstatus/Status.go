@@ -0,0 +1,292 @@
+// Package rackstatus includes functions to compare the wanted state of a shop with its actual state
+package rackstatus
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackconfig"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackfile"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackinspect"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshop"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshopdb"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshopstore"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackssh"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackstate"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/repository"
+)
+
+// Drift messages of a plugin
+const (
+	DriftMissing          = "listed in the rackfile, but missing on the shop"
+	DriftNotInRackFile    = "present on the shop, but not listed in the rackfile"
+	DriftModified         = "modified on the server"
+	DriftNotOnHash        = "checkout is not on the recorded commit"
+	DriftNoHash           = "no commit recorded for the plugin"
+	DriftVersion          = "installed version differs from the rackfile"
+	DriftNotInstalled     = "not installed"
+	DriftNotActive        = "not active"
+	DriftUnexpectedActive = "active, although the rackfile disables it"
+)
+
+// Report contains the status of all plugins and themes of a shop
+type Report struct {
+	Shop    string         `json:"shop"`
+	Plugins []PluginStatus `json:"plugins"`
+	// ExpectedTheme is the theme, that up would set for the default shop
+	ExpectedTheme string               `json:"expectedTheme,omitempty"`
+	SubShops      []rackshopdb.SubShop `json:"subShops,omitempty"`
+	// ThemeDrift contains a message if the themes of the subshops differ from the expected ones
+	ThemeDrift string `json:"themeDrift,omitempty"`
+	// Errors contains the parts of the status, that could not be determined
+	Errors []string `json:"errors,omitempty"`
+}
+
+// PluginStatus contains the wanted and the actual state of a single plugin
+type PluginStatus struct {
+	Name             string   `json:"name"`
+	InRackFile       bool     `json:"inRackFile"`
+	WantedVersion    string   `json:"wantedVersion,omitempty"`
+	Flag             string   `json:"flag,omitempty"`
+	Present          bool     `json:"present"`
+	InstalledVersion string   `json:"installedVersion,omitempty"`
+	Commit           string   `json:"commit,omitempty"`
+	RecordedCommit   string   `json:"recordedCommit,omitempty"`
+	Installed        bool     `json:"installed"`
+	Active           bool     `json:"active"`
+	Drift            []string `json:"drift,omitempty"`
+}
+
+// HasDrift checks if the actual state of the shop differs from the wanted one
+func (r Report) HasDrift() bool {
+	if len(r.ThemeDrift) > 0 {
+		return true
+	}
+
+	for _, plugin := range r.Plugins {
+		if len(plugin.Drift) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetStatus compares the rackfile and the deployment state of a shop with the plugins, their checkouts,
+// their state in shopware and the active theme, and reports all differences.
+func GetStatus(shopName string) (*Report, error) {
+	shop, err := rackshopstore.GetShopFromStore(shopName)
+	if err != nil {
+		return nil, err
+	}
+
+	executor := rackssh.NewExecutor(shop)
+	report := &Report{Shop: shop.Name, Plugins: []PluginStatus{}}
+
+	wanted, themes := readWantedPlugins(executor, shop, report)
+	deployed := readDeploymentState(executor, shop, report)
+
+	installed, err := rackinspect.InspectPlugins(executor, shop)
+	if err != nil {
+		return nil, err
+	}
+
+	states, err := rackinspect.ReadPluginStates(executor, shop)
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+	}
+
+	statuses := map[string]*PluginStatus{}
+
+	for _, entry := range wanted {
+		status := &PluginStatus{Name: entry.Name, InRackFile: true, WantedVersion: entry.Version, Flag: entry.Flag}
+		statuses[entry.Name] = status
+	}
+
+	for _, plugin := range installed {
+		status, ok := statuses[plugin.Name]
+		if !ok {
+			status = &PluginStatus{Name: plugin.Name}
+			statuses[plugin.Name] = status
+		}
+
+		comparePlugin(status, plugin, deployed, states)
+	}
+
+	for _, status := range statuses {
+		if status.InRackFile && !status.Present {
+			status.Drift = append(status.Drift, DriftMissing)
+		}
+
+		report.Plugins = append(report.Plugins, *status)
+	}
+
+	sort.Slice(report.Plugins, func(i, j int) bool {
+		return report.Plugins[i].Name < report.Plugins[j].Name
+	})
+
+	compareTheme(executor, shop, wanted, themes, report)
+
+	return report, nil
+}
+
+// readWantedPlugins returns the mandatory plugins of the config and the plugins and themes of the shop's rackfile
+func readWantedPlugins(executor rackssh.Executor, shop *rackshop.RackShop,
+	report *Report) ([]rackfile.Entry, []rackfile.ThemeEntry) {
+	entries := []rackfile.Entry{}
+	seen := map[string]bool{}
+
+	add := func(plugin string) {
+		entry := rackfile.ParseEntry(plugin)
+		if seen[entry.Name] {
+			return
+		}
+
+		seen[entry.Name] = true
+
+		if version, err := repository.ResolvePluginVersion(entry.Name, entry.Version); err == nil {
+			entry.Version = version
+		}
+
+		entries = append(entries, entry)
+	}
+
+	for _, plugin := range rackconfig.GetConfig().MandatoryPlugins {
+		add(plugin)
+	}
+
+	data, err := executor.ReadFile(filepath.Join(shop.ShopwareDir, "custom/rackfile.yaml"))
+	if err != nil {
+		report.Errors = append(report.Errors, "failed to read the rackfile: "+err.Error())
+		return entries, nil
+	}
+
+	rackFile, err := rackfile.UnmarshalRackFileData(data)
+	if err != nil {
+		report.Errors = append(report.Errors, "failed to parse the rackfile: "+err.Error())
+		return entries, nil
+	}
+
+	for _, plugin := range rackFile.Plugins {
+		add(plugin)
+	}
+
+	for _, theme := range rackFile.Themes {
+		add(theme.PluginEntry())
+	}
+
+	return entries, rackFile.Themes
+}
+
+// readDeploymentState returns the deployment state recorded on the shop
+func readDeploymentState(executor rackssh.Executor, shop *rackshop.RackShop, report *Report) *rackstate.State {
+	state, err := rackstate.ReadShopState(executor, shop)
+	if err != nil {
+		report.Errors = append(report.Errors, "failed to read the deployment state: "+err.Error())
+		return rackstate.NewState()
+	}
+
+	return state
+}
+
+// comparePlugin fills the actual state of a plugin into its status and adds the found drift
+func comparePlugin(status *PluginStatus, plugin rackinspect.InstalledPlugin, deployed *rackstate.State,
+	states map[string]rackinspect.PluginState) {
+	status.Present = true
+	status.InstalledVersion = plugin.Version
+	status.Commit = plugin.Commit
+
+	if state, ok := states[plugin.Name]; ok {
+		status.Installed = state.Installed
+		status.Active = state.Active
+	}
+
+	if plugin.Modified {
+		status.Drift = append(status.Drift, DriftModified)
+	}
+
+	if !status.InRackFile {
+		status.Drift = append(status.Drift, DriftNotInRackFile)
+		return
+	}
+
+	if status.Flag == rackfile.FlagUnmanaged {
+		return
+	}
+
+	if recorded, err := deployed.GetCommit(plugin.Name); err != nil {
+		status.Drift = append(status.Drift, DriftNoHash)
+	} else {
+		status.RecordedCommit = recorded
+		if recorded != plugin.Commit {
+			status.Drift = append(status.Drift, DriftNotOnHash)
+		}
+	}
+
+	if len(plugin.Version) > 0 && len(status.WantedVersion) > 0 && !sameVersion(plugin.Version, status.WantedVersion) {
+		status.Drift = append(status.Drift, DriftVersion)
+	}
+
+	if states == nil {
+		return
+	}
+
+	switch status.Flag {
+	case "noinstall":
+	case "noactivate":
+		if !status.Installed {
+			status.Drift = append(status.Drift, DriftNotInstalled)
+		} else if status.Active {
+			status.Drift = append(status.Drift, DriftUnexpectedActive)
+		}
+	default:
+		if !status.Installed {
+			status.Drift = append(status.Drift, DriftNotInstalled)
+		} else if !status.Active {
+			status.Drift = append(status.Drift, DriftNotActive)
+		}
+	}
+}
+
+// Print prints the report in a human readable form
+func (r Report) Print() {
+	fmt.Printf("Status of shop %v:\n", r.Shop)
+
+	for _, plugin := range r.Plugins {
+		state := "ok"
+		if len(plugin.Drift) > 0 {
+			state = strings.Join(plugin.Drift, ", ")
+		}
+
+		fmt.Printf(" - %v: %v\n", plugin.Name, state)
+
+		if plugin.InRackFile {
+			fmt.Printf("\tWanted: %v\n", rackfile.Entry{Name: plugin.Name, Version: plugin.WantedVersion, Flag: plugin.Flag})
+		}
+
+		if plugin.Present {
+			fmt.Printf("\tInstalled: %v (commit %v, installed: %v, active: %v)\n",
+				plugin.InstalledVersion, plugin.Commit, plugin.Installed, plugin.Active)
+		}
+	}
+
+	for _, subShop := range r.SubShops {
+		fmt.Printf(" - Shop %v (%v): theme %v\n", subShop.Name, subShop.ID, subShop.Theme)
+	}
+
+	if len(r.ThemeDrift) > 0 {
+		fmt.Println("Theme: " + r.ThemeDrift)
+	}
+
+	for _, err := range r.Errors {
+		fmt.Println("Warning: " + err)
+	}
+
+	if r.HasDrift() {
+		fmt.Println("The shop differs from its rackfile.")
+	} else {
+		fmt.Println("The shop corresponds to its rackfile.")
+	}
+}
@@ -0,0 +1,90 @@
+package rackstatus
+
+import (
+	"strings"
+
+	"github.com/hashicorp/go-version"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackfile"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshop"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshopdb"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackssh"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racktheme"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/repository"
+)
+
+//compareTheme reads the themes of the shop's subshops and compares them with the expected themes.
+//If the rackfile lists themes, their assignments are expected. Otherwise like up,
+//the theme of the last plugin without flag, whose rackspec defines a theme, is expected for the default shop.
+func compareTheme(executor rackssh.Executor, shop *rackshop.RackShop, wanted []rackfile.Entry,
+	themes []rackfile.ThemeEntry, report *Report) {
+	subShops, err := rackshopdb.ReadSubShops(executor, shop)
+	if err != nil {
+		report.Errors = append(report.Errors, "failed to read the themes: "+err.Error())
+		return
+	}
+
+	report.SubShops = subShops
+
+	if len(themes) > 0 {
+		compareThemeAssignments(themes, subShops, report)
+		return
+	}
+
+	for _, entry := range wanted {
+		if len(entry.Flag) > 0 {
+			continue
+		}
+
+		spec, _, err := repository.GetRackSpec(entry.Name, entry.Version)
+		if err == nil && len(spec.Theme) > 0 {
+			report.ExpectedTheme = strings.ReplaceAll(spec.Theme, " ", "_")
+		}
+	}
+
+	if len(report.ExpectedTheme) == 0 {
+		return
+	}
+
+	for _, subShop := range subShops {
+		if subShop.Default && subShop.Theme != report.ExpectedTheme {
+			report.ThemeDrift = "default shop uses theme " + subShop.Theme + " instead of " + report.ExpectedTheme
+		}
+	}
+}
+
+//compareThemeAssignments compares the themes of the subshops with the themes assigned to them by the rackfile
+func compareThemeAssignments(themes []rackfile.ThemeEntry, subShops []rackshopdb.SubShop, report *Report) {
+	assignments, err := racktheme.ResolveAssignments(themes, subShops)
+	if err != nil {
+		report.Errors = append(report.Errors, "failed to resolve the themes of the rackfile: "+err.Error())
+		return
+	}
+
+	drift := []string{}
+
+	for _, assignment := range assignments {
+		if assignment.SubShop.Default {
+			report.ExpectedTheme = assignment.Template
+		}
+
+		if !assignment.IsAssigned() {
+			drift = append(drift, "shop "+assignment.SubShop.Name+" uses theme "+assignment.SubShop.Theme+
+				" instead of "+assignment.Template)
+		}
+	}
+
+	report.ThemeDrift = strings.Join(drift, "; ")
+}
+
+//sameVersion compares two versions, falling back to a string comparison if they can not be parsed
+func sameVersion(a string, b string) bool {
+	versionA, errA := version.NewVersion(a)
+	versionB, errB := version.NewVersion(b)
+
+	if errA != nil || errB != nil {
+		return a == b
+	}
+
+	return versionA.Equal(versionB)
+}
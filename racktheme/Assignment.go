@@ -0,0 +1,97 @@
+// Package racktheme includes functions to assign the themes of a rackfile to the subshops of a shop
+package racktheme
+
+import (
+	"errors"
+	"strings"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackfile"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshopdb"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/repository"
+)
+
+// Assignment is a theme of the rackfile, that shall be assigned to a subshop
+type Assignment struct {
+	SubShop rackshopdb.SubShop
+	Theme   rackfile.ThemeEntry
+	// Template is the name of the theme in shopware, as defined by the rackspec of the theme
+	Template string
+}
+
+// IsAssigned returns if the subshop already uses the theme
+func (a Assignment) IsAssigned() bool {
+	return a.SubShop.Theme == a.Template
+}
+
+// ResolveAssignments returns the theme of the rackfile for every subshop.
+// Themes assigned to a subshop by its ID or name win over the default theme,
+//subshops without an assigned theme and without a default theme are left out.
+func ResolveAssignments(themes []rackfile.ThemeEntry, subShops []rackshopdb.SubShop) ([]Assignment, error) {
+	if err := rackfile.ValidateThemes(themes); err != nil {
+		return nil, err
+	}
+
+	assigned := map[string]rackfile.ThemeEntry{}
+
+	var defaultTheme *rackfile.ThemeEntry
+
+	for i, theme := range themes {
+		if theme.Default {
+			defaultTheme = &themes[i]
+		}
+
+		for _, reference := range theme.Shops {
+			subShop, err := rackshopdb.FindSubShop(subShops, reference)
+			if err != nil {
+				return nil, err
+			}
+
+			if other, ok := assigned[subShop.ID]; ok && other.Name != theme.Name {
+				return nil, errors.New("the shop " + reference + " is assigned to " + other.Name + " and " + theme.Name)
+			}
+
+			assigned[subShop.ID] = theme
+		}
+	}
+
+	assignments := []Assignment{}
+
+	for _, subShop := range subShops {
+		theme, ok := assigned[subShop.ID]
+		if !ok {
+			if defaultTheme == nil {
+				continue
+			}
+
+			theme = *defaultTheme
+		}
+
+		template, err := GetTemplate(theme)
+		if err != nil {
+			return nil, err
+		}
+
+		assignments = append(assignments, Assignment{SubShop: subShop, Theme: theme, Template: template})
+	}
+
+	return assignments, nil
+}
+
+// GetTemplate returns the name of the theme in shopware, as defined by the rackspec of the theme's version
+func GetTemplate(theme rackfile.ThemeEntry) (string, error) {
+	version, err := repository.ResolvePluginVersion(theme.Name, theme.Version)
+	if err != nil {
+		return "", err
+	}
+
+	spec, _, err := repository.GetRackSpec(theme.Name, version)
+	if err != nil {
+		return "", err
+	}
+
+	if len(spec.Theme) == 0 {
+		return "", errors.New("the rackspec of " + theme.Name + " " + version + " defines no theme")
+	}
+
+	return strings.ReplaceAll(spec.Theme, " ", "_"), nil
+}
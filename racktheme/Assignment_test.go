@@ -0,0 +1,105 @@
+package racktheme
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/fileutil"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackfile"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshopdb"
+)
+
+func TestResolveAssignments(t *testing.T) {
+	home, err := ioutil.TempDir("", "rackjobber")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(home)
+
+	if err := fileutil.SetHome(home); err != nil {
+		t.Fatal(err)
+	}
+
+	dataPath, err := fileutil.GetAppFolderPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for spec, content := range map[string]string{
+		"SwagTheme/1.0.0":  "name: SwagTheme\nversion: 1.0.0\ntheme: Swag Theme\n",
+		"SwagTheme/1.1.0":  "name: SwagTheme\nversion: 1.1.0\ntheme: Swag Theme 2\n",
+		"OtherTheme/1.0.0": "name: OtherTheme\nversion: 1.0.0\ntheme: Other\n",
+		"NoTheme/1.0.0":    "name: NoTheme\nversion: 1.0.0\n",
+	} {
+		path := filepath.Join(*dataPath, "repos", "master", spec, "rackspec.yaml")
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	subShops := []rackshopdb.SubShop{
+		{ID: "1", Name: "Deutsch", Default: true, Theme: "Swag_Theme"},
+		{ID: "2", Name: "English"},
+		{ID: "3", Name: "Outlet"},
+	}
+
+	swag := rackfile.ThemeEntry{Name: "SwagTheme", Version: "1.0.0", Default: true}
+	other := rackfile.ThemeEntry{Name: "OtherTheme", Shops: []string{"English", "3"}}
+
+	tests := []struct {
+		name    string
+		themes  []rackfile.ThemeEntry
+		want    []Assignment
+		wantErr bool
+	}{
+		{name: "no themes", themes: []rackfile.ThemeEntry{}, want: []Assignment{}},
+		{name: "default theme", themes: []rackfile.ThemeEntry{swag}, want: []Assignment{
+			{SubShop: subShops[0], Theme: swag, Template: "Swag_Theme"},
+			{SubShop: subShops[1], Theme: swag, Template: "Swag_Theme"},
+			{SubShop: subShops[2], Theme: swag, Template: "Swag_Theme"},
+		}},
+		{name: "assigned themes win over the default", themes: []rackfile.ThemeEntry{swag, other}, want: []Assignment{
+			{SubShop: subShops[0], Theme: swag, Template: "Swag_Theme"},
+			{SubShop: subShops[1], Theme: other, Template: "Other"},
+			{SubShop: subShops[2], Theme: other, Template: "Other"},
+		}},
+		{name: "without default", themes: []rackfile.ThemeEntry{other}, want: []Assignment{
+			{SubShop: subShops[1], Theme: other, Template: "Other"},
+			{SubShop: subShops[2], Theme: other, Template: "Other"},
+		}},
+		{name: "latest version", themes: []rackfile.ThemeEntry{{Name: "SwagTheme", Shops: []string{"1"}}},
+			want: []Assignment{{SubShop: subShops[0], Theme: rackfile.ThemeEntry{Name: "SwagTheme", Shops: []string{"1"}},
+				Template: "Swag_Theme_2"}}},
+		{name: "unknown subshop", themes: []rackfile.ThemeEntry{{Name: "OtherTheme", Shops: []string{"French"}}},
+			wantErr: true},
+		{name: "subshop assigned twice", themes: []rackfile.ThemeEntry{other,
+			{Name: "SwagTheme", Shops: []string{"2"}}}, wantErr: true},
+		{name: "rackspec without theme", themes: []rackfile.ThemeEntry{{Name: "NoTheme", Default: true}},
+			wantErr: true},
+		{name: "invalid themes", themes: []rackfile.ThemeEntry{{Name: "SwagTheme"}}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		assignments, err := ResolveAssignments(test.themes, subShops)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%v: got error %v, want an error %v", test.name, err, test.wantErr)
+			continue
+		}
+
+		if !test.wantErr && !reflect.DeepEqual(assignments, test.want) {
+			t.Errorf("%v: got %+v, want %+v", test.name, assignments, test.want)
+		}
+	}
+
+	if assigned := (Assignment{SubShop: subShops[0], Template: "Swag_Theme"}); !assigned.IsAssigned() {
+		t.Error("expected the theme of the subshop to be assigned")
+	}
+}
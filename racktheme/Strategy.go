@@ -0,0 +1,213 @@
+package racktheme
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshop"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshopdb"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackssh"
+)
+
+const (
+	customThemeCommand      = "wdy:theme:set"
+	synchronizeThemeCommand = "sw:theme:synchronize"
+)
+
+// assignScript boots the shopware kernel and assigns a theme to a shop with the theme service of shopware,
+// like the theme manager of the backend does. The request is passed base64 encoded.
+const assignScript = `<?php
+require '/var/www/html/autoload.php';
+$request = json_decode(base64_decode('%s'), true);
+$environment = getenv('SHOPWARE_ENV') ?: 'production';
+$kernel = new \Shopware\Kernel($environment, false);
+$kernel->boot();
+$container = $kernel->getContainer();
+$template = $container->get('models')->getRepository(\Shopware\Models\Shop\Template::class)
+    ->findOneBy(['template' => $request['template']]);
+if ($template === null) {
+    fwrite(STDERR, 'the theme ' . $request['template'] . ' is not known to shopware');
+    exit(1);
+}
+$container->get('theme_service')->assignShopTemplate($request['shopId'], $template->getId());
+`
+
+// assignRequest is passed to the assignScript
+type assignRequest struct {
+	ShopID   string `json:"shopId"`
+	Template string `json:"template"`
+}
+
+// Strategy assigns a theme to a subshop of a shop
+type Strategy interface {
+	// Name returns the name of the strategy, one of rackshop.ThemeStrategies
+	Name() string
+	// Assign assigns the theme with the given template name to the subshop and returns the output
+	Assign(subShop rackshopdb.SubShop, template string) (string, error)
+}
+
+// GetStrategy returns the strategy, that is configured for the shop.
+// The auto strategy checks the commands of the shopware console to choose one of the others.
+func GetStrategy(executor rackssh.Executor, shop *rackshop.RackShop) (Strategy, error) {
+	switch shop.ThemeStrategy {
+	case rackshop.ThemeStrategyCommand:
+		return commandStrategy{executor, shop}, nil
+	case rackshop.ThemeStrategyConsole:
+		return consoleStrategy{executor, shop}, nil
+	case rackshop.ThemeStrategyDatabase:
+		return databaseStrategy{executor, shop}, nil
+	case "", rackshop.ThemeStrategyAuto:
+		commands, err := listConsoleCommands(executor, shop)
+		if err != nil {
+			return nil, err
+		}
+
+		if commands[customThemeCommand] {
+			return commandStrategy{executor, shop}, nil
+		}
+
+		if commands[synchronizeThemeCommand] {
+			return consoleStrategy{executor, shop}, nil
+		}
+
+		return databaseStrategy{executor, shop}, nil
+	}
+
+	return nil, rackshop.ValidateThemeStrategy(shop.ThemeStrategy)
+}
+
+// AssignTheme assigns the theme to the subshop with the given strategy
+//and verifies the assignment by reading the theme of the subshop back.
+func AssignTheme(executor rackssh.Executor, shop *rackshop.RackShop, strategy Strategy, subShopID string,
+	template string) (string, error) {
+	subShop, err := readSubShop(executor, shop, subShopID)
+	if err != nil {
+		return "", err
+	}
+
+	out, err := strategy.Assign(*subShop, template)
+	if err != nil {
+		return out, err
+	}
+
+	assigned, err := readSubShop(executor, shop, subShopID)
+	if err != nil {
+		return out, err
+	}
+
+	if assigned.Theme != template {
+		return out, errors.New("the " + strategy.Name() + " strategy did not assign the theme " + template +
+			" to shop " + subShop.Name + ", it uses " + assigned.Theme)
+	}
+
+	return out, nil
+}
+
+// DefaultSubShopID returns the ID of the default subshop
+func DefaultSubShopID(executor rackssh.Executor, shop *rackshop.RackShop) (string, error) {
+	subShops, err := rackshopdb.ReadSubShops(executor, shop)
+	if err != nil {
+		return "", err
+	}
+
+	subShop, err := rackshopdb.DefaultSubShop(subShops)
+	if err != nil {
+		return "", err
+	}
+
+	return subShop.ID, nil
+}
+
+//readSubShop returns the subshop with the given ID
+func readSubShop(executor rackssh.Executor, shop *rackshop.RackShop, subShopID string) (*rackshopdb.SubShop, error) {
+	subShops, err := rackshopdb.ReadSubShops(executor, shop)
+	if err != nil {
+		return nil, err
+	}
+
+	return rackshopdb.FindSubShop(subShops, subShopID)
+}
+
+//listConsoleCommands returns the names of the commands of the shopware console
+func listConsoleCommands(executor rackssh.Executor, shop *rackshop.RackShop) (map[string]bool, error) {
+	out, err := executor.Run(rackssh.ConsoleCommand(shop, "list --raw"))
+	if err != nil {
+		return nil, errors.New("failed to list the console commands: " + strings.TrimSpace(out))
+	}
+
+	commands := map[string]bool{}
+
+	for _, line := range strings.Split(out, "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			commands[fields[0]] = true
+		}
+	}
+
+	return commands, nil
+}
+
+//commandStrategy uses the custom console command, which sets the theme of the default shop
+type commandStrategy struct {
+	executor rackssh.Executor
+	shop     *rackshop.RackShop
+}
+
+func (s commandStrategy) Name() string {
+	return rackshop.ThemeStrategyCommand
+}
+
+func (s commandStrategy) Assign(subShop rackshopdb.SubShop, template string) (string, error) {
+	if !subShop.Default {
+		return "", errors.New("the " + customThemeCommand + " command can only assign the theme of the default shop")
+	}
+
+	return s.executor.Run(rackssh.ConsoleCommand(s.shop, customThemeCommand+" -q "+template))
+}
+
+//consoleStrategy uses the tools built into shopware: the themes are synchronized by the console
+//and the theme is assigned by the theme service of shopware, which also updates the language shops
+type consoleStrategy struct {
+	executor rackssh.Executor
+	shop     *rackshop.RackShop
+}
+
+func (s consoleStrategy) Name() string {
+	return rackshop.ThemeStrategyConsole
+}
+
+func (s consoleStrategy) Assign(subShop rackshopdb.SubShop, template string) (string, error) {
+	out, err := s.executor.Run(rackssh.ConsoleCommand(s.shop, synchronizeThemeCommand+" -q"))
+	if err != nil {
+		return out, err
+	}
+
+	requestData, err := json.Marshal(assignRequest{ShopID: subShop.ID, Template: template})
+	if err != nil {
+		return out, err
+	}
+
+	script := fmt.Sprintf(assignScript, base64.StdEncoding.EncodeToString(requestData))
+	command := "echo " + base64.StdEncoding.EncodeToString([]byte(script)) + " | base64 -d | docker exec -i " +
+		s.shop.Container + " php"
+
+	assignOut, err := s.executor.Run(command)
+
+	return out + assignOut, err
+}
+
+//databaseStrategy updates the theme of the subshop in the database
+type databaseStrategy struct {
+	executor rackssh.Executor
+	shop     *rackshop.RackShop
+}
+
+func (s databaseStrategy) Name() string {
+	return rackshop.ThemeStrategyDatabase
+}
+
+func (s databaseStrategy) Assign(subShop rackshopdb.SubShop, template string) (string, error) {
+	return "", rackshopdb.AssignTemplate(s.executor, s.shop, subShop.ID, template)
+}
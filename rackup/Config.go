@@ -0,0 +1,120 @@
+package rackup
+
+import (
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackfile"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackplan"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshop"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshopdb"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackssh"
+)
+
+// secretPlaceholder replaces the values of secrets in the plan and the history
+const secretPlaceholder = "***"
+
+//addConfigSteps adds the steps setting the configuration values of the rackfile before the last step of the plan.
+// The current values are read from the shop, values that already correspond to the rackfile are skipped.
+func addConfigSteps(shop *rackshop.RackShop, plan *rackplan.Plan, config []rackfile.PluginConfig,
+	wantedPlugins []string, opts Options) error {
+	if err := rackfile.ValidateConfig(config, wantedPlugins); err != nil {
+		return err
+	}
+
+	executor := rackssh.NewExecutor(shop)
+
+	subShops, err := rackshopdb.ReadSubShops(executor, shop)
+	if err != nil {
+		return err
+	}
+
+	steps := []rackplan.Step{}
+
+	for _, entry := range config {
+		if !opts.selects(entry.Plugin) {
+			continue
+		}
+
+		subShop, err := findConfigSubShop(subShops, entry.Shop)
+		if err != nil {
+			return err
+		}
+
+		current, err := rackshopdb.ReadPluginConfig(executor, shop, entry.Plugin, subShop.ID)
+		if err != nil {
+			return err
+		}
+
+		for _, key := range entry.Keys() {
+			step, err := planConfigValue(entry, key, subShop.ID, current)
+			if err != nil {
+				return err
+			}
+
+			steps = append(steps, step)
+		}
+	}
+
+	last := plan.Steps[len(plan.Steps)-1]
+	plan.Steps = append(append(plan.Steps[:len(plan.Steps)-1], steps...), last)
+
+	return nil
+}
+
+//findConfigSubShop returns the subshop of a configuration entry, the default shop if the entry names none
+func findConfigSubShop(subShops []rackshopdb.SubShop, reference string) (*rackshopdb.SubShop, error) {
+	if len(reference) == 0 {
+		return rackshopdb.DefaultSubShop(subShops)
+	}
+
+	return rackshopdb.FindSubShop(subShops, reference)
+}
+
+//planConfigValue returns the step setting a configuration value, or a skip step if the shop already has the value
+func planConfigValue(entry rackfile.PluginConfig, key string, subShopID string,
+	current map[string]string) (rackplan.Step, error) {
+	value := entry.Values[key]
+	step := rackplan.Step{Action: rackplan.ActionConfigure, Plugin: entry.Plugin, SubShop: subShopID, Key: key, Value: value}
+
+	resolved, err := rackfile.ResolveValue(value)
+	if err != nil {
+		return step, err
+	}
+
+	currentValue, ok := current[key]
+
+	switch {
+	case !ok:
+		step.Reason = "not set"
+	case currentValue == resolved:
+		step.Action = rackplan.ActionSkip
+		step.Reason = "unchanged"
+	case rackfile.IsSecret(value):
+		step.Reason = "was " + secretPlaceholder
+	default:
+		step.Reason = "was " + currentValue
+	}
+
+	return step, nil
+}
+
+//configurePlugin sets the configuration value of the step with the shopware console.
+//The secret of the value is resolved on the executing machine and only the reference is recorded.
+func (r *runner) configurePlugin(step rackplan.Step) error {
+	value, err := rackfile.ResolveValue(step.Value)
+	if err != nil {
+		return err
+	}
+
+	args := func(value string) string {
+		return "sw:plugin:config:set " + step.Plugin + " " + rackssh.QuoteArgument(step.Key) + " " +
+			rackssh.QuoteArgument(value) + " --shop=" + step.SubShop
+	}
+
+	command := rackssh.ConsoleCommand(r.shop, args(value))
+	recorded := rackssh.ConsoleCommand(r.shop, args(step.Value))
+
+	if rackfile.IsSecret(step.Value) {
+		return r.runCommand(command, recorded, value)
+	}
+
+	return r.runCommand(command, recorded)
+}
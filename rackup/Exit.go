@@ -0,0 +1,155 @@
+package rackup
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racklog"
+)
+
+// ErrCancelled is returned by up and rollback, if SIGINT or SIGTERM cancelled the run
+var ErrCancelled = errors.New("the run was cancelled")
+
+//killTimeout is how long the running command may take after a cancellation, before it is killed
+const killTimeout = time.Minute
+
+//exitHandlers undo temporary changes to a shop, like its lock or its maintenance mode,
+//before rackjobber exits because of an error or a signal
+var exitHandlers = struct {
+	sync.Mutex
+	handlers map[int]func()
+	next     int
+	signals  chan os.Signal
+	// cancel cancels the running deployment instead of exiting, if it is set
+	cancel context.CancelFunc
+	// interrupted is set by the first signal, the second one exits at once
+	interrupted bool
+}{handlers: map[int]func(){}}
+
+//addExitHandler registers a handler, that runs if rackjobber exits because of an error or a signal.
+//The returned function removes the handler again.
+func addExitHandler(handler func()) func() {
+	exitHandlers.Lock()
+	defer exitHandlers.Unlock()
+
+	id := exitHandlers.next
+	exitHandlers.next++
+	exitHandlers.handlers[id] = handler
+
+	awaitSignals()
+
+	return func() {
+		exitHandlers.Lock()
+		defer exitHandlers.Unlock()
+
+		delete(exitHandlers.handlers, id)
+	}
+}
+
+//withCancellation returns a context, that is cancelled by the first SIGINT or SIGTERM instead of exiting.
+//The running command is finished, before the deployment stops. A second signal exits at once.
+//The returned function ends the cancellation, so signals exit again.
+func withCancellation() (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	exitHandlers.Lock()
+	exitHandlers.cancel = cancel
+	awaitSignals()
+	exitHandlers.Unlock()
+
+	return ctx, func() {
+		exitHandlers.Lock()
+		exitHandlers.cancel = nil
+		exitHandlers.Unlock()
+
+		cancel()
+	}
+}
+
+//awaitSignals starts handling SIGINT and SIGTERM, if it is not handled yet,
+//and runs the exit handlers before racklog.Fatalf exits, like after a failed command deep in a helper.
+//The caller has to hold the lock of the exitHandlers.
+func awaitSignals() {
+	if exitHandlers.signals != nil {
+		return
+	}
+
+	exitHandlers.signals = make(chan os.Signal, 1)
+	signal.Notify(exitHandlers.signals, os.Interrupt, syscall.SIGTERM)
+	racklog.SetExitHook(runExitHandlers)
+
+	go func() {
+		for range exitHandlers.signals {
+			exitHandlers.Lock()
+			cancel := exitHandlers.cancel
+			interrupted := exitHandlers.interrupted
+			exitHandlers.interrupted = true
+			exitHandlers.Unlock()
+
+			switch {
+			case interrupted:
+				racklog.Warnf("Interrupted again, exiting without cleaning up. " +
+					"Use shop unlock to remove the lock of the shop, if it is left behind.")
+				os.Exit(1)
+			case cancel != nil:
+				racklog.Warnf("Interrupted, finishing the current command and skipping the remaining steps. " +
+					"Press Ctrl-C again to exit at once.")
+				cancel()
+			default:
+				racklog.Warnf("Interrupted, cleaning up. Press Ctrl-C again to exit at once.")
+
+				go func() {
+					runExitHandlers()
+					os.Exit(1)
+				}()
+			}
+		}
+	}()
+}
+
+//runExitHandlers runs and removes all registered handlers, the latest first
+func runExitHandlers() {
+	exitHandlers.Lock()
+	handlers := []func(){}
+
+	for id := exitHandlers.next - 1; id >= 0; id-- {
+		if handler, ok := exitHandlers.handlers[id]; ok {
+			handlers = append(handlers, handler)
+			delete(exitHandlers.handlers, id)
+		}
+	}
+
+	exitHandlers.Unlock()
+
+	for _, handler := range handlers {
+		handler()
+	}
+}
+
+//killAfter returns a context for the commands of a deployment, that is cancelled the timeout after ctx is cancelled,
+//so a running command can finish, but does not block the cancellation forever
+func killAfter(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	commandCtx, kill := context.WithCancel(context.Background())
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-commandCtx.Done():
+			return
+		}
+
+		select {
+		case <-time.After(timeout):
+			racklog.Warnf("The command did not finish within %v after the cancellation, killing it.\n", timeout)
+			kill()
+		case <-commandCtx.Done():
+		}
+	}()
+
+	return commandCtx, kill
+}
@@ -0,0 +1,28 @@
+package rackup
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestKillAfter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	commandCtx, kill := killAfter(ctx, 100*time.Millisecond)
+	defer kill()
+
+	cancel()
+
+	select {
+	case <-commandCtx.Done():
+		t.Fatal("the command was killed without a grace period")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	select {
+	case <-commandCtx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("the command was not killed after the grace period")
+	}
+}
@@ -0,0 +1,37 @@
+package rackup
+
+import (
+	"errors"
+	"time"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackhealth"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackplan"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshop"
+)
+
+//addHealthCheckSteps adds the health checks of the shop after the last step of the plan
+func addHealthCheckSteps(shop *rackshop.RackShop, plan *rackplan.Plan) {
+	for i := range shop.HealthChecks {
+		check := shop.HealthChecks[i]
+		plan.Steps = append(plan.Steps, rackplan.Step{Action: rackplan.ActionHealthCheck, HealthCheck: &check})
+	}
+}
+
+//checkHealth runs a health check and records its result in the run
+func (r *runner) checkHealth(check rackshop.HealthCheck) error {
+	start := time.Now()
+	result := rackhealth.Check(r.ctx, check)
+
+	var err error
+	if result.Healthy {
+		r.logger.Infof("Health check %v", result.String())
+	} else {
+		r.logger.Warnf("Health check %v", result.String())
+		err = errors.New(check.URL + " is unhealthy: " + result.Error)
+	}
+
+	r.step.AddCommand("GET "+check.URL, result.String(), time.Since(start), err)
+	r.run.Health = append(r.run.Health, result)
+
+	return err
+}
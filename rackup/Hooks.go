@@ -0,0 +1,106 @@
+package rackup
+
+import (
+	"strings"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackfile"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackhook"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackplan"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshop"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackssh"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/repository"
+)
+
+//addHookSteps adds the hooks of the shop before the first and after the last step of the plan.
+// The hooks of the rackfile and the opted in post install hooks of the rackspecs are added around the deployed plugins.
+func addHookSteps(shop *rackshop.RackShop, plan *rackplan.Plan, hooks []rackfile.PluginHooks,
+	wantedPlugins []string) error {
+	if err := rackhook.ValidateHooks(shop.Hooks.Before, shop.Hooks.After); err != nil {
+		return err
+	}
+
+	if err := rackfile.ValidateHooks(hooks, wantedPlugins); err != nil {
+		return err
+	}
+
+	steps := hookSteps(shop.Hooks.Before, "", "before up")
+
+	for _, step := range plan.Steps {
+		if step.Action != rackplan.ActionDeploy {
+			steps = append(steps, step)
+			continue
+		}
+
+		pluginHooks := findPluginHooks(hooks, step.Plugin)
+
+		steps = append(steps, hookSteps(pluginHooks.Before, step.Plugin, "before deploy")...)
+		steps = append(steps, step)
+
+		if pluginHooks.SpecHooks && step.Flag != "noinstall" {
+			spec, _, err := repository.GetRackSpec(step.Plugin, step.Version)
+			if err != nil {
+				return err
+			}
+
+			if err := rackhook.ValidateHooks(spec.PostInstall); err != nil {
+				return err
+			}
+
+			steps = append(steps, hookSteps(spec.PostInstall, step.Plugin, "post install of the rackspec")...)
+		}
+
+		steps = append(steps, hookSteps(pluginHooks.After, step.Plugin, "after deploy")...)
+	}
+
+	plan.Steps = append(steps, hookSteps(shop.Hooks.After, "", "after up")...)
+
+	return nil
+}
+
+//hookSteps returns the steps running the hooks
+func hookSteps(hooks []rackhook.Hook, pluginName string, reason string) []rackplan.Step {
+	steps := []rackplan.Step{}
+
+	for i := range hooks {
+		hook := hooks[i]
+		steps = append(steps, rackplan.Step{Action: rackplan.ActionHook, Plugin: pluginName, Hook: &hook, Reason: reason})
+	}
+
+	return steps
+}
+
+//findPluginHooks returns the hooks of the rackfile for a plugin
+func findPluginHooks(hooks []rackfile.PluginHooks, pluginName string) rackfile.PluginHooks {
+	found := rackfile.PluginHooks{Plugin: pluginName}
+
+	for _, entry := range hooks {
+		if entry.Plugin == pluginName {
+			found.Before = append(found.Before, entry.Before...)
+			found.After = append(found.After, entry.After...)
+			found.SpecHooks = found.SpecHooks || entry.SpecHooks
+		}
+	}
+
+	return found
+}
+
+//runHook runs the command of a hook on the shop and prints its output.
+//Shell commands run in the shopware directory, console commands in the container of the shop.
+func (r *runner) runHook(hook rackhook.Hook) error {
+	r.logger.Infof("Running hook %v", hook.String())
+
+	command := "cd " + rackssh.QuoteArgument(r.shop.ShopwareDir) + " && " + hook.Run
+	if len(hook.Console) > 0 {
+		command = rackssh.ConsoleCommand(r.shop, hook.Console)
+	}
+
+	err := r.runCommands(command)
+
+	if commands := r.step.Commands; len(commands) > 0 {
+		if out := strings.TrimSpace(commands[len(commands)-1].Output); len(out) > 0 {
+			r.logger.Infof("%v", out)
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,40 @@
+package rackup
+
+import (
+	"context"
+	"time"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racklock"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racklog"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshop"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackssh"
+)
+
+// LockShop locks the shop for the command and returns the function, that releases the lock.
+// The lock is refreshed until it is released, so it does not expire during long runs.
+// It is also released, if rackjobber exits because of an error or a signal.
+func LockShop(ctx context.Context, shop *rackshop.RackShop, command string, wait time.Duration) (func(), error) {
+	executor := rackssh.NewExecutor(shop)
+
+	lock, err := racklock.Acquire(ctx, executor, shop, command, wait, racklock.DefaultTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	stopRefresh := lock.KeepAlive(executor, shop)
+
+	release := func() {
+		stopRefresh()
+
+		if err := lock.Release(executor, shop); err != nil {
+			racklog.With("shop", shop.Name).Errorf("failed to release the lock of %v: %v\n", shop.Name, err)
+		}
+	}
+
+	removeHandler := addExitHandler(release)
+
+	return func() {
+		removeHandler()
+		release()
+	}, nil
+}
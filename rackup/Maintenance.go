@@ -0,0 +1,227 @@
+package rackup
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackhistory"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackplan"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshop"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshopdb"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackssh"
+)
+
+// Basic settings of shopware, that switch a shop into maintenance mode
+const (
+	settingOffline   = "setoffline"
+	settingOfflineIP = "offlineip"
+)
+
+//addMaintenanceSteps switches the shop into maintenance mode before the first step, that deletes or deploys a plugin,
+//and back after the last step of the plan. Plans without such a step leave the shop online.
+func addMaintenanceSteps(plan *rackplan.Plan) {
+	for i, step := range plan.Steps {
+		if step.Action != rackplan.ActionDelete && step.Action != rackplan.ActionDeploy {
+			continue
+		}
+
+		steps := append([]rackplan.Step{}, plan.Steps[:i]...)
+		steps = append(steps, rackplan.Step{Action: rackplan.ActionEnableMaintenance})
+		steps = append(steps, plan.Steps[i:]...)
+		plan.Steps = append(steps, rackplan.Step{Action: rackplan.ActionDisableMaintenance})
+
+		return
+	}
+}
+
+//enableMaintenance switches the shop into maintenance mode.
+//Until it is switched off again, it is switched off before rackjobber exits because of an error or a signal.
+//The switch is only undone, once it succeeded or the settings it changes were read, so a failed switch
+//does not put a shop online, that was offline before.
+func (r *runner) enableMaintenance() error {
+	maintenance := r.shop.Maintenance
+	if err := maintenance.Validate(); err != nil {
+		return err
+	}
+
+	switch maintenance.GetMode() {
+	case rackshop.MaintenanceFile:
+		path := filepath.Join(r.shop.ShopwareDir, maintenance.File)
+		if err := r.writeFile(path, strings.Join(maintenance.Whitelist, "\n")); err != nil {
+			return err
+		}
+
+		r.markMaintenanceOn()
+
+		return nil
+	case rackshop.MaintenanceCommand:
+		if err := r.runCommands(maintenanceCommand(r.shop, maintenance.On)); err != nil {
+			return err
+		}
+
+		r.markMaintenanceOn()
+
+		return nil
+	}
+
+	subShopID, err := r.defaultSubShopID()
+	if err != nil {
+		return err
+	}
+
+	previousOffline, err := rackshopdb.ReadCoreConfig(r.executor, r.shop, subShopID, settingOffline)
+	if err != nil {
+		return err
+	}
+
+	if previousOffline == nil {
+		previousOffline = false
+	}
+
+	var previousWhitelist interface{}
+
+	if len(maintenance.Whitelist) > 0 {
+		if previousWhitelist, err = rackshopdb.ReadCoreConfig(r.executor, r.shop, subShopID,
+			settingOfflineIP); err != nil {
+			return err
+		}
+
+		if previousWhitelist == nil {
+			previousWhitelist = ""
+		}
+	}
+
+	r.previousOffline = previousOffline
+	r.previousWhitelist = previousWhitelist
+	r.markMaintenanceOn()
+
+	if len(maintenance.Whitelist) > 0 {
+		if err = r.writeSetting(subShopID, settingOfflineIP, strings.Join(maintenance.Whitelist, ";")); err != nil {
+			return err
+		}
+	}
+
+	if err := r.writeSetting(subShopID, settingOffline, true); err != nil {
+		return err
+	}
+
+	return r.clearShopCache()
+}
+
+//disableMaintenance switches the maintenance mode of the shop off and restores its whitelist.
+//A shop, that was already in maintenance mode before, stays in it.
+func (r *runner) disableMaintenance() error {
+	maintenance := r.shop.Maintenance
+
+	switch maintenance.GetMode() {
+	case rackshop.MaintenanceFile:
+		if err := r.runCommands("rm -f " + filepath.Join(r.shop.ShopwareDir, maintenance.File)); err != nil {
+			return err
+		}
+	case rackshop.MaintenanceCommand:
+		if err := r.runCommands(maintenanceCommand(r.shop, maintenance.Off)); err != nil {
+			return err
+		}
+	default:
+		subShopID, err := r.defaultSubShopID()
+		if err != nil {
+			return err
+		}
+
+		if r.previousOffline == nil {
+			r.logger.Warnf("The previous maintenance setting of %v is unknown, it stays as it is.", r.shop.Name)
+		} else if err = r.writeSetting(subShopID, settingOffline, r.previousOffline); err != nil {
+			return err
+		}
+
+		if r.previousWhitelist != nil {
+			if err = r.writeSetting(subShopID, settingOfflineIP, r.previousWhitelist); err != nil {
+				return err
+			}
+		}
+
+		if err = r.clearShopCache(); err != nil {
+			return err
+		}
+	}
+
+	r.maintenanceOn = false
+
+	if r.removeMaintenanceHandler != nil {
+		r.removeMaintenanceHandler()
+		r.removeMaintenanceHandler = nil
+	}
+
+	return nil
+}
+
+//markMaintenanceOn records, that the shop is in maintenance mode, and switches it off, if rackjobber exits
+func (r *runner) markMaintenanceOn() {
+	r.maintenanceOn = true
+	r.addMaintenanceExitHandler()
+}
+
+//addMaintenanceExitHandler switches the maintenance mode off, if rackjobber exits while the shop is in maintenance mode
+func (r *runner) addMaintenanceExitHandler() {
+	r.removeMaintenanceHandler = addExitHandler(func() {
+		r.logger.Infof("Switching off the maintenance mode of %v.", r.shop.Name)
+
+		cleanup := &runner{
+			shop:              r.shop,
+			executor:          rackssh.NewExecutor(r.shop),
+			ctx:               context.Background(),
+			step:              &rackhistory.StepResult{},
+			logger:            r.logger,
+			previousWhitelist: r.previousWhitelist,
+			previousOffline:   r.previousOffline,
+		}
+
+		if err := cleanup.disableMaintenance(); err != nil {
+			r.logger.Errorf("failed to switch off the maintenance mode: %v\n", err)
+		}
+	})
+}
+
+//defaultSubShopID returns the ID of the default subshop, whose settings switch the maintenance mode
+func (r *runner) defaultSubShopID() (string, error) {
+	subShops, err := rackshopdb.ReadSubShops(r.executor, r.shop)
+	if err != nil {
+		return "", err
+	}
+
+	subShop, err := rackshopdb.DefaultSubShop(subShops)
+	if err != nil {
+		return "", err
+	}
+
+	return subShop.ID, nil
+}
+
+//writeSetting sets a basic setting of shopware for the subshop and records it for the current step
+func (r *runner) writeSetting(subShopID string, name string, value interface{}) error {
+	start := time.Now()
+	err := rackshopdb.WriteCoreConfig(r.executor, r.shop, subShopID, name, value)
+
+	r.step.AddCommand(fmt.Sprintf("set %v of shop %v to %v", name, subShopID, value), "", time.Since(start), err)
+
+	return err
+}
+
+//writeFile writes a file on the shop and records it for the current step
+func (r *runner) writeFile(path string, content string) error {
+	start := time.Now()
+	err := r.executor.WriteFile(path, []byte(content))
+
+	r.step.AddCommand("write "+path, "", time.Since(start), err)
+
+	return err
+}
+
+//maintenanceCommand returns the command, that runs a maintenance command of the shop in its shopware directory
+func maintenanceCommand(shop *rackshop.RackShop, command string) string {
+	return "cd " + rackssh.QuoteArgument(shop.ShopwareDir) + " && export RACKJOBBER_WHITELIST=" +
+		rackssh.QuoteArgument(strings.Join(shop.Maintenance.Whitelist, ",")) + " && " + command
+}
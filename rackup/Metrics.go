@@ -0,0 +1,22 @@
+package rackup
+
+import (
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackmetrics"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackplan"
+)
+
+//stepResult returns the result of the plugin of a step for the metrics, steps, that change no plugin, have none
+func stepResult(action string, err error) string {
+	switch {
+	case action == rackplan.ActionSkip:
+		return rackmetrics.ResultSkipped
+	case action != rackplan.ActionDeploy && action != rackplan.ActionDelete:
+		return ""
+	case err != nil:
+		return rackmetrics.ResultFailed
+	case action == rackplan.ActionDeploy:
+		return rackmetrics.ResultUpdated
+	default:
+		return rackmetrics.ResultDeleted
+	}
+}
@@ -0,0 +1,127 @@
+package rackup
+
+import (
+	"context"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackconfig"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackhistory"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racklog"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racknotify"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackplan"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshop"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshopstore"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackstate"
+)
+
+// NotificationTargets returns the targets of the config, of the groups of the shop and of the shop,
+// that are notified of the runs on the shop. Invalid targets are left out with a warning.
+func NotificationTargets(shop *rackshop.RackShop) []racknotify.Target {
+	targets := append([]racknotify.Target{}, rackconfig.GetNotifications()...)
+
+	groups, err := rackshopstore.ListGroupsOfShop(*shop)
+	if err != nil {
+		racklog.With("shop", shop.Name).Warnf("Failed to read the groups of %v: %v", shop.Name, err)
+	}
+
+	for _, group := range groups {
+		targets = append(targets, group.Notifications...)
+	}
+
+	targets = append(targets, shop.Notifications...)
+
+	valid := []racknotify.Target{}
+
+	for _, target := range targets {
+		if err := target.Validate(); err != nil {
+			racklog.With("shop", shop.Name).Warnf("Skipping notification: %v", err)
+			continue
+		}
+
+		valid = append(valid, target)
+	}
+
+	return valid
+}
+
+//pluginChanges returns the plugins deployed or deleted by the plan, with their versions in the state before the run
+func pluginChanges(plan *rackplan.Plan, state *rackstate.State) []racknotify.PluginChange {
+	changes := []racknotify.PluginChange{}
+
+	for _, step := range plan.Steps {
+		if step.Action != rackplan.ActionDeploy && step.Action != rackplan.ActionDelete {
+			continue
+		}
+
+		change := racknotify.PluginChange{Plugin: step.Plugin, Action: step.Action}
+
+		if plugin, ok := state.GetPlugin(step.Plugin); ok {
+			change.OldVersion = plugin.Version
+		}
+
+		if step.Action == rackplan.ActionDeploy {
+			change.NewVersion = step.Version
+		}
+
+		changes = append(changes, change)
+	}
+
+	return changes
+}
+
+//notify sends the event of the run to the notification targets of the runner.
+//The notifications of a cancelled run are still sent.
+func (r *runner) notify(event string) {
+	if len(r.notifications) == 0 {
+		return
+	}
+
+	payload := racknotify.Payload{
+		Event:     event,
+		Shop:      r.shop.Name,
+		RunID:     r.run.ID,
+		Command:   r.run.Command,
+		User:      r.run.User,
+		Host:      r.run.Host,
+		StartedAt: r.run.StartedAt,
+		Plugins:   r.changes,
+	}
+
+	if event != racknotify.EventStarted {
+		finishedAt := r.run.FinishedAt
+		payload.FinishedAt = &finishedAt
+		payload.DurationMillis = r.run.DurationMillis
+		payload.Outcome = r.run.Outcome
+		payload.Errors = runErrors(r.run)
+	}
+
+	racknotify.Send(context.Background(), r.notifications, payload)
+}
+
+//finishedEvent returns the event of the notifications, that is sent for the outcome of a run
+func finishedEvent(outcome string) string {
+	switch outcome {
+	case rackhistory.OutcomeSucceeded:
+		return racknotify.EventSucceeded
+	case rackhistory.OutcomeCancelled:
+		return racknotify.EventCancelled
+	default:
+		return racknotify.EventFailed
+	}
+}
+
+//runErrors returns the errors of the failed steps of the run, or the error of the run, if no step failed
+func runErrors(run *rackhistory.Run) []string {
+	errs := []string{}
+
+	for _, step := range run.Steps {
+		if len(step.Error) > 0 {
+			errs = append(errs, step.Step.String()+": "+step.Error)
+		}
+	}
+
+	if len(errs) == 0 && len(run.Error) > 0 {
+		errs = append(errs, run.Error)
+	}
+
+	return errs
+}
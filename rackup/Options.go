@@ -0,0 +1,145 @@
+package rackup
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackbackup"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackevent"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackfile"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racklog"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackmetrics"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/repository"
+)
+
+// Options limit the plugins of the rackfile, that are deployed by Up, and decide how runs are reported
+type Options struct {
+	// Only are the names of the plugins, that are deployed.
+	// The mandatory plugins and the plugins required by their rackspecs are deployed with them.
+	Only []string
+	// Except are the names of the plugins, that are left out
+	Except []string
+	// Maintenance switches the shop into maintenance mode while plugins are deleted and deployed
+	Maintenance bool
+	// Backup backs up the shop before the plan is executed
+	Backup        bool
+	BackupOptions rackbackup.Options
+	// LockWait is how long up waits for the lock of the shop, if another run holds it.
+	// Up fails at once, if it is zero.
+	LockWait time.Duration
+	// Events receives the events of the runs, no events are written if it is nil
+	Events *rackevent.Stream
+	// JSON receives the record of every run as a single line of JSON when it finishes, if it is set
+	JSON io.Writer
+	// Text receives the plans of the runs, the standard output if it is nil
+	Text io.Writer
+	// Notify sends the start and the result of every run to the notification targets of the shop
+	Notify bool
+	// Metrics replaces the values of the metrics output of the config, that the metrics of every run are written to
+	Metrics rackmetrics.Output
+}
+
+//textWriter returns the writer of the plans
+func (o Options) textWriter() io.Writer {
+	if o.Text == nil {
+		return os.Stdout
+	}
+
+	return o.Text
+}
+
+//isLimited returns if the options select only some of the plugins
+func (o Options) isLimited() bool {
+	return len(o.Only) > 0 || len(o.Except) > 0
+}
+
+//selects returns if a plugin of the rackfile is deployed with these options
+func (o Options) selects(plugin string) bool {
+	pluginName := strings.Split(plugin, ":")[0]
+
+	if containsPlugin(o.Except, pluginName) {
+		return false
+	}
+
+	if len(o.Only) == 0 || containsPlugin(o.Only, pluginName) {
+		return true
+	}
+
+	for _, mandatoryPlugin := range getMandatoryPlugins() {
+		if strings.Split(mandatoryPlugin, ":")[0] == pluginName {
+			return true
+		}
+	}
+
+	return false
+}
+
+//validate returns an error, if the options name a plugin, that is not wanted on the shop
+func (o Options) validate(wantedPlugins []string) error {
+	wantedNames := []string{}
+	for _, plugin := range wantedPlugins {
+		wantedNames = append(wantedNames, strings.Split(plugin, ":")[0])
+	}
+
+	for _, pluginName := range append(append([]string{}, o.Only...), o.Except...) {
+		if !containsPlugin(wantedNames, pluginName) {
+			return errors.New("plugin " + pluginName + " is not listed in the rackfile")
+		}
+	}
+
+	return nil
+}
+
+//withDependencies returns the options with the plugins, that the rackspecs of the selected plugins require, added to Only.
+//The requirements are resolved transitively in the versions of the rackfile.
+//returns an error, if a required plugin is not listed in the rackfile or left out by Except
+func (o Options) withDependencies(wantedPlugins []string) (Options, error) {
+	if len(o.Only) == 0 {
+		return o, nil
+	}
+
+	versions := map[string]string{}
+	for _, plugin := range wantedPlugins {
+		entry := rackfile.ParseEntry(plugin)
+		versions[entry.Name] = entry.Version
+	}
+
+	only := append([]string{}, o.Only...)
+
+	for i := 0; i < len(only); i++ {
+		version, err := repository.ResolvePluginVersion(only[i], versions[only[i]])
+		if err != nil {
+			continue
+		}
+
+		spec, _, err := repository.GetRackSpec(only[i], version)
+		if err != nil {
+			continue
+		}
+
+		for _, required := range spec.Requires {
+			if containsPlugin(only, required) {
+				continue
+			}
+
+			if _, ok := versions[required]; !ok {
+				return o, errors.New("plugin " + only[i] + " requires " + required + ", which is not listed in the rackfile")
+			}
+
+			if containsPlugin(o.Except, required) {
+				return o, errors.New("plugin " + only[i] + " requires " + required + ", which is left out by --except")
+			}
+
+			racklog.Infof("Deploying %v, as it is required by %v.", required, only[i])
+
+			only = append(only, required)
+		}
+	}
+
+	o.Only = only
+
+	return o, nil
+}
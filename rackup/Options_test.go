@@ -0,0 +1,79 @@
+package rackup
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOptionsSelects(t *testing.T) {
+	_, cleanup := useHome(t, "plugins:\n- MandatoryPlugin:1.0.0\n", nil)
+	defer cleanup()
+
+	tests := []struct {
+		name   string
+		opts   Options
+		plugin string
+		want   bool
+	}{
+		{name: "no limits", opts: Options{}, plugin: "SwagPlugin:1.2.0", want: true},
+		{name: "only the plugin", opts: Options{Only: []string{"SwagPlugin"}}, plugin: "SwagPlugin:1.2.0", want: true},
+		{name: "only another plugin", opts: Options{Only: []string{"OtherPlugin"}}, plugin: "SwagPlugin", want: false},
+		{name: "except the plugin", opts: Options{Except: []string{"SwagPlugin"}}, plugin: "SwagPlugin:1.2.0:noactivate",
+			want: false},
+		{name: "except another plugin", opts: Options{Except: []string{"OtherPlugin"}}, plugin: "SwagPlugin", want: true},
+		{name: "mandatory plugin with only", opts: Options{Only: []string{"SwagPlugin"}}, plugin: "MandatoryPlugin:1.0.0",
+			want: true},
+		{name: "mandatory plugin left out", opts: Options{Only: []string{"SwagPlugin"}, Except: []string{"MandatoryPlugin"}},
+			plugin: "MandatoryPlugin", want: false},
+	}
+
+	for _, test := range tests {
+		if got := test.opts.selects(test.plugin); got != test.want {
+			t.Errorf("%v: selects %v: got %v, want %v", test.name, test.plugin, got, test.want)
+		}
+	}
+}
+
+func TestOptionsWithDependencies(t *testing.T) {
+	_, cleanup := useHome(t, "", map[string]string{
+		"Checkout/1.0.0": "name: Checkout\nversion: 1.0.0\nrequires:\n- Payment\n",
+		"Payment/2.0.0":  "name: Payment\nversion: 2.0.0\nrequires:\n- Core\n",
+		"Payment/1.0.0":  "name: Payment\nversion: 1.0.0\n",
+		"Core/1.0.0":     "name: Core\nversion: 1.0.0\nrequires:\n- Checkout\n",
+		"Search/1.0.0":   "name: Search\nversion: 1.0.0\nrequires:\n- Missing\n",
+	})
+	defer cleanup()
+
+	wanted := []string{"Checkout:1.0.0", "Payment", "Core:1.0.0", "Search:1.0.0", "Other:1.0.0"}
+
+	tests := []struct {
+		name    string
+		opts    Options
+		wanted  []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "not limited", opts: Options{}, wanted: wanted, want: nil},
+		{name: "no requirements", opts: Options{Only: []string{"Other"}}, wanted: wanted, want: []string{"Other"}},
+		{name: "transitive requirements of the latest version", opts: Options{Only: []string{"Checkout"}},
+			wanted: wanted, want: []string{"Checkout", "Payment", "Core"}},
+		{name: "requirements of the rackfile version", opts: Options{Only: []string{"Checkout"}},
+			wanted: []string{"Checkout:1.0.0", "Payment:1.0.0"}, want: []string{"Checkout", "Payment"}},
+		{name: "requirement not in the rackfile", opts: Options{Only: []string{"Search"}}, wanted: wanted,
+			wantErr: true},
+		{name: "requirement left out", opts: Options{Only: []string{"Checkout"}, Except: []string{"Payment"}},
+			wanted: wanted, wantErr: true},
+	}
+
+	for _, test := range tests {
+		opts, err := test.opts.withDependencies(test.wanted)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%v: got error %v, want an error %v", test.name, err, test.wantErr)
+			continue
+		}
+
+		if !test.wantErr && !reflect.DeepEqual(opts.Only, test.want) {
+			t.Errorf("%v: got %v, want %v", test.name, opts.Only, test.want)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package rackup
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/fileutil"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/gitutil"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackfile"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racklog"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackplan"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshop"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackstate"
+)
+
+//buildPlan compares the wanted plugins with the plugins installed on the shop and its deployment state
+//and returns the steps, that deploy the wanted plugins.
+//If the options limit the plugins, no plugins are deleted.
+//The theme is only initialized, if one of the deployed plugins sets its theme.
+//returns false if the plan could not be built, because the repository was reinstalled
+func buildPlan(ctx context.Context, shop *rackshop.RackShop, wantedPlugins []string, installedPlugins []string,
+	state *rackstate.State, opts Options) (*rackplan.Plan, bool) {
+	plan := &rackplan.Plan{Shop: shop.Name, RunID: state.RunID, Steps: []rackplan.Step{}}
+
+	if !opts.isLimited() {
+		for _, unwantedPlugin := range getUnwantedPlugins(installedPlugins, wantedPlugins) {
+			if unwantedPlugin != "" {
+				plan.Steps = append(plan.Steps, rackplan.Step{Action: rackplan.ActionDelete, Plugin: unwantedPlugin})
+			}
+		}
+	}
+
+	pluginSteps := []rackplan.Step{}
+	setsTheme := false
+
+	for _, plugin := range wantedPlugins {
+		if !opts.selects(plugin) {
+			pluginSteps = append(pluginSteps, rackplan.Step{
+				Action: rackplan.ActionSkip,
+				Plugin: strings.Split(plugin, ":")[0],
+				Reason: "not selected",
+			})
+
+			continue
+		}
+
+		step, ok := planPlugin(ctx, plugin, installedPlugins, state)
+		if !ok {
+			return nil, false
+		}
+
+		setsTheme = setsTheme || len(step.Theme) > 0
+		pluginSteps = append(pluginSteps, step)
+	}
+
+	if setsTheme {
+		plan.Steps = append(plan.Steps, rackplan.Step{Action: rackplan.ActionInitializeTheme})
+	}
+
+	plan.Steps = append(plan.Steps, pluginSteps...)
+	plan.Steps = append(plan.Steps, rackplan.Step{Action: rackplan.ActionClearCache})
+
+	return plan, true
+}
+
+//planPlugin returns the step for a plugin of the rackfile.
+// Plugins, that are up-to-date, unmanaged or have no rackspec, are skipped.
+func planPlugin(ctx context.Context, plugin string, installedPlugins []string, state *rackstate.State) (rackplan.Step, bool) {
+	pluginName := strings.Split(plugin, ":")[0]
+	if getPluginFlag(plugin) == rackfile.FlagUnmanaged {
+		return rackplan.Step{Action: rackplan.ActionSkip, Plugin: pluginName, Reason: "unmanaged"}, true
+	}
+
+	pluginRepo := getPluginRepo(pluginName)
+	pluginVersion := getPluginVersion(plugin)
+	step := rackplan.Step{
+		Action:  rackplan.ActionSkip,
+		Plugin:  pluginName,
+		Version: pluginVersion,
+		Flag:    getPluginFlag(plugin),
+		Repo:    pluginRepo,
+	}
+
+	rackresourcesPath, _ := fileutil.GetAppFolderPath()
+	rackspecPath := filepath.Join(*rackresourcesPath, "repos", pluginRepo, pluginName, pluginVersion)
+
+	rackspec := getRackSpec(rackspecPath)
+	if rackspec == nil {
+		if reinstallMaster(pluginName) {
+			return step, false
+		}
+
+		step.Reason = "no rackspec found"
+
+		return step, true
+	}
+
+	gitHash, err := gitutil.GetHashOfLastCommit(ctx, rackspec.Source.GIT, pluginVersion)
+	if err != nil {
+		racklog.Warnf("Failed to retrieve hash for Plugin %v: %v", pluginName, err)
+
+		step.Reason = "failed to retrieve the commit of the version"
+
+		return step, true
+	}
+
+	step.Commit = *gitHash
+	step.Source = gitutil.StripURLAuth(rackspec.Source.GIT)
+
+	if pluginIsUpToDate(pluginName, *gitHash, state) {
+		refreshPluginState(state, step)
+
+		step.Reason = "up-to-date"
+		return step, true
+	}
+
+	step.Action = rackplan.ActionDeploy
+	step.Clone = !containsPlugin(installedPlugins, pluginName)
+
+	if step.Flag == "" {
+		step.Theme = rackspec.Theme
+	}
+
+	return step, true
+}
+
+//refreshPluginState fills the version and source of an up-to-date plugin into its state,
+//if the state only records its commit, because it was migrated from the rackpluginhashes.yaml
+func refreshPluginState(state *rackstate.State, step rackplan.Step) {
+	plugin, ok := state.GetPlugin(step.Plugin)
+	if !ok || len(plugin.Tag) > 0 {
+		return
+	}
+
+	plugin.Version = step.Version
+	plugin.Tag = step.Version
+	plugin.Source = step.Source
+	plugin.SpecRepo = step.Repo
+}
+
+//containsPlugin returns if the plugin is in the list of plugins
+func containsPlugin(plugins []string, pluginName string) bool {
+	for _, plugin := range plugins {
+		if plugin == pluginName {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,49 @@
+package rackup
+
+import (
+	"testing"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackplan"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackstate"
+)
+
+func TestRefreshPluginState(t *testing.T) {
+	step := rackplan.Step{Action: rackplan.ActionSkip, Plugin: "SwagPlugin", Version: "1.2.0",
+		Source: "https://git.example.com/swag.git", Repo: "master"}
+
+	tests := []struct {
+		name   string
+		plugin rackstate.PluginState
+		want   rackstate.PluginState
+	}{
+		{
+			name:   "migrated plugin",
+			plugin: rackstate.PluginState{Name: "SwagPlugin", Commit: "abc", Result: rackstate.ResultMigrated},
+			want: rackstate.PluginState{Name: "SwagPlugin", Version: "1.2.0", Tag: "1.2.0", Commit: "abc",
+				Source: "https://git.example.com/swag.git", SpecRepo: "master", Result: rackstate.ResultMigrated},
+		},
+		{
+			name:   "deployed plugin",
+			plugin: rackstate.PluginState{Name: "SwagPlugin", Version: "1.1.0", Tag: "1.1.0", Commit: "abc"},
+			want:   rackstate.PluginState{Name: "SwagPlugin", Version: "1.1.0", Tag: "1.1.0", Commit: "abc"},
+		},
+	}
+
+	for _, test := range tests {
+		state := rackstate.NewState()
+		state.SetPlugin(test.plugin)
+
+		refreshPluginState(state, step)
+
+		if plugin, _ := state.GetPlugin("SwagPlugin"); *plugin != test.want {
+			t.Errorf("%v: got %+v, want %+v", test.name, *plugin, test.want)
+		}
+	}
+
+	state := rackstate.NewState()
+	refreshPluginState(state, step)
+
+	if len(state.Plugins) != 0 {
+		t.Errorf("refreshing an unknown plugin added %+v", state.Plugins)
+	}
+}
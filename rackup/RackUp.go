@@ -2,137 +2,202 @@
 package rackup
 
 import (
-	"bytes"
-	"fmt"
+	"errors"
 	"io/ioutil"
-	"log"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/go-version"
 	"gopkg.in/yaml.v2"
 
-	sshrw "github.com/mosolovsa/go_cat_sshfilerw"
-
 	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/fileutil"
 	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/gitutil"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackbackup"
 	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackconfig"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackfile"
 	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackinput"
-	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackpluginhashes"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackinspect"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racklog"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackmetrics"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackplan"
 	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshop"
 	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshopstore"
 	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackspec"
 	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackssh"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackstate"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racktheme"
 	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/repository"
 )
 
 //RackFile Interface to save read data
 type RackFile struct {
-	Plugins []string `yaml:"Plugins"`
+	Plugins []string                `yaml:"Plugins"`
+	Themes  []rackfile.ThemeEntry   `yaml:"Themes"`
+	Config  []rackfile.PluginConfig `yaml:"Config"`
+	Hooks   []rackfile.PluginHooks  `yaml:"Hooks"`
 }
 
-//Up deploys plugins, that are listed in the Rackfile to a given shop
-func Up(shopName string) {
-	err := repository.UpdateRepos()
+//Up deploys plugins, that are listed in the Rackfile to a given shop.
+//The options can limit the deployment to some of the plugins.
+//SIGINT and SIGTERM cancel the deployment: the running command is finished, the remaining steps are skipped
+//and ErrCancelled is returned.
+func Up(shopName string, opts Options) error {
+	ctx, stop := withCancellation()
+	defer stop()
+
+	metrics := rackmetrics.Start(shopName, "up")
+
+	err := repository.UpdateRepos(ctx)
 	if err != nil && err.Error() != "already up-to-date" {
-		fmt.Printf("failed to update Master Repo: %v\n", err)
+		racklog.Errorf("failed to update Master Repo: %v\n", err)
 	} else {
-		fmt.Println("Repository is up-to-date")
+		racklog.Infof("Repository is up-to-date")
 	}
 
-	shop, _ := rackshopstore.GetShopFromStore(shopName)
-	rackfile := getRackFile(shop)
-	hashfile := getRackPluginHashes(shop)
-	installedPlugins := getInstalledPlugins(shop)
-	wantedPlugins := getMandatoryPlugins()
+	shop, err := rackshopstore.GetShopFromStore(shopName)
+	if err != nil {
+		return err
+	}
 
-	if rackfile != nil {
-	OUTER:
-		for _, rackplugin := range rackfile.Plugins {
-			for _, mandPlugin := range wantedPlugins {
-				if mandPlugin == rackplugin {
-					continue OUTER
-				}
-			}
-			wantedPlugins = append(wantedPlugins, rackplugin)
+	unlock, err := LockShop(ctx, shop, "up", opts.LockWait)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	state, err := getDeploymentState(shop)
+	if err != nil {
+		return err
+	}
+
+	state.RunID = rackstate.NewRunID()
+
+	rackFile := getRackFile(shop)
+	wantedPlugins := getWantedPlugins(rackFile)
+
+	if err := opts.validate(wantedPlugins); err != nil {
+		return err
+	}
+
+	opts, err = opts.withDependencies(wantedPlugins)
+	if err != nil {
+		return err
+	}
+
+	plan, ok := buildPlan(ctx, shop, wantedPlugins, getInstalledPlugins(shop), state, opts)
+	if !ok {
+		return nil
+	}
+
+	if rackFile != nil && len(rackFile.Themes) > 0 {
+		if err := addThemeSteps(shop, plan, rackFile.Themes, opts); err != nil {
+			return err
 		}
 	}
 
-	unwantedPlugins := getUnwantedPlugins(installedPlugins, wantedPlugins)
-	for _, unwantedPlugin := range unwantedPlugins {
-		if unwantedPlugin != "" {
-			deletePlugin(unwantedPlugin, shop)
+	if rackFile != nil && len(rackFile.Config) > 0 {
+		if err := addConfigSteps(shop, plan, rackFile.Config, wantedPlugins, opts); err != nil {
+			return err
 		}
 	}
 
-	initializeTheme(shop)
-	updatePlugins(wantedPlugins, shop, hashfile)
-	clearShopCache(shop)
-	fmt.Println("Process finished.")
-}
+	if opts.Maintenance {
+		addMaintenanceSteps(plan)
+	}
 
-//updatePlugins iterates over given plugins and updates these remotely on given RackShop.
-//It also checks for neseccity of updates for Plugins and updates the given Hashfile's Hashes
-func updatePlugins(pluginsToUpdate []string, shop *rackshop.RackShop, shopHashes *rackpluginhashes.PluginHashes) {
-	fmt.Println("updating plugins")
-
-	for _, plugin := range pluginsToUpdate {
-		pluginName := strings.Split(plugin, ":")[0]
-		pluginRepo := getPluginRepo(pluginName)
-		pluginVersion := getPluginVersion(plugin)
-		pluginFlag := getPluginFlag(plugin)
-		rackresourcesPath, _ := fileutil.GetAppFolderPath()
-		rackspecPath := filepath.Join(*rackresourcesPath, "repos", pluginRepo, pluginName, pluginVersion)
-
-		rackspec := getRackSpec(rackspecPath)
-		if rackspec == nil {
-			if reinstallMaster(pluginName) {
-				return
-			}
+	hooks := []rackfile.PluginHooks{}
+	if rackFile != nil {
+		hooks = rackFile.Hooks
+	}
 
-			continue
-		}
+	if err := addHookSteps(shop, plan, hooks, wantedPlugins); err != nil {
+		return err
+	}
+
+	addHealthCheckSteps(shop, plan)
+
+	plan.Print(opts.textWriter())
+
+	if ctx.Err() != nil {
+		racklog.With("shop", shop.Name).Warnf("Up of %v was cancelled before deploying.", shop.Name)
+		return ErrCancelled
+	}
+
+	metrics.Phase(rackmetrics.PhasePrepare, metrics.Started)
+
+	if opts.Backup {
+		backupStart := time.Now()
+		backupCtx, kill := killAfter(ctx, killTimeout)
+		_, err := rackbackup.Create(rackssh.WithContext(backupCtx, rackssh.NewExecutor(shop)), shop, state.RunID,
+			opts.BackupOptions)
+
+		kill()
 
-		gitHash, err := gitutil.GetHashOfLastCommit(rackspec.Source.GIT, pluginVersion)
 		if err != nil {
-			log.Printf("Failed to retrieve hash for Plugin %v: %v", pluginName, err)
+			return err
 		}
 
-		var isuptodate bool
+		metrics.Phase(rackmetrics.PhaseBackup, backupStart)
+	}
+
+	if err := executePlan(ctx, shop, plan, state, "up", opts, metrics); err != nil {
+		return err
+	}
+
+	racklog.Infof("Process finished.")
+
+	return nil
+}
 
-		shopHashes, isuptodate = pluginIsUpToDate(pluginIsUpToDateArgs{pluginName, *gitHash, shopHashes})
-		if isuptodate {
-			continue
+//getWantedPlugins returns the mandatory plugins followed by the plugins and themes of the shop's rackfile
+func getWantedPlugins(rackFile *RackFile) []string {
+	wantedPlugins := getMandatoryPlugins()
+
+	if rackFile != nil {
+		rackPlugins := append([]string{}, rackFile.Plugins...)
+		for _, theme := range rackFile.Themes {
+			rackPlugins = append(rackPlugins, theme.PluginEntry())
 		}
 
-		fmt.Println("Loading " + pluginName + " " + pluginVersion)
-		rackssh.CloneGitToRemoteShop(shop, pluginName, rackspec.Source.GIT, pluginVersion)
-		updatePlugin(pluginName, shop, pluginVersion)
-
-		switch pluginFlag {
-		case "noinstall":
-		case "noactivate":
-			installPlugin(pluginName, shop)
-		case "nosettheme":
-			installPlugin(pluginName, shop)
-			activatePlugin(pluginName, shop)
-		case "":
-			installPlugin(pluginName, shop)
-			activatePlugin(pluginName, shop)
-
-			themeName := rackspec.Theme
-			if len(themeName) != 0 {
-				setTheme(themeName, shop)
+	OUTER:
+		for _, rackplugin := range rackPlugins {
+			for _, mandPlugin := range wantedPlugins {
+				if mandPlugin == rackplugin {
+					continue OUTER
+				}
 			}
+			wantedPlugins = append(wantedPlugins, rackplugin)
 		}
 	}
 
-	fmt.Println("Finished updating plugins")
+	return wantedPlugins
+}
+
+//newPluginState returns the state of a plugin, that is about to be deployed by the given run
+func newPluginState(step rackplan.Step, runID string) *rackstate.PluginState {
+	deployedBy, deployedFrom := rackstate.Deployer()
 
-	err := updatePluginHashesToShop(shopHashes, shop)
+	specCommit, err := repository.GetRepoHeadCommit(step.Repo)
 	if err != nil {
-		log.Fatalf("updatePluginHashesToShop - error: %v\n", err)
+		racklog.Warnf("Failed to retrieve the commit of repository %v: %v\n", step.Repo, err)
+	}
+
+	return &rackstate.PluginState{
+		Name:         step.Plugin,
+		Version:      step.Version,
+		Tag:          step.Version,
+		Commit:       step.Commit,
+		Source:       step.Source,
+		SpecRepo:     step.Repo,
+		SpecCommit:   specCommit,
+		Flag:         step.Flag,
+		Result:       rackstate.ResultDeployed,
+		DeployedAt:   time.Now().UTC(),
+		DeployedBy:   deployedBy,
+		DeployedFrom: deployedFrom,
+		RunID:        runID,
 	}
 }
 
@@ -146,48 +211,37 @@ func reinstallMaster(pluginName string) bool {
 	if in == "y" {
 		err := gitutil.ReinstallMaster()
 		if err != nil {
-			log.Fatalf("Faied to reinstall Master: %v\n", err)
+			racklog.Fatalf("Faied to reinstall Master: %v\n", err)
 		}
 
-		fmt.Printf("successfully reinstalled Master Repository. Please restart rackjobber\n")
+		racklog.Infof("successfully reinstalled Master Repository. Please restart rackjobber\n")
 
 		return true
 	}
 
-	fmt.Println("Skipping this plugin")
+	racklog.Infof("Skipping this plugin")
 
 	return false
 }
 
-type pluginIsUpToDateArgs struct {
-	pluginName, gitHash string
-	shopHashes          *rackpluginhashes.PluginHashes
-}
-
-//pluginIsUpToDate checks for last recent commit hash on shop and on gitlab
-//compares these and updates the one on the shop.
+//pluginIsUpToDate checks for the commit recorded in the state of the shop and the last recent commit on gitlab
+//and compares these.
 //returns true if the plugin is up-to-date, otherwise returns false
-func pluginIsUpToDate(args pluginIsUpToDateArgs) (*rackpluginhashes.PluginHashes, bool) {
-	if args.shopHashes != nil {
-		hashOnShop, err := args.shopHashes.GetHash(args.pluginName)
-		if err != nil {
-			fmt.Printf("no Hash found on shop for Plugin: %v\n", args.pluginName)
-			args.shopHashes.SetOrUpdateHash(args.pluginName, args.gitHash)
-		} else {
-			if hashOnShop != args.gitHash {
-				fmt.Println("Plugin is not up-to-date, updating plugin and hash")
-				args.shopHashes.SetOrUpdateHash(args.pluginName, args.gitHash)
-			} else {
-				fmt.Printf("Plugin %v is up-to-date, skipping this one\n", args.pluginName)
-				return args.shopHashes, true
-			}
-		}
-	} else {
-		log.Println("No Hashfile found on server")
-		args.shopHashes = rackpluginhashes.MakeHashes(args.pluginName, args.gitHash)
+func pluginIsUpToDate(pluginName, gitHash string, state *rackstate.State) bool {
+	hashOnShop, err := state.GetCommit(pluginName)
+	if err != nil {
+		racklog.With("plugin", pluginName).Verbosef("no Hash found on shop for Plugin: %v\n", pluginName)
+		return false
+	}
+
+	if hashOnShop != gitHash {
+		racklog.With("plugin", pluginName).Verbosef("Plugin is not up-to-date, updating plugin and hash")
+		return false
 	}
 
-	return args.shopHashes, false
+	racklog.With("plugin", pluginName).Infof("Plugin %v is up-to-date, skipping this one\n", pluginName)
+
+	return true
 }
 
 //getInstalledPlugins returns a list of all installed plugins
@@ -218,11 +272,27 @@ func getUnwantedPlugins(installedPlugins []string, wantedPlugins []string) []str
 	return unwantedPlugins
 }
 
+//getUnmanagedPlugins returns the names of the plugins, that are marked as unmanaged in the rackfile
+func getUnmanagedPlugins(rackFile *RackFile) []string {
+	unmanaged := []string{}
+	if rackFile == nil {
+		return unmanaged
+	}
+
+	for _, plugin := range rackFile.Plugins {
+		if getPluginFlag(plugin) == rackfile.FlagUnmanaged {
+			unmanaged = append(unmanaged, strings.Split(plugin, ":")[0])
+		}
+	}
+
+	return unmanaged
+}
+
 //getRackFile returns the RackFile at given path
 func getRackFile(shop *rackshop.RackShop) *RackFile {
 	rackfile, err := rackssh.GetRemoteFileFromShop(shop, "custom/rackfile.yaml")
 	if err != nil {
-		log.Println("failed to get rackfile from shop")
+		racklog.Warnf("failed to get rackfile from shop")
 		return nil
 	}
 
@@ -230,49 +300,36 @@ func getRackFile(shop *rackshop.RackShop) *RackFile {
 
 	err = yaml.Unmarshal(rackfile, &rf)
 	if err != nil {
-		log.Printf("Unmarshal RackFile failed %v\n", err)
+		racklog.Warnf("Unmarshal RackFile failed %v\n", err)
 	}
 
 	return rf
 }
 
-//getRackPluginHashes returns the PluginHashes at given path
-func getRackPluginHashes(shop *rackshop.RackShop) *rackpluginhashes.PluginHashes {
-	pluginhashes, err := rackssh.GetRemoteFileFromShop(shop, "custom/rackpluginhashes.yaml")
-	if err != nil {
-		log.Println("failed to get rackhashes from shop")
-		return nil
+//getDeploymentState returns the deployment state of the shop, or an empty state if the shop has none yet.
+//Shops, that only have a rackpluginhashes.yaml, are migrated to the state file.
+//Failures to read the state are returned, so a shop is not deployed from scratch because of a broken connection.
+func getDeploymentState(shop *rackshop.RackShop) (*rackstate.State, error) {
+	state, err := rackstate.ReadShopState(rackssh.NewExecutor(shop), shop)
+	if err == rackstate.ErrNoState {
+		racklog.Infof("No state file found on %v, deploying from scratch", shop.Name)
+		return rackstate.NewState(), nil
 	}
 
-	ph := &rackpluginhashes.PluginHashes{}
-
-	err = yaml.Unmarshal(pluginhashes, &ph)
 	if err != nil {
-		log.Printf("Unmarshal PluginHashes failed %v\n", err)
+		return nil, errors.New("failed to read the deployment state of " + shop.Name + ": " + err.Error())
 	}
 
-	return ph
+	return state, nil
 }
 
-//updatePluginHashesToShop marshals the given PluginHashes into a yaml and uploads it to the rackshop
-func updatePluginHashesToShop(ph *rackpluginhashes.PluginHashes, shop *rackshop.RackShop) error {
-	hashfile, err := ph.MarshalPluginHashes()
-	if err != nil {
-		log.Printf("Marshal PluginHashes failed %v\n", err)
-		return err
-	}
-
-	c, err := sshrw.NewSSHclt(shop.Address+":22", shop.GetRemoteConfig())
-	if err != nil {
-		log.Printf("Failed to connect to shop %v\n", err)
-		return err
-	}
-
-	fileDir := filepath.Join(shop.ShopwareDir, "custom/rackpluginhashes.yaml")
+//updateDeploymentStateToShop marshals the given State into a yaml and uploads it to the rackshop
+func updateDeploymentStateToShop(state *rackstate.State, shop *rackshop.RackShop) error {
+	state.UpdatedAt = time.Now().UTC()
 
-	err = c.WriteFile(bytes.NewReader(*hashfile), fileDir)
+	err := rackstate.WriteShopState(rackssh.NewExecutor(shop), shop, state)
 	if err != nil {
-		log.Printf("WriteFile - error: %v\n", err)
+		racklog.Warnf("WriteShopState - error: %v\n", err)
 		return err
 	}
 
@@ -286,14 +343,14 @@ func getRackSpec(path string) *rackspec.RackSpec {
 		if strings.Contains(file.Name(), "rackspec.yaml") {
 			rackspecData, err := ioutil.ReadFile(filepath.Join(path, file.Name())) //nolint, only being unmarshaled
 			if err != nil {
-				log.Printf("Failed reading RackSpec %v\n", err)
+				racklog.Warnf("Failed reading RackSpec %v\n", err)
 			}
 
 			rs := &rackspec.RackSpec{}
 
 			err = yaml.Unmarshal(rackspecData, &rs)
 			if err != nil {
-				log.Printf("Unmarshal RackSpec failed %v\n", err)
+				racklog.Warnf("Unmarshal RackSpec failed %v\n", err)
 			}
 
 			return rs
@@ -310,7 +367,7 @@ func getPluginRepo(pluginName string) string {
 
 	repoDirs, err := ioutil.ReadDir(reposPath)
 	if err != nil {
-		log.Fatalf("ReadDir - error: %v\n", err)
+		racklog.Fatalf("ReadDir - error: %v\n", err)
 	}
 
 	for _, repoDir := range repoDirs {
@@ -348,7 +405,7 @@ func getLatestVersion(pluginName string) string {
 
 	repoDirs, err := ioutil.ReadDir(reposPath)
 	if err != nil {
-		log.Fatalf("ReadDir - error: %v\n", err)
+		racklog.Fatalf("ReadDir - error: %v\n", err)
 	}
 
 	latestVersion, _ := version.NewVersion("0.0.1")
@@ -361,7 +418,7 @@ func getLatestVersion(pluginName string) string {
 			if pluginInThisRepo {
 				versionDirs, err := ioutil.ReadDir(pluginPath)
 				if err != nil {
-					log.Fatalf("ReadDir - error: %v\n", err)
+					racklog.Fatalf("ReadDir - error: %v\n", err)
 				}
 
 				for _, versionDir := range versionDirs {
@@ -394,80 +451,116 @@ func getPluginFlag(plugin string) string {
 }
 
 //deletePlugin deactivates, uninstalls and deletes a plugin from Shopware
-func deletePlugin(pluginName string, shop *rackshop.RackShop) {
-	fmt.Println("Deleting plugin " + pluginName + ".")
-	commands := []string{
-		"docker exec -i " + shop.Container + " php /var/www/html/bin/console sw:plugin:refresh -q",
-		"docker exec -i " + shop.Container + " php /var/www/html/bin/console sw:plugin:deactivate -q " + pluginName,
-		"docker exec -i " + shop.Container + " php /var/www/html/bin/console sw:plugin:uninstall -S -q " + pluginName,
-		"docker exec -i " + shop.Container + " php /var/www/html/bin/console sw:plugin:delete -q " + pluginName,
+func (r *runner) deletePlugin(pluginName string) error {
+	r.logger.Infof("Deleting plugin %v.", pluginName)
+	if err := r.refreshPlugins(); err != nil {
+		return err
 	}
 
-	for _, command := range commands {
-		rackssh.RunRemoteCommandInShop(command, shop)
+	commands := []string{
+		"docker exec -i " + r.shop.Container + " php /var/www/html/bin/console sw:plugin:deactivate -q " + pluginName,
+		"docker exec -i " + r.shop.Container + " php /var/www/html/bin/console sw:plugin:uninstall -S -q " + pluginName,
+		"docker exec -i " + r.shop.Container + " php /var/www/html/bin/console sw:plugin:delete -q " + pluginName,
 	}
+
+	return r.runCommands(commands...)
 }
 
 //update Plugin fetches and checks out the Branch of the specified version and updates the Plugin on the given shop
-func updatePlugin(pluginName string, shop *rackshop.RackShop, version string) {
-	fmt.Println("Updating plugin " + pluginName + ".")
-	pluginPath := filepath.Join(shop.ShopwareDir, "custom", "plugins", pluginName)
+func (r *runner) updatePlugin(pluginName string, version string) error {
+	r.logger.Infof("Updating plugin %v.", pluginName)
+	pluginPath := filepath.Join(r.shop.ShopwareDir, "custom", "plugins", pluginName)
 	refspec := "\"+refs/tags/" + version + ":refs/tags/" + version + "\""
-	commands := []string{
-		"docker exec -i " + shop.Container + " php /var/www/html/bin/console sw:plugin:refresh -q",
-		"docker exec -i " + shop.Container + " php /var/www/html/bin/console sw:plugin:deactivate -q " + pluginName,
-		"git -C " + pluginPath + " config remote.origin.fetch " + refspec,
-		"git -C " + pluginPath + " fetch",
-		"git -C " + pluginPath + " checkout " + version,
-		"docker exec -i " + shop.Container + " php /var/www/html/bin/console sw:plugin:update -q " + pluginName,
+
+	if err := r.refreshPlugins(); err != nil {
+		return err
+	}
+
+	if err := r.runCommands(
+		"docker exec -i "+r.shop.Container+" php /var/www/html/bin/console sw:plugin:deactivate -q "+pluginName,
+		"git -C "+pluginPath+" config remote.origin.fetch "+refspec,
+	); err != nil {
+		return err
 	}
 
-	for _, command := range commands {
-		rackssh.RunRemoteCommandInShop(command, shop)
+	if err := r.runRetryableCommands("git -C " + pluginPath + " fetch"); err != nil {
+		return err
 	}
+
+	return r.runCommands(
+		"git -C "+pluginPath+" checkout "+version,
+		"docker exec -i "+r.shop.Container+" php /var/www/html/bin/console sw:plugin:update -q "+pluginName,
+	)
 }
 
 //installPlugin installs a plugin
-func installPlugin(pluginName string, shop *rackshop.RackShop) {
-	fmt.Println("Installing plugin " + pluginName + ".")
-	commands := []string{
-		"docker exec -i " + shop.Container + " php /var/www/html/bin/console sw:plugin:refresh -q ",
-		"docker exec -i " + shop.Container + " php /var/www/html/bin/console sw:plugin:install -q " + pluginName,
+func (r *runner) installPlugin(pluginName string) error {
+	r.logger.Infof("Installing plugin %v.", pluginName)
+
+	if err := r.refreshPlugins(); err != nil {
+		return err
 	}
 
-	for _, command := range commands {
-		rackssh.RunRemoteCommandInShop(command, shop)
+	command := "docker exec -i " + r.shop.Container + " php /var/www/html/bin/console sw:plugin:install -q " + pluginName
+	return r.runCommands(command)
+}
+
+//refreshPlugins lets shopware read the plugins in custom/plugins again.
+//It is retried with the retry policy of the shop, as refreshing can be repeated safely.
+func (r *runner) refreshPlugins() error {
+	return r.runRetryableCommands(rackssh.ConsoleCommand(r.shop, "sw:plugin:refresh -q"))
+}
+
+//uninstallPlugin uninstalls a plugin, if it is installed, and keeps its data
+func (r *runner) uninstallPlugin(pluginName string) error {
+	states, err := rackinspect.ReadPluginStates(r.executor, r.shop)
+	if err != nil {
+		return err
+	}
+
+	if !states[pluginName].Installed {
+		return nil
 	}
+
+	r.logger.Infof("Uninstalling plugin %v.", pluginName)
+
+	command := "docker exec -i " + r.shop.Container + " php /var/www/html/bin/console sw:plugin:uninstall -S -q " + pluginName
+	return r.runCommands(command)
 }
 
 //activatePlugin activates an installed plugin
-func activatePlugin(pluginName string, shop *rackshop.RackShop) {
-	fmt.Println("Activating plugin " + pluginName + ".")
+func (r *runner) activatePlugin(pluginName string) error {
+	r.logger.Infof("Activating plugin %v.", pluginName)
 
-	command := "docker exec -i " + shop.Container + " php /var/www/html/bin/console sw:plugin:activate -q " + pluginName
-	rackssh.RunRemoteCommandInShop(command, shop)
+	command := "docker exec -i " + r.shop.Container + " php /var/www/html/bin/console sw:plugin:activate -q " + pluginName
+	return r.runCommands(command)
 }
 
-// setTheme sets the given theme for a given shop
-func setTheme(themeName string, shop *rackshop.RackShop) {
+// setTheme sets the given theme for the default shop of a given shop
+func (r *runner) setTheme(themeName string) error {
 	escapedThemeName := escapeThemeName(themeName)
-	fmt.Printf("Setting Theme %v\n", escapedThemeName)
-	command := "docker exec -i " + shop.Container + " php /var/www/html/bin/console wdy:theme:set -q " + escapedThemeName
-	rackssh.RunRemoteCommandInShop(command, shop)
+	r.logger.Infof("Setting Theme %v\n", escapedThemeName)
+
+	subShopID, err := racktheme.DefaultSubShopID(r.executor, r.shop)
+	if err != nil {
+		return err
+	}
+
+	return r.assignTheme(rackplan.Step{Theme: escapedThemeName, SubShop: subShopID})
 }
 
 //initializeTheme resets a shop's theme to the Responsive theme
-func initializeTheme(shop *rackshop.RackShop) {
-	command := "docker exec -i " + shop.Container + " php /var/www/html/bin/console sw:theme:initialize -q"
-	rackssh.RunRemoteCommandInShop(command, shop)
+func (r *runner) initializeTheme() error {
+	command := "docker exec -i " + r.shop.Container + " php /var/www/html/bin/console sw:theme:initialize -q"
+	return r.runCommands(command)
 }
 
 //clearShopCache clears a shop's cache
-func clearShopCache(shop *rackshop.RackShop) {
-	fmt.Println("Clearing shop cache.")
+func (r *runner) clearShopCache() error {
+	r.logger.Infof("Clearing shop cache.")
 
-	command := "docker exec -i " + shop.Container + " php /var/www/html/bin/console sw:cache:clear -q "
-	rackssh.RunRemoteCommandInShop(command, shop)
+	command := "docker exec -i " + r.shop.Container + " php /var/www/html/bin/console sw:cache:clear -q "
+	return r.runCommands(command)
 }
 
 //exists returns if a path exists
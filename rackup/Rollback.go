@@ -0,0 +1,287 @@
+package rackup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/gitutil"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackhistory"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackinput"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racklog"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackmetrics"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackplan"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshop"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshopstore"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackssh"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackstate"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/repository"
+)
+
+// Rollback restores the plugin versions, flags and theme of a previous successful run on a shop.
+// Plugins are checked out at the tag of the run, plugins deleted since then are deployed again
+//and plugins added since then are deleted. The plan is printed and has to be confirmed, unless yes is set.
+//The shop is locked like by up, wait is how long to wait for the lock.
+//After the confirmation, SIGINT and SIGTERM cancel the rollback like the deployment of up.
+//The metrics of the rollback are written to the metrics output of the config, replaced by the given output.
+func Rollback(shopName string, runID string, yes bool, wait time.Duration, metrics rackmetrics.Output) error {
+	shop, err := rackshopstore.GetShopFromStore(shopName)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := LockShop(context.Background(), shop, "rollback", wait)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	executor := rackssh.NewExecutor(shop)
+
+	target, err := rackhistory.ReadRun(executor, shop, runID)
+	if err != nil {
+		return err
+	}
+
+	if !target.Succeeded() {
+		return errors.New("the run " + target.ID + " did not succeed and can not be restored")
+	}
+
+	state, err := getDeploymentState(shop)
+	if err != nil {
+		return err
+	}
+
+	state.RunID = rackstate.NewRunID()
+
+	plan, err := buildRollbackPlan(shop, target, getInstalledPlugins(shop), state)
+	if err != nil {
+		return err
+	}
+
+	plan.Print(os.Stdout)
+
+	if !plan.HasChanges() {
+		racklog.Infof("The shop already matches run %v.", target.ID)
+		return nil
+	}
+
+	if !yes && !confirmRollback(target.ID) {
+		fmt.Println("Rollback aborted.")
+		return nil
+	}
+
+	ctx, stop := withCancellation()
+	defer stop()
+
+	if err := executePlan(ctx, shop, plan, state, "rollback to "+target.ID, Options{Metrics: metrics},
+		rackmetrics.Start(shop.Name, "rollback")); err != nil {
+		return err
+	}
+
+	racklog.Infof("Process finished.")
+
+	return nil
+}
+
+//rollbackFailedRun restores the last successful run before the failed run on the shop.
+//The rollback is reported like the failed run.
+func rollbackFailedRun(ctx context.Context, shop *rackshop.RackShop, failedRunID string, opts Options) error {
+	metrics := rackmetrics.Start(shop.Name, "rollback")
+
+	runs, err := rackhistory.ListRuns(rackssh.NewExecutor(shop), shop)
+	if err != nil {
+		return err
+	}
+
+	var target *rackhistory.Run
+
+	for i := len(runs) - 1; i >= 0 && target == nil; i-- {
+		if runs[i].ID != failedRunID && runs[i].Succeeded() {
+			target = &runs[i]
+		}
+	}
+
+	if target == nil {
+		return errors.New("the shop has no successful run to restore")
+	}
+
+	state, err := getDeploymentState(shop)
+	if err != nil {
+		return err
+	}
+
+	state.RunID = rackstate.NewRunID()
+
+	plan, err := buildRollbackPlan(shop, target, getInstalledPlugins(shop), state)
+	if err != nil {
+		return err
+	}
+
+	racklog.With("shop", shop.Name).Infof("Rolling back to run %v.", target.ID)
+	plan.Print(opts.textWriter())
+	metrics.Phase(rackmetrics.PhasePrepare, metrics.Started)
+	return executePlan(ctx, shop, plan, state, "rollback to "+target.ID, Options{Events: opts.Events, JSON: opts.JSON,
+		Text: opts.Text, Notify: opts.Notify, Metrics: opts.Metrics}, metrics)
+}
+
+//buildRollbackPlan returns the steps, that change the plugins of the shop to the deployment state after the target run.
+//Plugins, that are unmanaged in the current rackfile of the shop, are not deleted.
+func buildRollbackPlan(shop *rackshop.RackShop, target *rackhistory.Run, installedPlugins []string,
+	state *rackstate.State) (*rackplan.Plan, error) {
+	plan := &rackplan.Plan{Shop: shop.Name, RunID: state.RunID, Steps: []rackplan.Step{}}
+	wanted := map[string]bool{}
+
+	for _, plugin := range target.Plugins {
+		wanted[plugin.Name] = true
+	}
+
+	unmanaged := getUnmanagedPlugins(getRackFile(shop))
+
+	for _, installedPlugin := range installedPlugins {
+		if installedPlugin != "" && !wanted[installedPlugin] && !containsPlugin(unmanaged, installedPlugin) {
+			plan.Steps = append(plan.Steps, rackplan.Step{
+				Action: rackplan.ActionDelete,
+				Plugin: installedPlugin,
+				Reason: "not deployed in run " + target.ID,
+			})
+		}
+	}
+
+	theme := ""
+
+	for _, plugin := range target.Plugins {
+		step, err := planRollbackPlugin(plugin, installedPlugins, state)
+		if err != nil {
+			return nil, err
+		}
+
+		plan.Steps = append(plan.Steps, step)
+
+		if len(plugin.Flag) == 0 {
+			if spec, _, err := repository.GetRackSpec(plugin.Name, plugin.Tag); err == nil && len(spec.Theme) > 0 {
+				theme = spec.Theme
+			}
+		}
+	}
+
+	assignments := getThemeAssignments(target)
+
+	switch {
+	case len(assignments) > 0:
+		plan.Steps = append(plan.Steps, rackplan.Step{Action: rackplan.ActionInitializeTheme})
+		plan.Steps = append(plan.Steps, assignments...)
+	case len(theme) > 0:
+		plan.Steps = append(plan.Steps, rackplan.Step{Action: rackplan.ActionInitializeTheme},
+			rackplan.Step{Action: rackplan.ActionSetTheme, Theme: theme})
+	}
+
+	plan.Steps = append(plan.Steps, rackplan.Step{Action: rackplan.ActionClearCache})
+
+	return plan, nil
+}
+
+//planRollbackPlugin returns the step, that restores a plugin to its state after the target run
+func planRollbackPlugin(plugin rackstate.PluginState, installedPlugins []string,
+	state *rackstate.State) (rackplan.Step, error) {
+	if len(plugin.Tag) == 0 {
+		tag, err := findTagOfCommit(plugin.Name, plugin.Commit)
+		if err != nil {
+			return rackplan.Step{}, errors.New("the run does not record the version of plugin " + plugin.Name +
+				": " + err.Error())
+		}
+
+		plugin.Tag = tag
+	}
+
+	step := rackplan.Step{
+		Action:  rackplan.ActionSkip,
+		Plugin:  plugin.Name,
+		Version: plugin.Tag,
+		Commit:  plugin.Commit,
+		Flag:    plugin.Flag,
+		Source:  plugin.Source,
+		Repo:    plugin.SpecRepo,
+	}
+
+	if len(step.Source) == 0 {
+		spec, repo, err := repository.GetRackSpec(plugin.Name, plugin.Tag)
+		if err != nil {
+			return step, err
+		}
+
+		step.Source = spec.Source.GIT
+		step.Repo = repo
+	}
+
+	installed := containsPlugin(installedPlugins, plugin.Name)
+
+	if current, ok := state.GetPlugin(plugin.Name); ok && installed && current.Commit == plugin.Commit &&
+		current.Flag == plugin.Flag {
+		step.Reason = "unchanged"
+		return step, nil
+	}
+
+	step.Action = rackplan.ActionDeploy
+	step.Clone = !installed
+
+	return step, nil
+}
+
+//findTagOfCommit returns the version of a plugin, whose tag points to the commit.
+//It is used for runs, that were recorded with a state migrated from the rackpluginhashes.yaml.
+func findTagOfCommit(pluginName string, commit string) (string, error) {
+	repo, err := repository.FindPluginRepo(pluginName)
+	if err != nil {
+		return "", err
+	}
+
+	versions, err := repository.GetPluginVersions(repo, pluginName)
+	if err != nil {
+		return "", err
+	}
+
+	for _, version := range versions {
+		spec, _, err := repository.GetRackSpec(pluginName, version)
+		if err != nil {
+			continue
+		}
+
+		hash, err := gitutil.GetHashOfLastCommit(context.Background(), spec.Source.GIT, version)
+		if err == nil && *hash == commit {
+			return version, nil
+		}
+	}
+
+	return "", errors.New("no version is tagged at commit " + commit)
+}
+
+//getThemeAssignments returns the steps, that assign the themes of the subshops as planned by the target run
+func getThemeAssignments(target *rackhistory.Run) []rackplan.Step {
+	assignments := []rackplan.Step{}
+
+	for _, step := range target.Plan.Steps {
+		if len(step.SubShop) > 0 && len(step.Theme) > 0 {
+			assignments = append(assignments, rackplan.Step{
+				Action:  rackplan.ActionAssignTheme,
+				Plugin:  step.Plugin,
+				Theme:   step.Theme,
+				SubShop: step.SubShop,
+			})
+		}
+	}
+
+	return assignments
+}
+
+//confirmRollback asks the user to confirm the rollback
+func confirmRollback(runID string) bool {
+	in := ""
+	for in != "y" && in != "n" {
+		in = rackinput.AwaitTextInput("Do you want to restore run " + runID + "? (y/n)")
+	}
+
+	return in == "y"
+}
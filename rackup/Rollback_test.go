@@ -0,0 +1,176 @@
+package rackup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/fileutil"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackhistory"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackplan"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshop"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackstate"
+)
+
+//useHome lets rackjobber use a temporary folder with the config and the rackspecs of the master repository.
+//specs maps "Plugin/version" to the content of the rackspec. The returned function removes the folder.
+func useHome(t *testing.T, config string, specs map[string]string) (string, func()) {
+	home, err := ioutil.TempDir("", "rackjobber")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fileutil.SetHome(home); err != nil {
+		t.Fatal(err)
+	}
+
+	configPath, err := fileutil.GetConfigFolderPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dataPath, err := fileutil.GetAppFolderPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{filepath.Join(*configPath, "config.yaml"): config}
+	for spec, content := range specs {
+		files[filepath.Join(*dataPath, "repos", "master", spec, "rackspec.yaml")] = content
+	}
+
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return home, func() { _ = os.RemoveAll(home) }
+}
+
+func TestBuildRollbackPlan(t *testing.T) {
+	home, cleanup := useHome(t, "", map[string]string{
+		"SwagTheme/1.0.0": "name: SwagTheme\nversion: 1.0.0\ntheme: Swag Theme\n" +
+			"source:\n  GIT: https://git.example.com/theme.git\n",
+	})
+	defer cleanup()
+
+	shop := &rackshop.RackShop{Name: "my-shop", Local: true, ShopwareDir: home}
+
+	rackFile := []byte("Plugins:\n- LegacyPlugin::unmanaged\n")
+	if err := os.MkdirAll(filepath.Join(home, "custom"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(home, "custom", "rackfile.yaml"), rackFile, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	swag := rackstate.PluginState{Name: "SwagPlugin", Tag: "1.2.0", Commit: "abc",
+		Source: "https://git.example.com/swag.git", SpecRepo: "master"}
+	other := rackstate.PluginState{Name: "OtherPlugin", Tag: "2.0.0", Commit: "def", Flag: "noactivate",
+		Source: "https://git.example.com/other.git", SpecRepo: "master"}
+	theme := rackstate.PluginState{Name: "SwagTheme", Tag: "1.0.0", Commit: "123",
+		Source: "https://git.example.com/theme.git", SpecRepo: "master"}
+
+	skip := func(plugin rackstate.PluginState) rackplan.Step {
+		return rackplan.Step{Action: rackplan.ActionSkip, Plugin: plugin.Name, Version: plugin.Tag, Commit: plugin.Commit,
+			Flag: plugin.Flag, Source: plugin.Source, Repo: plugin.SpecRepo, Reason: "unchanged"}
+	}
+	deploy := func(plugin rackstate.PluginState, clone bool) rackplan.Step {
+		return rackplan.Step{Action: rackplan.ActionDeploy, Plugin: plugin.Name, Version: plugin.Tag,
+			Commit: plugin.Commit, Flag: plugin.Flag, Source: plugin.Source, Repo: plugin.SpecRepo, Clone: clone}
+	}
+
+	tests := []struct {
+		name      string
+		target    rackhistory.Run
+		installed []string
+		current   []rackstate.PluginState
+		want      []rackplan.Step
+		wantErr   bool
+	}{
+		{
+			name:      "unchanged plugins",
+			target:    rackhistory.Run{ID: "run-1", Plugins: []rackstate.PluginState{swag, other}},
+			installed: []string{"SwagPlugin", "OtherPlugin"},
+			current:   []rackstate.PluginState{swag, other},
+			want:      []rackplan.Step{skip(swag), skip(other), {Action: rackplan.ActionClearCache}},
+		},
+		{
+			name:      "changed, removed and added plugins",
+			target:    rackhistory.Run{ID: "run-1", Plugins: []rackstate.PluginState{swag, other}},
+			installed: []string{"SwagPlugin", "NewPlugin", "LegacyPlugin", ""},
+			current: []rackstate.PluginState{{Name: "SwagPlugin", Tag: "1.3.0", Commit: "bcd"},
+				{Name: "NewPlugin", Tag: "1.0.0", Commit: "fff"}},
+			want: []rackplan.Step{
+				{Action: rackplan.ActionDelete, Plugin: "NewPlugin", Reason: "not deployed in run run-1"},
+				deploy(swag, false), deploy(other, true), {Action: rackplan.ActionClearCache},
+			},
+		},
+		{
+			name:      "changed flag",
+			target:    rackhistory.Run{ID: "run-1", Plugins: []rackstate.PluginState{other}},
+			installed: []string{"OtherPlugin"},
+			current:   []rackstate.PluginState{{Name: "OtherPlugin", Tag: "2.0.0", Commit: "def"}},
+			want:      []rackplan.Step{deploy(other, false), {Action: rackplan.ActionClearCache}},
+		},
+		{
+			name:      "theme of a rackspec",
+			target:    rackhistory.Run{ID: "run-1", Plugins: []rackstate.PluginState{theme}},
+			installed: []string{"SwagTheme"},
+			current:   []rackstate.PluginState{theme},
+			want: []rackplan.Step{skip(theme), {Action: rackplan.ActionInitializeTheme},
+				{Action: rackplan.ActionSetTheme, Theme: "Swag Theme"}, {Action: rackplan.ActionClearCache}},
+		},
+		{
+			name: "assigned themes",
+			target: rackhistory.Run{ID: "run-1", Plugins: []rackstate.PluginState{theme},
+				Plan: rackplan.Plan{Steps: []rackplan.Step{{Action: rackplan.ActionAssignTheme, Plugin: "SwagTheme",
+					Theme: "Swag_Theme", SubShop: "2"}}}},
+			installed: []string{"SwagTheme"},
+			current:   []rackstate.PluginState{theme},
+			want: []rackplan.Step{skip(theme), {Action: rackplan.ActionInitializeTheme},
+				{Action: rackplan.ActionAssignTheme, Plugin: "SwagTheme", Theme: "Swag_Theme", SubShop: "2"},
+				{Action: rackplan.ActionClearCache}},
+		},
+		{
+			name: "migrated plugin without a rackspec",
+			target: rackhistory.Run{ID: "run-1",
+				Plugins: []rackstate.PluginState{{Name: "SwagPlugin", Commit: "abc", Result: rackstate.ResultMigrated}}},
+			installed: []string{"SwagPlugin"},
+			wantErr:   true,
+		},
+	}
+
+	for _, test := range tests {
+		state := rackstate.NewState()
+		for _, plugin := range test.current {
+			state.SetPlugin(plugin)
+		}
+
+		plan, err := buildRollbackPlan(shop, &test.target, test.installed, state)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%v: expected an error, got %+v", test.name, plan)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(plan.Steps, test.want) {
+			t.Errorf("%v: got steps\n%+v\nwant\n%+v", test.name, plan.Steps, test.want)
+		}
+	}
+}
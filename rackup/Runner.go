@@ -0,0 +1,421 @@
+package rackup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/gitutil"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackconfig"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackevent"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackhistory"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackhook"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racklog"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackmetrics"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racknotify"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackplan"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackretry"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshop"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackssh"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackstate"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racktheme"
+)
+
+//runner executes the steps of a plan on a shop and records them for the history of the shop
+type runner struct {
+	shop     *rackshop.RackShop
+	executor rackssh.Executor
+	// ctx kills the running command, it is done some time after the run was cancelled.
+	// The executor runs its commands with ctx, so the helpers of other packages are killed as well.
+	ctx context.Context
+	// timeouts limit the git commands on the shop, retry repeats its idempotent commands
+	timeouts rackretry.Timeouts
+	retry    rackretry.Policy
+	run      *rackhistory.Run
+	step     *rackhistory.StepResult
+	// logger adds the shop, the run and the current step to the log messages
+	logger *racklog.Logger
+	// events and jsonOut receive the events and the record of the run, they may be nil
+	events  *rackevent.Stream
+	jsonOut io.Writer
+	// notifications are notified of the start and the end of the run with the plugins it changes
+	notifications []racknotify.Target
+	changes       []racknotify.PluginChange
+	// metrics measure the run from executeStart on and are written to metricsOutput, when it finishes
+	metrics       *rackmetrics.Run
+	metricsOutput rackmetrics.Output
+	executeStart  time.Time
+	// themeStrategy assigns the themes, it is chosen when the first theme is assigned
+	themeStrategy racktheme.Strategy
+	// maintenanceOn is set while the shop is in maintenance mode
+	maintenanceOn bool
+	// previousWhitelist is the whitelist of shopware's maintenance setting, that is restored with the maintenance mode
+	previousWhitelist interface{}
+	// previousOffline is the value of shopware's maintenance setting, that is restored with the maintenance mode
+	previousOffline interface{}
+	// removeMaintenanceHandler removes the exit handler, that switches the maintenance mode off
+	removeMaintenanceHandler func()
+}
+
+//executePlan executes the steps of the plan one after another and records the deployed plugins in the state.
+// The state and the run are written to the shop, even if a step fails.
+//A failing hook or health check can continue the run or restore the last successful run, depending on its failure policy.
+//When the context is cancelled, the running command may finish within the killTimeout and the remaining steps are
+//recorded as cancelled.
+//The maintenance mode is switched off, before a failing or cancelled run returns its error.
+//The events, the record and the notifications of the run are reported as the options decide.
+//The metrics of the run are exported, when it finishes, they may be nil.
+func executePlan(ctx context.Context, shop *rackshop.RackShop, plan *rackplan.Plan, state *rackstate.State,
+	command string, opts Options, metrics *rackmetrics.Run) error {
+	commandCtx, kill := killAfter(ctx, killTimeout)
+	defer kill()
+
+	r := &runner{shop: shop, executor: rackssh.WithContext(commandCtx, rackssh.NewExecutor(shop)), ctx: commandCtx,
+		timeouts: rackconfig.GetTimeouts(shop), retry: rackconfig.GetRetryPolicy(shop),
+		run: rackhistory.NewRun(command, *plan), events: opts.Events, jsonOut: opts.JSON,
+		metrics: metrics, metricsOutput: rackconfig.GetMetrics(opts.Metrics), executeStart: time.Now()}
+	r.logger = racklog.With("shop", shop.Name).With("run", r.run.ID)
+	runLogger := r.logger
+
+	if opts.Notify {
+		r.notifications = NotificationTargets(shop)
+		r.changes = pluginChanges(plan, state)
+	}
+
+	r.emit(rackevent.Event{Type: rackevent.RunStarted, Command: command})
+	r.notify(racknotify.EventStarted)
+
+	for i, step := range plan.Steps {
+		if ctx.Err() != nil {
+			return r.cancel(state, plan.Steps[i:])
+		}
+
+		r.step = r.run.StartStep(step)
+		r.logger = stepLogger(runLogger, step)
+		stepStart := time.Now()
+
+		if step.Action != rackplan.ActionSkip {
+			r.emit(rackevent.Event{Type: rackevent.StepStarted, Step: &step})
+		}
+
+		err := r.executeStep(step, state)
+		if step.Action == rackplan.ActionSkip {
+			r.step.Finish(rackhistory.OutcomeSkipped, err)
+			r.emit(rackevent.Event{Type: rackevent.PluginSkipped, Plugin: step.Plugin, Version: step.Version,
+				Reason: step.Reason})
+		} else {
+			r.step.Finish(rackhistory.OutcomeSucceeded, err)
+			r.emit(rackevent.Event{Type: rackevent.StepFinished, Step: &step, Outcome: r.step.Outcome,
+				DurationMillis: r.step.DurationMillis, Error: r.step.Error})
+		}
+
+		r.metrics.Step(step.Action, step.Plugin, stepResult(step.Action, err), time.Since(stepStart))
+
+		if err == nil {
+			continue
+		}
+
+		policy := rackhook.OnFailureAbort
+
+		switch step.Action {
+		case rackplan.ActionHook:
+			policy = step.Hook.Policy()
+		case rackplan.ActionHealthCheck:
+			policy = step.HealthCheck.Policy()
+		}
+
+		if policy == rackhook.OnFailureWarn {
+			r.logger.Warnf("%v failed: %v\n", step.String(), err)
+			continue
+		}
+
+		if ctx.Err() != nil {
+			return r.cancel(state, plan.Steps[i+1:])
+		}
+
+		r.abort(state, err)
+
+		if policy == rackhook.OnFailureRollback {
+			if rollbackErr := rollbackFailedRun(ctx, shop, r.run.ID, opts); rollbackErr != nil {
+				r.logger.Errorf("rollback of run %v failed: %v\n", r.run.ID, rollbackErr)
+			}
+		}
+
+		return errors.New(step.String() + " failed: " + err.Error())
+	}
+
+	if err := r.finish(state, nil); err != nil {
+		return errors.New("failed to write the deployment state: " + err.Error())
+	}
+
+	runLogger.Infof("Recorded run %v in the history of %v.", r.run.ID, shop.Name)
+
+	return nil
+}
+
+//stepLogger returns the logger of a step, that adds the step and its plugin to the fields of the run
+func stepLogger(logger *racklog.Logger, step rackplan.Step) *racklog.Logger {
+	logger = logger.With("step", step.Action)
+	if len(step.Plugin) > 0 {
+		logger = logger.With("plugin", step.Plugin)
+	}
+
+	return logger
+}
+
+//cancel records the remaining steps as cancelled, stops the run and returns ErrCancelled
+func (r *runner) cancel(state *rackstate.State, remaining []rackplan.Step) error {
+	for _, step := range remaining {
+		r.run.StartStep(step).Finish(rackhistory.OutcomeCancelled, nil)
+	}
+
+	r.abort(state, context.Canceled)
+	r.logger.Warnf("The run %v on %v was cancelled, %v steps were skipped.", r.run.ID, r.shop.Name, len(remaining))
+
+	return ErrCancelled
+}
+
+//abort switches the maintenance mode off and writes the state and the record of a run, that stops because of the error.
+//The commands to clean up are not killed by the cancellation.
+func (r *runner) abort(state *rackstate.State, err error) {
+	r.ctx = context.Background()
+	r.executor = rackssh.NewExecutor(r.shop)
+
+	if r.maintenanceOn {
+		if maintenanceErr := r.disableMaintenance(); maintenanceErr != nil {
+			r.logger.Errorf("failed to switch off the maintenance mode: %v\n", maintenanceErr)
+		}
+	}
+
+	r.finish(state, err)
+}
+
+//finish writes the state and the record of the run to the shop and exports its metrics
+func (r *runner) finish(state *rackstate.State, err error) error {
+	r.metrics.Phase(rackmetrics.PhaseExecute, r.executeStart)
+	finishStart := time.Now()
+
+	stateErr := updateDeploymentStateToShop(state, r.shop)
+	if err == nil {
+		err = stateErr
+	}
+
+	r.run.Finish(state, err)
+
+	if historyErr := rackhistory.WriteRun(r.executor, r.shop, r.run); historyErr != nil {
+		r.logger.Errorf("failed to write the run to the history: %v\n", historyErr)
+	}
+
+	r.emit(rackevent.Event{Type: rackevent.RunFinished, Outcome: r.run.Outcome, DurationMillis: r.run.DurationMillis,
+		Error: r.run.Error})
+	r.notify(finishedEvent(r.run.Outcome))
+
+	if r.jsonOut != nil {
+		data, jsonErr := json.Marshal(r.run)
+		if jsonErr != nil {
+			r.logger.Errorf("failed to encode the run: %v\n", jsonErr)
+		} else {
+			fmt.Fprintln(r.jsonOut, string(data))
+		}
+	}
+
+	r.metrics.Phase(rackmetrics.PhaseFinish, finishStart)
+	r.metrics.Finish(r.run.Outcome)
+	rackmetrics.Export(r.metrics, r.metricsOutput)
+
+	return stateErr
+}
+
+//emit sets the shop and the run of the event and writes it to the events of the run
+func (r *runner) emit(event rackevent.Event) {
+	event.Shop = r.shop.Name
+	event.RunID = r.run.ID
+	r.events.Emit(event)
+}
+
+//executeStep executes a single step of the plan
+func (r *runner) executeStep(step rackplan.Step, state *rackstate.State) error {
+	switch step.Action {
+	case rackplan.ActionDelete:
+		if err := r.deletePlugin(step.Plugin); err != nil {
+			return err
+		}
+
+		state.RemovePlugin(step.Plugin)
+	case rackplan.ActionDeploy:
+		return r.deployPlugin(step, state)
+	case rackplan.ActionInitializeTheme:
+		return r.initializeTheme()
+	case rackplan.ActionSetTheme:
+		return r.setTheme(step.Theme)
+	case rackplan.ActionAssignTheme:
+		return r.assignTheme(step)
+	case rackplan.ActionConfigure:
+		return r.configurePlugin(step)
+	case rackplan.ActionHook:
+		return r.runHook(*step.Hook)
+	case rackplan.ActionHealthCheck:
+		return r.checkHealth(*step.HealthCheck)
+	case rackplan.ActionEnableMaintenance:
+		return r.enableMaintenance()
+	case rackplan.ActionDisableMaintenance:
+		return r.disableMaintenance()
+	case rackplan.ActionClearCache:
+		return r.clearShopCache()
+	}
+
+	return nil
+}
+
+//deployPlugin checks out the version of the plugin, installs and activates it according to its flag
+//and records it in the state. The plugin is deactivated while it is updated and uninstalled, if its flag is noinstall,
+//so flags are also restored downward, like by a rollback to a run, that did not install the plugin.
+func (r *runner) deployPlugin(step rackplan.Step, state *rackstate.State) error {
+	pluginState := newPluginState(step, state.RunID)
+
+	r.logger.Infof("Loading %v %v", step.Plugin, step.Version)
+
+	if step.Clone {
+		r.logger.Infof("Cloning %v to %v.", step.Plugin, r.shop.Name)
+
+		if err := r.runCommands(rackssh.CloneCommand(r.shop, step.Plugin, step.Source, step.Version)); err != nil {
+			return err
+		}
+	}
+
+	if err := r.updatePlugin(step.Plugin, step.Version); err != nil {
+		return err
+	}
+
+	switch step.Flag {
+	case "noinstall":
+		if err := r.uninstallPlugin(step.Plugin); err != nil {
+			return err
+		}
+	case "noactivate":
+		if err := r.installPlugin(step.Plugin); err != nil {
+			return err
+		}
+
+		pluginState.Installed = true
+	case "nosettheme", "":
+		if err := r.installPlugin(step.Plugin); err != nil {
+			return err
+		}
+
+		pluginState.Installed = true
+
+		if err := r.activatePlugin(step.Plugin); err != nil {
+			return err
+		}
+
+		pluginState.Activated = true
+	}
+
+	state.SetPlugin(*pluginState)
+
+	if len(step.Theme) != 0 {
+		return r.setTheme(step.Theme)
+	}
+
+	return nil
+}
+
+//runCommands runs the commands on the shop one after another and records them for the current step
+func (r *runner) runCommands(commands ...string) error {
+	for _, command := range commands {
+		if err := r.runCommand(command, redactCommand(command)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//runRetryableCommands runs commands, that can be repeated safely when they fail, like fetching or refreshing
+//the plugins, one after another. Failed commands are retried with the retry policy of the shop.
+func (r *runner) runRetryableCommands(commands ...string) error {
+	for _, command := range commands {
+		if err := r.runCommandWithPolicy(r.retry, command, redactCommand(command)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//runCommand runs a command on the shop once and records it as the given recorded command.
+//The secrets are replaced in the recorded output and redacted from the log.
+func (r *runner) runCommand(command string, recorded string, secrets ...string) error {
+	return r.runCommandWithPolicy(rackretry.Policy{Attempts: 1}, command, recorded, secrets...)
+}
+
+//runCommandWithPolicy runs a command on the shop like runCommand and retries it with the policy,
+//every attempt is recorded
+func (r *runner) runCommandWithPolicy(policy rackretry.Policy, command string, recorded string,
+	secrets ...string) error {
+	for _, secret := range secrets {
+		racklog.AddSecret(secret)
+	}
+
+	return rackretry.Do(r.ctx, policy, recorded, func() error {
+		return r.runCommandOnce(command, recorded, secrets...)
+	})
+}
+
+//runCommandOnce runs a command on the shop and records it, git commands are killed after the git timeout
+func (r *runner) runCommandOnce(command string, recorded string, secrets ...string) error {
+	start := time.Now()
+
+	r.logger.Verbosef("Running %v", recorded)
+
+	var out string
+
+	run := func(ctx context.Context) error {
+		var err error
+		out, err = r.executor.RunContext(ctx, command)
+
+		return err
+	}
+
+	var err error
+	if strings.HasPrefix(command, "git ") {
+		err = rackretry.WithTimeout(r.ctx, r.timeouts.GetGit(), "the git command", run)
+		rackmetrics.AddGit(time.Since(start))
+	} else {
+		err = run(r.ctx)
+	}
+
+	out = redactCommand(out)
+	for _, secret := range secrets {
+		if len(secret) > 0 {
+			out = strings.ReplaceAll(out, secret, secretPlaceholder)
+		}
+	}
+
+	r.step.AddCommand(recorded, out, time.Since(start), err)
+
+	if trimmed := strings.TrimSpace(out); len(trimmed) > 0 {
+		r.logger.Debugf("Output of %v: %v", recorded, trimmed)
+	}
+
+	if err != nil {
+		return errors.New(recorded + ": " + err.Error())
+	}
+
+	return nil
+}
+
+//redactCommand removes the credentials from all urls in a command or its output
+func redactCommand(command string) string {
+	fields := strings.Split(command, " ")
+	for i, field := range fields {
+		fields[i] = gitutil.StripURLAuth(field)
+	}
+
+	return strings.Join(fields, " ")
+}
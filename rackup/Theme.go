@@ -0,0 +1,103 @@
+package rackup
+
+import (
+	"time"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackfile"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackplan"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshop"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackshopdb"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackssh"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racktheme"
+)
+
+//addThemeSteps adds the steps assigning the themes of the rackfile to the subshops before the last step of the plan.
+// The themes of the rackfile replace the themes set by plugins. If a theme is deployed, the themes are initialized
+//and all subshops are assigned again, otherwise only subshops using another theme are changed.
+func addThemeSteps(shop *rackshop.RackShop, plan *rackplan.Plan, themes []rackfile.ThemeEntry, opts Options) error {
+	subShops, err := rackshopdb.ReadSubShops(rackssh.NewExecutor(shop), shop)
+	if err != nil {
+		return err
+	}
+
+	assignments, err := racktheme.ResolveAssignments(themes, subShops)
+	if err != nil {
+		return err
+	}
+
+	steps := []rackplan.Step{}
+	deploysTheme := false
+
+	for _, step := range plan.Steps {
+		if step.Action == rackplan.ActionInitializeTheme {
+			continue
+		}
+
+		step.Theme = ""
+		deploysTheme = deploysTheme || (step.Action == rackplan.ActionDeploy && isTheme(themes, step.Plugin))
+		steps = append(steps, step)
+	}
+
+	last := steps[len(steps)-1]
+	steps = steps[:len(steps)-1]
+
+	if deploysTheme {
+		steps = append(steps, rackplan.Step{Action: rackplan.ActionInitializeTheme})
+	}
+
+	for _, assignment := range assignments {
+		if !opts.selects(assignment.Theme.Name) {
+			continue
+		}
+
+		step := rackplan.Step{
+			Action:  rackplan.ActionAssignTheme,
+			Plugin:  assignment.Theme.Name,
+			Theme:   assignment.Template,
+			SubShop: assignment.SubShop.ID,
+			Reason:  "shop " + assignment.SubShop.Name,
+		}
+
+		if assignment.IsAssigned() && !deploysTheme {
+			step.Action = rackplan.ActionSkip
+			step.Reason = "already assigned to shop " + assignment.SubShop.Name
+		}
+
+		steps = append(steps, step)
+	}
+
+	plan.Steps = append(steps, last)
+
+	return nil
+}
+
+//isTheme returns if the plugin is a theme of the rackfile
+func isTheme(themes []rackfile.ThemeEntry, pluginName string) bool {
+	for _, theme := range themes {
+		if theme.Name == pluginName {
+			return true
+		}
+	}
+
+	return false
+}
+
+//assignTheme assigns the theme of the step to its subshop with the theme strategy of the shop
+func (r *runner) assignTheme(step rackplan.Step) error {
+	if r.themeStrategy == nil {
+		strategy, err := racktheme.GetStrategy(r.executor, r.shop)
+		if err != nil {
+			return err
+		}
+
+		r.themeStrategy = strategy
+	}
+
+	start := time.Now()
+	out, err := racktheme.AssignTheme(r.executor, r.shop, r.themeStrategy, step.SubShop, step.Theme)
+
+	r.step.AddCommand("assign theme "+step.Theme+" to shop "+step.SubShop+" ("+r.themeStrategy.Name()+")",
+		out, time.Since(start), err)
+
+	return err
+}
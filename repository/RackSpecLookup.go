@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/hashicorp/go-version"
+
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/fileutil"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/gitutil"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackspec"
+)
+
+// FindPluginRepo returns the name of the first repository, that contains rackspecs for the given plugin
+func FindPluginRepo(pluginName string) (string, error) {
+	repoStorePath, err := GetRepoStorePath()
+	if err != nil {
+		return "", err
+	}
+
+	repoDirs, err := ioutil.ReadDir(*repoStorePath)
+	if err != nil {
+		return "", err
+	}
+
+	for _, repoDir := range repoDirs {
+		if !repoDir.IsDir() {
+			continue
+		}
+
+		exists, _ := fileutil.ObjectExists(filepath.Join(*repoStorePath, repoDir.Name(), pluginName))
+		if exists {
+			return repoDir.Name(), nil
+		}
+	}
+
+	return "", errors.New("no rackspec found for plugin " + pluginName)
+}
+
+// GetPluginVersions returns all versions of a plugin, that have a rackspec in the given repository
+func GetPluginVersions(repoName string, pluginName string) ([]string, error) {
+	repoPath, err := GetSpecificRepoPath(repoName)
+	if err != nil {
+		return nil, err
+	}
+
+	versionDirs, err := ioutil.ReadDir(filepath.Join(*repoPath, pluginName))
+	if err != nil {
+		return nil, err
+	}
+
+	versions := []string{}
+
+	for _, versionDir := range versionDirs {
+		if versionDir.IsDir() {
+			versions = append(versions, versionDir.Name())
+		}
+	}
+
+	return versions, nil
+}
+
+// GetRackSpec returns the rackspec of a plugin in a specific version and the name of the repository it was found in
+func GetRackSpec(pluginName string, pluginVersion string) (*rackspec.RackSpec, string, error) {
+	repoName, err := FindPluginRepo(pluginName)
+	if err != nil {
+		return nil, "", err
+	}
+
+	repoPath, err := GetSpecificRepoPath(repoName)
+	if err != nil {
+		return nil, "", err
+	}
+
+	specPath, err := rackspec.FindRackSpecInDir(filepath.Join(*repoPath, pluginName, pluginVersion))
+	if err != nil {
+		return nil, repoName, err
+	}
+
+	spec, err := rackspec.UnmarshalRackSpec(*specPath)
+
+	return spec, repoName, err
+}
+
+// MatchPluginVersion returns the version of a plugin's rackspec, that matches one of the given candidates.
+// Candidates are compared as versions, so a git tag "v1.0.0" matches the rackspec version "1.0.0".
+func MatchPluginVersion(repoName string, pluginName string, candidates ...string) (string, error) {
+	versions, err := GetPluginVersions(repoName, pluginName)
+	if err != nil {
+		return "", err
+	}
+
+	for _, candidate := range candidates {
+		candidateVersion, err := version.NewVersion(candidate)
+		if err != nil {
+			continue
+		}
+
+		for _, specVersion := range versions {
+			parsed, err := version.NewVersion(specVersion)
+			if err == nil && parsed.Equal(candidateVersion) {
+				return specVersion, nil
+			}
+		}
+	}
+
+	return "", errors.New("no rackspec of plugin " + pluginName + " matches its installed version")
+}
+
+// GetLatestPluginVersion returns the highest version of a plugin, that has a rackspec in the local repositories
+func GetLatestPluginVersion(pluginName string) (string, error) {
+	repoName, err := FindPluginRepo(pluginName)
+	if err != nil {
+		return "", err
+	}
+
+	versions, err := GetPluginVersions(repoName, pluginName)
+	if err != nil {
+		return "", err
+	}
+
+	var latest *version.Version
+
+	latestName := ""
+
+	for _, specVersion := range versions {
+		parsed, err := version.NewVersion(specVersion)
+		if err != nil {
+			continue
+		}
+
+		if latest == nil || parsed.GreaterThan(latest) {
+			latest = parsed
+			latestName = specVersion
+		}
+	}
+
+	if latest == nil {
+		return "", errors.New("no versions found for plugin " + pluginName)
+	}
+
+	return latestName, nil
+}
+
+// ResolvePluginVersion returns the given version, or the latest version of the plugin if version is empty or "latest"
+func ResolvePluginVersion(pluginName string, pluginVersion string) (string, error) {
+	if pluginVersion == "" || pluginVersion == "latest" {
+		return GetLatestPluginVersion(pluginName)
+	}
+
+	return pluginVersion, nil
+}
+
+// GetRepoHeadCommit returns the hash of the commit, the local checkout of a repository is on
+func GetRepoHeadCommit(repoName string) (string, error) {
+	repoPath, err := GetSpecificRepoPath(repoName)
+	if err != nil {
+		return "", err
+	}
+
+	repo, err := gitutil.GetRepoFromLocalDir(*repoPath)
+	if err != nil {
+		return "", err
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return "", err
+	}
+
+	return head.Hash().String(), nil
+}
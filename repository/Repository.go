@@ -2,6 +2,7 @@
 package repository
 
 import (
+	"context"
 	"encoding/xml"
 	"errors"
 	"strings"
@@ -10,12 +11,12 @@ import (
 	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/fileutil"
 	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/gitutil"
 	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackconfig"
+	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/racklog"
 	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackplugin"
 	"gitlab.worldiety.net/worldiety/customer/wdy/libriety/shopware/rackjobber/rackspec"
 
 	"fmt"
 	"io/ioutil"
-	"log"
 	"os"
 	"path/filepath"
 
@@ -62,7 +63,7 @@ func CreateRepoStoreDir() error {
 	}
 
 	if exists {
-		log.Fatalln("Repo Directory already exists - Aborting")
+		racklog.Fatalf("Repo Directory already exists - Aborting")
 		return errors.New("Directory already exists")
 	}
 
@@ -71,8 +72,8 @@ func CreateRepoStoreDir() error {
 	return err
 }
 
-// UpdateRepos will update all repositories
-func UpdateRepos() error {
+// UpdateRepos will update all repositories, until the context is done
+func UpdateRepos(ctx context.Context) error {
 	repoStorePath, err := GetRepoStorePath()
 	if err != nil {
 		return err
@@ -85,19 +86,30 @@ func UpdateRepos() error {
 
 	for _, dir := range directories {
 		if dir.IsDir() {
-			err := updateRepo(dir.Name())
+			err := updateRepo(ctx, dir.Name())
 			if err != nil {
-				log.Printf("Failed to update repo %v: %v\n", dir.Name(), err)
+				logUpdateError(dir.Name(), "Failed to update repo", err)
 				return err
 			}
 		}
 	}
 
-	fmt.Println("Successfully updated repositories")
+	racklog.Infof("Successfully updated repositories")
 
 	return nil
 }
 
+//logUpdateError logs the error of updating a repository, a repository, that is already up-to-date, only verbosely
+func logUpdateError(repoName string, message string, err error) {
+	logger := racklog.With("repo", repoName)
+	if err == git.NoErrAlreadyUpToDate {
+		logger.Verbosef("%v: %v\n", message, err)
+		return
+	}
+
+	logger.Warnf("%v: %v\n", message, err)
+}
+
 // ListRepos will make a map of all Repos connected to RackJobber with their Git URL
 func ListRepos() (*map[string]string, error) {
 	repos := make(map[string]string)
@@ -138,26 +150,26 @@ func ListRepos() (*map[string]string, error) {
 	return &repos, err
 }
 
-func fetchRepo(repoName string) error {
-	fmt.Printf("Start fetching repo with name: %v\n", repoName)
+func fetchRepo(ctx context.Context, repoName string) error {
+	racklog.With("repo", repoName).Verbosef("Start fetching repo with name: %v\n", repoName)
 
 	repoPath, err := GetSpecificRepoPath(repoName)
 	if err != nil {
-		log.Fatalf("GetSpecificRepoPath - error: %v\n", err)
+		racklog.Fatalf("GetSpecificRepoPath - error: %v\n", err)
 		return err
 	}
 
 	repo, err := git.PlainOpen(*repoPath)
 	if err != nil {
-		log.Fatalf("PlainOpen - error: %v\n", err)
+		racklog.Fatalf("PlainOpen - error: %v\n", err)
 		return err
 	}
 
 	domain := getRepoDomain(repoName)
 
-	err = gitutil.Fetch(*repo, domain, git.FetchOptions{
+	err = gitutil.Fetch(ctx, *repo, domain, git.FetchOptions{
 		RemoteName: "origin",
-		Progress:   os.Stdout,
+		Progress:   racklog.Progress(),
 	})
 	if err != nil {
 		return err
@@ -166,65 +178,65 @@ func fetchRepo(repoName string) error {
 	return nil
 }
 
-func updateRepo(repoName string) error {
-	err := fetchRepo(repoName)
+func updateRepo(ctx context.Context, repoName string) error {
+	err := fetchRepo(ctx, repoName)
 	if err != nil {
-		log.Printf("FetchRepo - error: %v\n", err)
+		logUpdateError(repoName, "FetchRepo - error", err)
 		return err
 	}
 
-	fmt.Printf("Start updating repo with name: %v\n", repoName)
+	racklog.With("repo", repoName).Verbosef("Start updating repo with name: %v\n", repoName)
 
 	repoPath, err := GetSpecificRepoPath(repoName)
 	if err != nil {
-		log.Printf("GetSpecificRepoPath - error: %v\n", err)
+		racklog.Warnf("GetSpecificRepoPath - error: %v\n", err)
 		return err
 	}
 
 	repo, err := git.PlainOpen(*repoPath)
 	if err != nil {
-		log.Printf("PlainOpen - error: %v\n", err)
+		racklog.Warnf("PlainOpen - error: %v\n", err)
 		return err
 	}
 
 	worktree, err := repo.Worktree()
 	if err != nil {
-		log.Printf("Worktree - error: %v\n", err)
+		racklog.Warnf("Worktree - error: %v\n", err)
 		return err
 	}
 
 	domain := getRepoDomain(repoName)
 
-	err = gitutil.Update(*worktree, domain, git.PullOptions{
+	err = gitutil.Update(ctx, *worktree, domain, git.PullOptions{
 		RemoteName: "origin",
-		Progress:   os.Stdout,
+		Progress:   racklog.Progress(),
 	})
 	if err != nil {
-		log.Printf("Update - error: %v\n", err)
+		racklog.Warnf("Update - error: %v\n", err)
 		return err
 	}
 
 	ref, err := repo.Head()
 	if err != nil {
-		log.Printf("Head - error: %v\n", err)
+		racklog.Warnf("Head - error: %v\n", err)
 		return err
 	}
 
 	commit, err := repo.CommitObject(ref.Hash())
 	if err != nil {
-		log.Printf("CommitObject - error: %v\n", err)
+		racklog.Warnf("CommitObject - error: %v\n", err)
 		return err
 	}
 
-	fmt.Printf("Updated repo with name: %v\n", repoName)
-	fmt.Printf("Commit message: %v\n", commit)
+	racklog.With("repo", repoName).Infof("Updated repo with name: %v\n", repoName)
+	racklog.With("repo", repoName).Verbosef("Commit message: %v\n", commit)
 
 	return nil
 }
 
 // PushSpecToRepo pushes a rackspec to a specific repository
 func PushSpecToRepo(repoName string) error {
-	err := updateRepo(repoName)
+	err := updateRepo(context.Background(), repoName)
 	if err != nil {
 		return err
 	}
@@ -292,7 +304,7 @@ func commitYml(entryPath, repoName, name, version, versionDir string) error {
 	if err != nil {
 		remerr := os.RemoveAll(versionDir)
 		if remerr != nil {
-			log.Printf("os.Remove - error: %v\n", remerr)
+			racklog.Warnf("os.Remove - error: %v\n", remerr)
 		}
 
 		return err
@@ -308,7 +320,7 @@ func fileExists(repoPath string) error {
 	}
 
 	if !exists {
-		log.Fatalf("Could not find repository at path: %v\n", repoPath)
+		racklog.Fatalf("Could not find repository at path: %v\n", repoPath)
 		return errors.New("Repository does not exist")
 	}
 
@@ -331,8 +343,7 @@ func addFileToGit(filePath string, repoName string, commitMsg string) error {
 		return err
 	}
 
-	fmt.Println("File to add:")
-	fmt.Println(filePath)
+	racklog.Verbosef("File to add: %v", filePath)
 
 	_, err = worktree.Add(filePath)
 	if err != nil {
@@ -344,22 +355,21 @@ func addFileToGit(filePath string, repoName string, commitMsg string) error {
 		return err
 	}
 
-	fmt.Print("Current Worktree status: \n\n")
-	fmt.Println(status)
-	fmt.Println("Create Commit")
+	racklog.Verbosef("Current Worktree status: \n\n%v", status)
+	racklog.Infof("Create Commit")
 
 	err = commit(repo, worktree, commitMsg)
 	if err != nil {
 		return err
 	}
 
-	fmt.Println("Pushing commits:")
+	racklog.Infof("Pushing commits")
 
 	domain := getRepoDomain(repoName)
 
-	err = gitutil.Push(*repo, domain, git.PushOptions{
+	err = gitutil.Push(context.Background(), *repo, domain, git.PushOptions{
 		RemoteName: "origin",
-		Progress:   os.Stdout,
+		Progress:   racklog.Progress(),
 	})
 	if err != nil {
 		return err
@@ -385,8 +395,7 @@ func commit(repo *git.Repository, worktree *git.Worktree, commitMsg string) erro
 		return err
 	}
 
-	fmt.Print("Created Commit: \n\n")
-	fmt.Println(obj)
+	racklog.Verbosef("Created Commit: \n\n%v", obj)
 
 	return nil
 }
@@ -395,24 +404,24 @@ func commit(repo *git.Repository, worktree *git.Worktree, commitMsg string) erro
 func AddRepo(name string, source string) error {
 	repoStorePath, err := GetRepoStorePath()
 	if err != nil {
-		log.Fatalf("RepoStorePath - error: %v\n", err)
+		racklog.Fatalf("RepoStorePath - error: %v\n", err)
 		return err
 	}
 
 	if repoStorePath == nil {
-		log.Fatalln("RepoStorePath is unexpectedly nil")
+		racklog.Fatalf("RepoStorePath is unexpectedly nil")
 		return errors.New("Unexpected nil value")
 	}
 
 	newRepoStorePath := filepath.Join(*repoStorePath, name)
 
-	_, err = gitutil.Clone(newRepoStorePath, git.CloneOptions{
+	_, err = gitutil.Clone(context.Background(), newRepoStorePath, git.CloneOptions{
 		URL:      source,
-		Progress: os.Stdout,
+		Progress: racklog.Progress(),
 	})
 
 	if err != nil {
-		log.Fatalf("PlainClone - error: %v\n", err)
+		racklog.Fatalf("PlainClone - error: %v\n", err)
 	}
 
 	return err